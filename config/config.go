@@ -1,32 +1,156 @@
 package config
 
 import (
+	"fmt"
+	"log"
+
 	"github.com/emmanuelYohore/vet-clinic-api/database"
 	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/database/migrations"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/mailer"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/password"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/service"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 type Config struct {
+	Settings
+
+	PasswordPolicy *password.Policy
+	Mailer         mailer.Mailer
+
 	CatRepository       dbmodel.CatRepository
 	VisitRepository     dbmodel.VisitRepository
 	TreatmentRepository dbmodel.TreatmentRepository
 	UserRepository      dbmodel.UserRepository
+	OwnerRepository     dbmodel.OwnerRepository
+
+	AppointmentRepository  dbmodel.AppointmentRepository
+	WorkingHoursRepository dbmodel.WorkingHoursRepository
+	VeterinarianRepository dbmodel.VeterinarianRepository
+	VaccinationRepository  dbmodel.VaccinationRepository
+
+	WeightMeasurementRepository dbmodel.WeightMeasurementRepository
+	RefreshTokenRepository      dbmodel.RefreshTokenRepository
+	PasswordResetRepository     dbmodel.PasswordResetRepository
+	RoleRepository              dbmodel.RoleRepository
+	AuditLogRepository          dbmodel.AuditLogRepository
+	PurgeRepository             dbmodel.PurgeRepository
+
+	UnitOfWork   dbmodel.UnitOfWork
+	VisitService service.VisitService
 }
 
 func New() (*Config, error) {
 	config := Config{}
 
-	databaseSession, err := gorm.Open(sqlite.Open("data.db"), &gorm.Config{})
+	settings, err := LoadSettings()
+	if err != nil {
+		return &config, err
+	}
+	config.Settings = settings
+
+	config.PasswordPolicy, err = password.NewPolicy(settings.PasswordMinLength, settings.PasswordMinClasses,
+		settings.PasswordCheckBreached, settings.PasswordWordlist)
+	if err != nil {
+		return &config, err
+	}
+
+	switch settings.MailDriver {
+	case "smtp":
+		config.Mailer = &mailer.SMTPMailer{
+			Host:     settings.SMTPHost,
+			Port:     settings.SMTPPort,
+			Username: settings.SMTPUsername,
+			Password: settings.SMTPPassword,
+			From:     settings.MailFrom,
+		}
+	case "file":
+		config.Mailer = &mailer.FileMailer{Path: settings.MailFile}
+	default:
+		config.Mailer = mailer.LogMailer{}
+	}
+
+	databaseSession, err := OpenDatabase(settings)
 	if err != nil {
 		return &config, err
 	}
 
-	database.Migrate(databaseSession)
+	if settings.AutoMigrate {
+		applied, err := migrations.Up(databaseSession)
+		if err != nil {
+			return &config, err
+		}
+		for _, migration := range applied {
+			log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+		}
+	} else if err := migrations.Check(databaseSession); err != nil {
+		return &config, fmt.Errorf("%w; run \"migrate up\" or set auto_migrate", err)
+	}
+	if err := database.SeedRoles(databaseSession); err != nil {
+		return &config, err
+	}
 
 	config.CatRepository = dbmodel.NewCatRepository(databaseSession)
 	config.VisitRepository = dbmodel.NewVisitRepository(databaseSession)
 	config.TreatmentRepository = dbmodel.NewTreatmentRipository(databaseSession)
 	config.UserRepository = dbmodel.NewUserRepository(databaseSession)
+	config.OwnerRepository = dbmodel.NewOwnerRepository(databaseSession)
+	config.AppointmentRepository = dbmodel.NewAppointmentRepository(databaseSession)
+	config.WorkingHoursRepository = dbmodel.NewWorkingHoursRepository(databaseSession)
+	config.VeterinarianRepository = dbmodel.NewVeterinarianRepository(databaseSession)
+	config.VaccinationRepository = dbmodel.NewVaccinationRepository(databaseSession)
+	config.WeightMeasurementRepository = dbmodel.NewWeightMeasurementRepository(databaseSession)
+	config.RefreshTokenRepository = dbmodel.NewRefreshTokenRepository(databaseSession)
+	config.PasswordResetRepository = dbmodel.NewPasswordResetRepository(databaseSession)
+	config.RoleRepository = dbmodel.NewRoleRepository(databaseSession)
+	config.AuditLogRepository = dbmodel.NewAuditLogRepository(databaseSession)
+	config.PurgeRepository = dbmodel.NewPurgeRepository(databaseSession)
+
+	config.UnitOfWork = dbmodel.NewUnitOfWork(databaseSession)
+	config.VisitService = service.NewVisitService(config.UnitOfWork)
 	return &config, nil
 }
+
+// OpenDatabase opens the database configured in settings and tunes its connection pool,
+// without touching its schema.
+func OpenDatabase(settings Settings) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch settings.DatabaseDriver {
+	case "postgres":
+		dialector = postgres.Open(settings.DatabaseDSN)
+	case "mysql":
+		dsn, err := mysqldriver.ParseDSN(settings.DatabaseDSN)
+		if err != nil {
+			return nil, fmt.Errorf("mysql DSN: %w", err)
+		}
+		// Timestamps are scanned into time.Time, which needs parseTime.
+		dsn.ParseTime = true
+		dialector = mysql.New(mysql.Config{
+			DSNConfig: dsn,
+			// Strings without an explicit size become VARCHAR instead of LONGTEXT, which
+			// MySQL cannot index.
+			DefaultStringSize: 255,
+		})
+	default:
+		dialector = sqlite.Open(settings.DatabaseDSN)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(settings.DatabaseMaxOpenConns)
+	sqlDB.SetMaxIdleConns(settings.DatabaseMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(settings.DatabaseConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(settings.DatabaseConnMaxIdleTime)
+	return db, nil
+}
@@ -0,0 +1,539 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/BurntSushi/toml"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	defaultListenAddr      = ":8080"
+	defaultDatabaseDSN     = "data.db"
+	defaultSwaggerURL      = "/swagger/doc.json"
+	defaultAccessTokenTTL  = time.Hour
+	defaultRefreshTokenTTL = 7 * 24 * time.Hour
+	defaultSessionTTL      = 30 * 24 * time.Hour
+	defaultTimezone        = "UTC"
+
+	defaultDatabaseDriver       = "sqlite"
+	defaultDatabaseMaxIdleConns = 2
+	defaultQueryTimeout         = 10 * time.Second
+
+	defaultPasswordMinLength  = 10
+	defaultPasswordMinClasses = 3
+	defaultPasswordResetTTL   = time.Hour
+
+	defaultLoginMaxAttempts   = 5
+	defaultLoginIPMaxAttempts = 20
+	defaultLoginLockout       = 15 * time.Minute
+
+	defaultDeletedRetention = 365 * 24 * time.Hour
+	defaultPurgeInterval    = 24 * time.Hour
+
+	defaultSMTPPort = 587
+)
+
+// Secrets that shipped hardcoded in earlier versions and must never be used again.
+var insecureSecrets = map[string]bool{
+	"your_secret_key":   true,
+	"my_refresh_secret": true,
+	"secret":            true,
+	"changeme":          true,
+}
+
+type Settings struct {
+	ListenAddr      string        `yaml:"listen_addr" toml:"listen_addr"`
+	DatabaseDSN     string        `yaml:"database_dsn" toml:"database_dsn"`
+	AutoMigrate     bool          `yaml:"auto_migrate" toml:"auto_migrate"`
+	SwaggerURL      string        `yaml:"swagger_url" toml:"swagger_url"`
+	AccessSecret    string        `yaml:"access_secret" toml:"access_secret"`
+	RefreshSecret   string        `yaml:"refresh_secret" toml:"refresh_secret"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+	SessionTTL      time.Duration `yaml:"session_ttl" toml:"session_ttl"`
+	CORSOrigins     []string      `yaml:"cors_origins" toml:"cors_origins"`
+	Timezone        string        `yaml:"timezone" toml:"timezone"`
+
+	DatabaseDriver          string        `yaml:"database_driver" toml:"database_driver"`
+	DatabaseMaxOpenConns    int           `yaml:"database_max_open_conns" toml:"database_max_open_conns"`
+	DatabaseMaxIdleConns    int           `yaml:"database_max_idle_conns" toml:"database_max_idle_conns"`
+	DatabaseConnMaxLifetime time.Duration `yaml:"database_conn_max_lifetime" toml:"database_conn_max_lifetime"`
+	DatabaseConnMaxIdleTime time.Duration `yaml:"database_conn_max_idle_time" toml:"database_conn_max_idle_time"`
+
+	// QueryTimeout bounds the database queries of a request; QueryTimeouts overrides it
+	// for the routes named "METHOD /pattern", e.g. "GET /api/v1/cats/{id}/history".
+	QueryTimeout  time.Duration            `yaml:"query_timeout" toml:"query_timeout"`
+	QueryTimeouts map[string]time.Duration `yaml:"query_timeouts" toml:"query_timeouts"`
+
+	PasswordMinLength     int    `yaml:"password_min_length" toml:"password_min_length"`
+	PasswordMinClasses    int    `yaml:"password_min_classes" toml:"password_min_classes"`
+	PasswordCheckBreached bool   `yaml:"password_check_breached" toml:"password_check_breached"`
+	PasswordWordlist      string `yaml:"password_wordlist" toml:"password_wordlist"`
+
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl" toml:"password_reset_ttl"`
+	PasswordResetURL string        `yaml:"password_reset_url" toml:"password_reset_url"`
+
+	LoginMaxAttempts   int           `yaml:"login_max_attempts" toml:"login_max_attempts"`
+	LoginIPMaxAttempts int           `yaml:"login_ip_max_attempts" toml:"login_ip_max_attempts"`
+	LoginLockout       time.Duration `yaml:"login_lockout" toml:"login_lockout"`
+	TrustProxyHeaders  bool          `yaml:"trust_proxy_headers" toml:"trust_proxy_headers"`
+
+	DeletedRetention time.Duration `yaml:"deleted_retention" toml:"deleted_retention"`
+	PurgeInterval    time.Duration `yaml:"purge_interval" toml:"purge_interval"`
+
+	MailDriver   string `yaml:"mail_driver" toml:"mail_driver"`
+	MailFrom     string `yaml:"mail_from" toml:"mail_from"`
+	MailFile     string `yaml:"mail_file" toml:"mail_file"`
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host"`
+	SMTPPort     int    `yaml:"smtp_port" toml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password"`
+
+	location *time.Location
+}
+
+func defaultSettings() Settings {
+	return Settings{
+		ListenAddr:      defaultListenAddr,
+		DatabaseDSN:     defaultDatabaseDSN,
+		SwaggerURL:      defaultSwaggerURL,
+		AccessTokenTTL:  defaultAccessTokenTTL,
+		RefreshTokenTTL: defaultRefreshTokenTTL,
+		SessionTTL:      defaultSessionTTL,
+		Timezone:        defaultTimezone,
+
+		DatabaseDriver:       defaultDatabaseDriver,
+		DatabaseMaxIdleConns: defaultDatabaseMaxIdleConns,
+		QueryTimeout:         defaultQueryTimeout,
+
+		PasswordMinLength:     defaultPasswordMinLength,
+		PasswordMinClasses:    defaultPasswordMinClasses,
+		PasswordCheckBreached: true,
+		PasswordResetTTL:      defaultPasswordResetTTL,
+
+		LoginMaxAttempts:   defaultLoginMaxAttempts,
+		LoginIPMaxAttempts: defaultLoginIPMaxAttempts,
+		LoginLockout:       defaultLoginLockout,
+
+		DeletedRetention: defaultDeletedRetention,
+		PurgeInterval:    defaultPurgeInterval,
+
+		SMTPPort: defaultSMTPPort,
+	}
+}
+
+// Location is the clinic timezone in which working hours are expressed.
+func (s Settings) Location() *time.Location {
+	if s.location == nil {
+		return time.UTC
+	}
+	return s.location
+}
+
+// LoadSettings builds the settings from the defaults, then the optional file
+// named by VET_CONFIG_FILE, then the VET_* environment variables.
+func LoadSettings() (Settings, error) {
+	settings, err := loadSettings()
+	if err != nil {
+		return settings, err
+	}
+
+	if err := settings.Validate(); err != nil {
+		return settings, err
+	}
+
+	location, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return settings, fmt.Errorf("timezone %q: %w", settings.Timezone, err)
+	}
+	settings.location = location
+	return settings, nil
+}
+
+// LoadDatabaseSettings builds the settings like LoadSettings but only validates those of
+// the database, for the commands that do not run the server, such as migrate.
+func LoadDatabaseSettings() (Settings, error) {
+	settings, err := loadSettings()
+	if err != nil {
+		return settings, err
+	}
+	return settings, errors.Join(settings.databaseErrors()...)
+}
+
+func loadSettings() (Settings, error) {
+	settings := defaultSettings()
+
+	if path := os.Getenv("VET_CONFIG_FILE"); path != "" {
+		if err := settings.loadFile(path); err != nil {
+			return settings, err
+		}
+	}
+
+	err := settings.loadEnv()
+	return settings, err
+}
+
+func (s *Settings) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file %s: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, s)
+	case ".toml":
+		err = toml.Unmarshal(content, s)
+	default:
+		return fmt.Errorf("unsupported config file format %q (expected .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (s *Settings) loadEnv() error {
+	if value, ok := os.LookupEnv("VET_LISTEN_ADDR"); ok {
+		s.ListenAddr = value
+	}
+	if value, ok := os.LookupEnv("VET_DATABASE_DSN"); ok {
+		s.DatabaseDSN = value
+	}
+	if value, ok := os.LookupEnv("VET_DATABASE_DRIVER"); ok {
+		s.DatabaseDriver = value
+	}
+	if value, ok := os.LookupEnv("VET_DATABASE_MAX_OPEN_CONNS"); ok {
+		conns, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("VET_DATABASE_MAX_OPEN_CONNS: %w", err)
+		}
+		s.DatabaseMaxOpenConns = conns
+	}
+	if value, ok := os.LookupEnv("VET_DATABASE_MAX_IDLE_CONNS"); ok {
+		conns, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("VET_DATABASE_MAX_IDLE_CONNS: %w", err)
+		}
+		s.DatabaseMaxIdleConns = conns
+	}
+	if value, ok := os.LookupEnv("VET_DATABASE_CONN_MAX_LIFETIME"); ok {
+		lifetime, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("VET_DATABASE_CONN_MAX_LIFETIME: %w", err)
+		}
+		s.DatabaseConnMaxLifetime = lifetime
+	}
+	if value, ok := os.LookupEnv("VET_DATABASE_CONN_MAX_IDLE_TIME"); ok {
+		idle, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("VET_DATABASE_CONN_MAX_IDLE_TIME: %w", err)
+		}
+		s.DatabaseConnMaxIdleTime = idle
+	}
+	if value, ok := os.LookupEnv("VET_QUERY_TIMEOUT"); ok {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("VET_QUERY_TIMEOUT: %w", err)
+		}
+		s.QueryTimeout = timeout
+	}
+	if value, ok := os.LookupEnv("VET_QUERY_TIMEOUTS"); ok {
+		timeouts := map[string]time.Duration{}
+		for _, entry := range splitList(value) {
+			route, duration, found := strings.Cut(entry, "=")
+			if !found {
+				return fmt.Errorf("VET_QUERY_TIMEOUTS: %q is not ROUTE=DURATION", entry)
+			}
+			timeout, err := time.ParseDuration(strings.TrimSpace(duration))
+			if err != nil {
+				return fmt.Errorf("VET_QUERY_TIMEOUTS: %w", err)
+			}
+			timeouts[strings.TrimSpace(route)] = timeout
+		}
+		s.QueryTimeouts = timeouts
+	}
+	if value, ok := os.LookupEnv("VET_AUTO_MIGRATE"); ok {
+		migrate, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("VET_AUTO_MIGRATE: %w", err)
+		}
+		s.AutoMigrate = migrate
+	}
+	if value, ok := os.LookupEnv("VET_SWAGGER_URL"); ok {
+		s.SwaggerURL = value
+	}
+	if value, ok := os.LookupEnv("VET_ACCESS_SECRET"); ok {
+		s.AccessSecret = value
+	}
+	if value, ok := os.LookupEnv("VET_REFRESH_SECRET"); ok {
+		s.RefreshSecret = value
+	}
+	if value, ok := os.LookupEnv("VET_ACCESS_TOKEN_TTL"); ok {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("VET_ACCESS_TOKEN_TTL: %w", err)
+		}
+		s.AccessTokenTTL = ttl
+	}
+	if value, ok := os.LookupEnv("VET_REFRESH_TOKEN_TTL"); ok {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("VET_REFRESH_TOKEN_TTL: %w", err)
+		}
+		s.RefreshTokenTTL = ttl
+	}
+	if value, ok := os.LookupEnv("VET_SESSION_TTL"); ok {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("VET_SESSION_TTL: %w", err)
+		}
+		s.SessionTTL = ttl
+	}
+	if value, ok := os.LookupEnv("VET_CORS_ORIGINS"); ok {
+		s.CORSOrigins = splitList(value)
+	}
+	if value, ok := os.LookupEnv("VET_TIMEZONE"); ok {
+		s.Timezone = value
+	}
+	if value, ok := os.LookupEnv("VET_PASSWORD_MIN_LENGTH"); ok {
+		length, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("VET_PASSWORD_MIN_LENGTH: %w", err)
+		}
+		s.PasswordMinLength = length
+	}
+	if value, ok := os.LookupEnv("VET_PASSWORD_MIN_CLASSES"); ok {
+		classes, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("VET_PASSWORD_MIN_CLASSES: %w", err)
+		}
+		s.PasswordMinClasses = classes
+	}
+	if value, ok := os.LookupEnv("VET_PASSWORD_CHECK_BREACHED"); ok {
+		check, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("VET_PASSWORD_CHECK_BREACHED: %w", err)
+		}
+		s.PasswordCheckBreached = check
+	}
+	if value, ok := os.LookupEnv("VET_PASSWORD_WORDLIST"); ok {
+		s.PasswordWordlist = value
+	}
+	if value, ok := os.LookupEnv("VET_PASSWORD_RESET_TTL"); ok {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("VET_PASSWORD_RESET_TTL: %w", err)
+		}
+		s.PasswordResetTTL = ttl
+	}
+	if value, ok := os.LookupEnv("VET_PASSWORD_RESET_URL"); ok {
+		s.PasswordResetURL = value
+	}
+	if value, ok := os.LookupEnv("VET_LOGIN_MAX_ATTEMPTS"); ok {
+		attempts, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("VET_LOGIN_MAX_ATTEMPTS: %w", err)
+		}
+		s.LoginMaxAttempts = attempts
+	}
+	if value, ok := os.LookupEnv("VET_LOGIN_IP_MAX_ATTEMPTS"); ok {
+		attempts, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("VET_LOGIN_IP_MAX_ATTEMPTS: %w", err)
+		}
+		s.LoginIPMaxAttempts = attempts
+	}
+	if value, ok := os.LookupEnv("VET_LOGIN_LOCKOUT"); ok {
+		lockout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("VET_LOGIN_LOCKOUT: %w", err)
+		}
+		s.LoginLockout = lockout
+	}
+	if value, ok := os.LookupEnv("VET_TRUST_PROXY_HEADERS"); ok {
+		trust, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("VET_TRUST_PROXY_HEADERS: %w", err)
+		}
+		s.TrustProxyHeaders = trust
+	}
+	if value, ok := os.LookupEnv("VET_DELETED_RETENTION"); ok {
+		retention, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("VET_DELETED_RETENTION: %w", err)
+		}
+		s.DeletedRetention = retention
+	}
+	if value, ok := os.LookupEnv("VET_PURGE_INTERVAL"); ok {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("VET_PURGE_INTERVAL: %w", err)
+		}
+		s.PurgeInterval = interval
+	}
+	if value, ok := os.LookupEnv("VET_MAIL_DRIVER"); ok {
+		s.MailDriver = value
+	}
+	if value, ok := os.LookupEnv("VET_MAIL_FROM"); ok {
+		s.MailFrom = value
+	}
+	if value, ok := os.LookupEnv("VET_MAIL_FILE"); ok {
+		s.MailFile = value
+	}
+	if value, ok := os.LookupEnv("VET_SMTP_HOST"); ok {
+		s.SMTPHost = value
+	}
+	if value, ok := os.LookupEnv("VET_SMTP_PORT"); ok {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("VET_SMTP_PORT: %w", err)
+		}
+		s.SMTPPort = port
+	}
+	if value, ok := os.LookupEnv("VET_SMTP_USERNAME"); ok {
+		s.SMTPUsername = value
+	}
+	if value, ok := os.LookupEnv("VET_SMTP_PASSWORD"); ok {
+		s.SMTPPassword = value
+	}
+	return nil
+}
+
+// Validate refuses configurations the server must not start with.
+func (s *Settings) Validate() error {
+	var errs []error
+
+	if s.ListenAddr == "" {
+		errs = append(errs, errors.New("listen address must not be empty"))
+	}
+	errs = append(errs, s.databaseErrors()...)
+	if s.QueryTimeout <= 0 {
+		errs = append(errs, errors.New("query timeout must be positive"))
+	}
+	for route, timeout := range s.QueryTimeouts {
+		method, pattern, found := strings.Cut(route, " ")
+		if !found || method == "" || method != strings.ToUpper(method) || !strings.HasPrefix(pattern, "/") {
+			errs = append(errs, fmt.Errorf("query timeout route %q must read \"METHOD /pattern\"", route))
+		}
+		if timeout <= 0 {
+			errs = append(errs, fmt.Errorf("query timeout of %q must be positive", route))
+		}
+	}
+	if err := validateSecret("access secret (VET_ACCESS_SECRET)", s.AccessSecret); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateSecret("refresh secret (VET_REFRESH_SECRET)", s.RefreshSecret); err != nil {
+		errs = append(errs, err)
+	}
+	if s.AccessSecret != "" && s.AccessSecret == s.RefreshSecret {
+		errs = append(errs, errors.New("access and refresh secrets must differ"))
+	}
+	if s.AccessTokenTTL <= 0 {
+		errs = append(errs, errors.New("access token lifetime must be positive"))
+	}
+	if s.RefreshTokenTTL <= 0 {
+		errs = append(errs, errors.New("refresh token lifetime must be positive"))
+	}
+	if s.RefreshTokenTTL > 0 && s.RefreshTokenTTL < s.AccessTokenTTL {
+		errs = append(errs, errors.New("refresh token lifetime must not be shorter than the access token lifetime"))
+	}
+	if s.SessionTTL < s.RefreshTokenTTL {
+		errs = append(errs, errors.New("session lifetime must not be shorter than the refresh token lifetime"))
+	}
+	if s.PasswordMinLength < 8 || s.PasswordMinLength > 72 {
+		errs = append(errs, errors.New("password minimum length must be between 8 and 72"))
+	}
+	if s.PasswordMinClasses < 1 || s.PasswordMinClasses > 4 {
+		errs = append(errs, errors.New("password minimum character classes must be between 1 and 4"))
+	}
+	if s.PasswordResetTTL <= 0 {
+		errs = append(errs, errors.New("password reset token lifetime must be positive"))
+	}
+	if s.LoginMaxAttempts < 1 || s.LoginIPMaxAttempts < 1 {
+		errs = append(errs, errors.New("login attempt limits must be positive"))
+	}
+	if s.LoginLockout <= 0 {
+		errs = append(errs, errors.New("login lockout duration must be positive"))
+	}
+	if s.DeletedRetention <= 0 {
+		errs = append(errs, errors.New("retention of deleted records must be positive"))
+	}
+	if s.PurgeInterval < 0 {
+		errs = append(errs, errors.New("purge interval must not be negative (0 disables the purge job)"))
+	}
+	switch s.MailDriver {
+	case "":
+		// The log driver writes live reset links to the server log: it has to be chosen
+		// explicitly, for development.
+		errs = append(errs, errors.New("mail driver (VET_MAIL_DRIVER) must be set: smtp, file, or log for development"))
+	case "log":
+	case "file":
+		if s.MailFile == "" {
+			errs = append(errs, errors.New("mail file (VET_MAIL_FILE) must be set for the file mail driver"))
+		}
+	case "smtp":
+		if s.SMTPHost == "" || s.MailFrom == "" {
+			errs = append(errs, errors.New("SMTP host (VET_SMTP_HOST) and sender (VET_MAIL_FROM) must be set for the smtp mail driver"))
+		}
+		if s.SMTPPort < 1 || s.SMTPPort > 65535 {
+			errs = append(errs, errors.New("SMTP port must be between 1 and 65535"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown mail driver %q (expected log, file or smtp)", s.MailDriver))
+	}
+
+	return errors.Join(errs...)
+}
+
+// databaseErrors lists what is wrong with the database settings.
+func (s *Settings) databaseErrors() []error {
+	var errs []error
+
+	if s.DatabaseDSN == "" {
+		errs = append(errs, errors.New("database DSN must not be empty"))
+	}
+	switch s.DatabaseDriver {
+	case "sqlite", "postgres", "mysql":
+	default:
+		errs = append(errs, fmt.Errorf("unknown database driver %q (expected sqlite, postgres or mysql)", s.DatabaseDriver))
+	}
+	if s.DatabaseMaxOpenConns < 0 || s.DatabaseMaxIdleConns < 0 {
+		errs = append(errs, errors.New("database connection limits must not be negative"))
+	}
+	if s.DatabaseConnMaxLifetime < 0 || s.DatabaseConnMaxIdleTime < 0 {
+		errs = append(errs, errors.New("database connection lifetimes must not be negative"))
+	}
+	return errs
+}
+
+func validateSecret(name, secret string) error {
+	if secret == "" {
+		return fmt.Errorf("%s must be set", name)
+	}
+	if insecureSecrets[strings.ToLower(secret)] {
+		return fmt.Errorf("%s uses a known default value", name)
+	}
+	if len(secret) < 32 {
+		return fmt.Errorf("%s must be at least 32 characters long", name)
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,18 +0,0 @@
-package database
-
-import (
-	"log"
-
-	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
-	"gorm.io/gorm"
-)
-
-func Migrate(db *gorm.DB) {
-	db.AutoMigrate(
-		&dbmodel.Cat{},
-		&dbmodel.Visit{},
-		&dbmodel.Treatment{},
-		&dbmodel.User{},
-	)
-	log.Println("Database migrated successfully")
-}
@@ -0,0 +1,240 @@
+package dbmodel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	AppointmentBooked    = "booked"
+	AppointmentCheckedIn = "checked_in"
+	AppointmentCancelled = "cancelled"
+	AppointmentNoShow    = "no_show"
+	AppointmentCompleted = "completed"
+)
+
+var (
+	// ErrAppointmentConflict is returned when an appointment overlaps another one of the same veterinarian.
+	ErrAppointmentConflict = errors.New("the veterinarian already has an appointment at that time")
+	// ErrAppointmentHasVisit is returned when linking a visit to an appointment that already has one.
+	ErrAppointmentHasVisit = errors.New("a visit was already recorded for this appointment")
+)
+
+// activeAppointmentStatuses are the statuses that occupy a veterinarian's calendar.
+var activeAppointmentStatuses = []string{AppointmentBooked, AppointmentCheckedIn, AppointmentCompleted}
+
+var appointmentTransitions = map[string][]string{
+	AppointmentBooked:    {AppointmentCheckedIn, AppointmentCancelled, AppointmentNoShow},
+	AppointmentCheckedIn: {AppointmentCompleted, AppointmentCancelled},
+}
+
+type Appointment struct {
+	ID             uint `gorm:"primarykey"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+	CatID          uint           `gorm:"index"`
+	Cat            Cat            `gorm:"foreignKey:CatID"`
+	VeterinarianID uint           `gorm:"index"`
+	StartsAt       time.Time      `gorm:"index"`
+	EndsAt         time.Time
+	Status         string `gorm:"type:varchar(20);default:'booked'"`
+	Reason         string `gorm:"varchar(255)"`
+	Notes          string `gorm:"type:text"`
+	VisitID        *uint
+}
+
+func (a *Appointment) IsActive() bool {
+	for _, status := range activeAppointmentStatuses {
+		if a.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Appointment) CanTransitionTo(status string) bool {
+	for _, next := range appointmentTransitions[a.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+type AppointmentRepository interface {
+	Create(ctx context.Context, appointment *Appointment) (*Appointment, error)
+	FindAll(ctx context.Context, opts QueryOptions) ([]*Appointment, int64, error)
+	FindById(ctx context.Context, id uint) (*Appointment, error)
+	Update(ctx context.Context, appointment *Appointment) (*Appointment, error)
+	Delete(ctx context.Context, id uint, appointment *Appointment) error
+	Restore(ctx context.Context, id uint) (*Appointment, error)
+	FindActiveBetween(ctx context.Context, veterinarianID uint, from, to time.Time) ([]Appointment, error)
+	LinkVisit(ctx context.Context, appointment *Appointment, visitID uint) error
+}
+
+type appointmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAppointmentRepository(db *gorm.DB) AppointmentRepository {
+	return &appointmentRepository{db: db}
+}
+
+func (r *appointmentRepository) Delete(ctx context.Context, id uint, appointment *Appointment) error {
+	return audited(ctx, r.db, AuditActionDelete, "appointment", id, appointment, func(tx *gorm.DB) error {
+		return tx.Delete(appointment, id).Error
+	})
+}
+
+// Restore brings back a deleted appointment. Its cat must not be deleted, and its slot
+// must still be free unless the appointment no longer occupies it.
+func (r *appointmentRepository) Restore(ctx context.Context, id uint) (*Appointment, error) {
+	var appointment Appointment
+	err := restoreDeleted(ctx, r.db, "appointment", id, &appointment, func(tx *gorm.DB, deletedAt time.Time) error {
+		if err := requireLive(tx, &Cat{}, appointment.CatID); err != nil {
+			return err
+		}
+		return checkAppointmentConflict(tx, &appointment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.FindById(ctx, id)
+}
+
+func (r *appointmentRepository) FindById(ctx context.Context, id uint) (*Appointment, error) {
+	var appointment Appointment
+	if err := r.db.WithContext(ctx).First(&appointment, id).Error; err != nil {
+		return nil, err
+	}
+	return &appointment, nil
+}
+
+// Update and Create run the overlap check and the write in the same transaction, which
+// checkAppointmentConflict serializes per veterinarian so that two front-desk agents
+// cannot book the same slot concurrently.
+func (r *appointmentRepository) Update(ctx context.Context, appointment *Appointment) (*Appointment, error) {
+	err := audited(ctx, r.db, AuditActionUpdate, "appointment", appointment.ID, appointment, func(tx *gorm.DB) error {
+		if err := checkAppointmentConflict(tx, appointment); err != nil {
+			return err
+		}
+		return tx.Save(appointment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return appointment, nil
+}
+
+func (r *appointmentRepository) Create(ctx context.Context, appointment *Appointment) (*Appointment, error) {
+	if appointment.Status == "" {
+		appointment.Status = AppointmentBooked
+	}
+	err := audited(ctx, r.db, AuditActionCreate, "appointment", 0, appointment, func(tx *gorm.DB) error {
+		if err := checkAppointmentConflict(tx, appointment); err != nil {
+			return err
+		}
+		return tx.Create(appointment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return appointment, nil
+}
+
+// checkAppointmentConflict returns ErrAppointmentConflict if the appointment overlaps
+// another active appointment of its veterinarian. It first locks the veterinarian row
+// until the transaction ends: a concurrent booking for the same veterinarian waits for
+// this one to commit, then sees its appointment. SQLite has no row locks but lets a single
+// transaction write at a time.
+func checkAppointmentConflict(tx *gorm.DB, appointment *Appointment) error {
+	if !appointment.IsActive() {
+		return nil
+	}
+	var veterinarian Veterinarian
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+		Select("id").First(&veterinarian, appointment.VeterinarianID).Error; err != nil {
+		return err
+	}
+	var count int64
+	err := tx.Model(&Appointment{}).
+		Where("veterinarian_id = ?", appointment.VeterinarianID).
+		Where("status IN ?", activeAppointmentStatuses).
+		Where("starts_at < ? AND ends_at > ?", appointment.EndsAt, appointment.StartsAt).
+		Where("id <> ?", appointment.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrAppointmentConflict
+	}
+	return nil
+}
+
+var appointmentListSpec = listSpec{
+	sortable: map[string]string{
+		"id":              "id",
+		"starts_at":       "starts_at",
+		"veterinarian_id": "veterinarian_id",
+		"status":          "status",
+		"cat_id":          "cat_id",
+		"created_at":      "created_at",
+	},
+	filters: map[string]filterFunc{
+		"cat_id":          idFilter("cat_id"),
+		"veterinarian_id": idFilter("veterinarian_id"),
+		"status":          equalFilter("status"),
+		"from":            dateFilter("starts_at", ">="),
+		"to":              dateFilter("starts_at", "<="),
+	},
+}
+
+func (r *appointmentRepository) FindAll(ctx context.Context, opts QueryOptions) ([]*Appointment, int64, error) {
+	var appointments []*Appointment
+	total, err := appointmentListSpec.find(r.db.WithContext(ctx), &Appointment{}, &appointments, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return appointments, total, nil
+}
+
+func (r *appointmentRepository) FindActiveBetween(ctx context.Context, veterinarianID uint, from, to time.Time) ([]Appointment, error) {
+	var appointments []Appointment
+	if err := r.db.WithContext(ctx).
+		Where("veterinarian_id = ?", veterinarianID).
+		Where("status IN ?", activeAppointmentStatuses).
+		Where("starts_at < ? AND ends_at > ?", to, from).
+		Order("starts_at").
+		Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// LinkVisit records that the visit with the given ID took place for the appointment,
+// unless a visit was already linked to it.
+func (r *appointmentRepository) LinkVisit(ctx context.Context, appointment *Appointment, visitID uint) error {
+	err := audited(ctx, r.db, AuditActionUpdate, "appointment", appointment.ID, appointment, func(tx *gorm.DB) error {
+		result := tx.Model(&Appointment{}).
+			Where("id = ? AND visit_id IS NULL", appointment.ID).
+			Update("visit_id", visitID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAppointmentHasVisit
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	appointment.VisitID = &visitID
+	return nil
+}
@@ -0,0 +1,89 @@
+package dbmodel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+)
+
+func TestAppointmentRepositoryOverlap(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	appointments := dbmodel.NewAppointmentRepository(db)
+	veterinarians := dbmodel.NewVeterinarianRepository(db)
+
+	veterinarian, err := veterinarians.Create(ctx, &dbmodel.Veterinarian{Name: "Dr Martin", Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	colleague, err := veterinarians.Create(ctx, &dbmodel.Veterinarian{Name: "Dr Petit", Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, err := dbmodel.NewCatRepository(db).Create(ctx, &dbmodel.Cat{Name: "Felix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	booked, err := appointments.Create(ctx, appointmentAt(cat.ID, veterinarian.ID, 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if booked.Status != dbmodel.AppointmentBooked {
+		t.Errorf("status of a new appointment = %q, want %q", booked.Status, dbmodel.AppointmentBooked)
+	}
+
+	overlapping := appointmentAt(cat.ID, veterinarian.ID, 9)
+	overlapping.StartsAt = overlapping.StartsAt.Add(15 * time.Minute)
+	overlapping.EndsAt = overlapping.EndsAt.Add(15 * time.Minute)
+	if _, err := appointments.Create(ctx, overlapping); !errors.Is(err, dbmodel.ErrAppointmentConflict) {
+		t.Errorf("overlapping booking: %v, want %v", err, dbmodel.ErrAppointmentConflict)
+	}
+
+	// The next slot, the same slot with another veterinarian and a cancelled appointment
+	// over the booked one do not conflict.
+	next := appointmentAt(cat.ID, veterinarian.ID, 9)
+	next.StartsAt = booked.EndsAt
+	next.EndsAt = booked.EndsAt.Add(30 * time.Minute)
+	if _, err := appointments.Create(ctx, next); err != nil {
+		t.Errorf("booking right after another: %v", err)
+	}
+	if _, err := appointments.Create(ctx, appointmentAt(cat.ID, colleague.ID, 9)); err != nil {
+		t.Errorf("booking the same slot with another veterinarian: %v", err)
+	}
+	cancelled := appointmentAt(cat.ID, veterinarian.ID, 9)
+	cancelled.Status = dbmodel.AppointmentCancelled
+	if _, err := appointments.Create(ctx, cancelled); err != nil {
+		t.Errorf("cancelled appointment over a booking: %v", err)
+	}
+
+	later, err := appointments.Create(ctx, appointmentAt(cat.ID, veterinarian.ID, 14))
+	if err != nil {
+		t.Fatal(err)
+	}
+	later.StartsAt = booked.StartsAt
+	later.EndsAt = booked.EndsAt
+	if _, err := appointments.Update(ctx, later); !errors.Is(err, dbmodel.ErrAppointmentConflict) {
+		t.Errorf("moving an appointment over another: %v, want %v", err, dbmodel.ErrAppointmentConflict)
+	}
+
+	// Freeing the slot lets the appointment move there.
+	booked.Status = dbmodel.AppointmentCancelled
+	if _, err := appointments.Update(ctx, booked); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := appointments.Update(ctx, later); err != nil {
+		t.Errorf("moving an appointment to a freed slot: %v", err)
+	}
+
+	active, err := appointments.FindActiveBetween(ctx, veterinarian.ID, booked.StartsAt, booked.EndsAt.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 2 || active[0].ID != later.ID || active[1].ID != next.ID {
+		t.Errorf("FindActiveBetween = %+v, want the moved appointment then the next one", active)
+	}
+}
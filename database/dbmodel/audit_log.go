@@ -0,0 +1,238 @@
+package dbmodel
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge"
+)
+
+// auditRedacted replaces the value of columns that must never be copied into the audit
+// trail; the entry still shows that they changed.
+var auditRedacted = map[string]bool{"password": true}
+
+// auditIgnored columns change on every write and would only add noise to the diffs.
+var auditIgnored = map[string]bool{"created_at": true, "updated_at": true, "version": true}
+
+// AuditChange is the value of a column before and after a write; Before is null for a
+// creation and After for a deletion. Purges have no changes.
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditLog records one write to an audited entity: who made it, in which request, and
+// the columns it changed.
+type AuditLog struct {
+	ID         uint      `gorm:"primarykey"`
+	CreatedAt  time.Time `gorm:"index"`
+	ActorID    *uint     `gorm:"index"`
+	ActorEmail string
+	Action     string                 `gorm:"type:varchar(20)"`
+	EntityType string                 `gorm:"type:varchar(50);index:idx_audit_entity"`
+	EntityID   uint                   `gorm:"index:idx_audit_entity"`
+	Changes    map[string]AuditChange `gorm:"serializer:json"`
+	RequestID  string                 `gorm:"type:varchar(100)"`
+}
+
+type auditContextKey int
+
+const (
+	auditActorKey auditContextKey = iota
+	auditRequestIDKey
+)
+
+type auditActor struct {
+	id    uint
+	email string
+}
+
+// WithAuditActor returns a copy of ctx whose writes are attributed to the user.
+func WithAuditActor(ctx context.Context, userID uint, email string) context.Context {
+	return context.WithValue(ctx, auditActorKey, auditActor{id: userID, email: email})
+}
+
+// WithAuditRequestID returns a copy of ctx whose writes are tagged with the request ID.
+func WithAuditRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, auditRequestIDKey, requestID)
+}
+
+type AuditLogRepository interface {
+	FindAll(ctx context.Context, opts QueryOptions) ([]*AuditLog, int64, error)
+}
+
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+var auditLogListSpec = listSpec{
+	sortable: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"entity_type": equalFilter("entity_type"),
+		"entity_id":   idFilter("entity_id"),
+		"actor_id":    idFilter("actor_id"),
+		"action":      equalFilter("action"),
+		"from":        dateFilter("created_at", ">="),
+		"to":          dateFilter("created_at", "<="),
+	},
+}
+
+// FindAll lists the entries, most recent first unless another order is asked for.
+func (r *auditLogRepository) FindAll(ctx context.Context, opts QueryOptions) ([]*AuditLog, int64, error) {
+	if len(opts.Sort) == 0 {
+		opts.Sort = []SortField{{Field: "id", Desc: true}}
+	}
+	var entries []*AuditLog
+	total, err := auditLogListSpec.find(r.db.WithContext(ctx), &AuditLog{}, &entries, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// audited runs write in a transaction and records what it did to the entity of type T
+// with the given ID (0 for a creation, whose ID is read from entity afterwards). The
+// stored row, deleted or not, is read before and after the write, so the entry reflects
+// what the database holds whatever columns the write touched. If the entry cannot be saved, the
+// write is rolled back.
+func audited[T any](ctx context.Context, db *gorm.DB, action, entityType string, id uint, entity *T, write func(tx *gorm.DB) error) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var before map[string]interface{}
+		if action != AuditActionCreate {
+			stored := new(T)
+			if err := tx.Unscoped().First(stored, id).Error; err != nil {
+				return err
+			}
+			var err error
+			if before, err = auditSnapshot(tx, stored); err != nil {
+				return err
+			}
+		}
+
+		if err := write(tx); err != nil {
+			return err
+		}
+
+		var after map[string]interface{}
+		if action != AuditActionDelete {
+			if action == AuditActionCreate {
+				snapshot, err := auditSnapshot(tx, entity)
+				if err != nil {
+					return err
+				}
+				id, _ = snapshot["id"].(uint)
+			}
+			stored := new(T)
+			if err := tx.First(stored, id).Error; err != nil {
+				return err
+			}
+			var err error
+			if after, err = auditSnapshot(tx, stored); err != nil {
+				return err
+			}
+		}
+
+		changes := auditDiff(before, after)
+		if len(changes) == 0 {
+			return nil
+		}
+		return recordAudit(ctx, tx, action, entityType, id, changes)
+	})
+}
+
+// recordAudit saves an entry attributed to the actor and request stored in ctx.
+func recordAudit(ctx context.Context, tx *gorm.DB, action, entityType string, id uint, changes map[string]AuditChange) error {
+	entry := &AuditLog{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   id,
+		Changes:    changes,
+	}
+	if actor, ok := ctx.Value(auditActorKey).(auditActor); ok {
+		entry.ActorID = &actor.id
+		entry.ActorEmail = actor.email
+	}
+	entry.RequestID, _ = ctx.Value(auditRequestIDKey).(string)
+	return tx.Create(entry).Error
+}
+
+// auditSnapshot maps the column names of a model to their values.
+func auditSnapshot(db *gorm.DB, model interface{}) (map[string]interface{}, error) {
+	statement := &gorm.Statement{DB: db}
+	if err := statement.Parse(model); err != nil {
+		return nil, err
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(model))
+	snapshot := map[string]interface{}{}
+	for _, field := range statement.Schema.Fields {
+		if field.DBName == "" || auditIgnored[field.DBName] {
+			continue
+		}
+		if field.Serializer != nil {
+			// ValueOf wraps serialized fields in their serializer, which JSON cannot encode.
+			snapshot[field.DBName] = field.ReflectValueOf(db.Statement.Context, value).Interface()
+			continue
+		}
+		fieldValue, _ := field.ValueOf(db.Statement.Context, value)
+		snapshot[field.DBName] = fieldValue
+	}
+	return snapshot, nil
+}
+
+// auditDiff lists the columns whose JSON form differs between the two snapshots. Empty
+// columns of a created or deleted row are left out.
+func auditDiff(before, after map[string]interface{}) map[string]AuditChange {
+	changes := map[string]AuditChange{}
+	columns := map[string]bool{}
+	for column := range before {
+		columns[column] = true
+	}
+	for column := range after {
+		columns[column] = true
+	}
+
+	for column := range columns {
+		oldValue, hadOld := before[column]
+		newValue, hasNew := after[column]
+		oldJSON, _ := json.Marshal(oldValue)
+		newJSON, _ := json.Marshal(newValue)
+		if string(oldJSON) == string(newJSON) {
+			continue
+		}
+
+		var change AuditChange
+		if hadOld {
+			change.Before = auditValue(column, oldValue)
+		}
+		if hasNew {
+			change.After = auditValue(column, newValue)
+		}
+		changes[column] = change
+	}
+	return changes
+}
+
+func auditValue(column string, value interface{}) interface{} {
+	if auditRedacted[column] {
+		return "[redacted]"
+	}
+	return value
+}
@@ -0,0 +1,115 @@
+package dbmodel_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"gorm.io/gorm"
+)
+
+// auditEntries lists the actions recorded for the entity, oldest first.
+func auditEntries(t *testing.T, db *gorm.DB, entityType string, id uint) []*dbmodel.AuditLog {
+	t.Helper()
+	entries, _, err := dbmodel.NewAuditLogRepository(db).FindAll(context.Background(), dbmodel.QueryOptions{
+		Filters: map[string]string{"entity_type": entityType, "entity_id": strconv.FormatUint(uint64(id), 10)},
+		Sort:    []dbmodel.SortField{{Field: "id"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func assertActions(t *testing.T, entries []*dbmodel.AuditLog, want ...string) {
+	t.Helper()
+	if len(entries) != len(want) {
+		t.Fatalf("audit entries = %d, want %v", len(entries), want)
+	}
+	for i, entry := range entries {
+		if entry.Action != want[i] {
+			t.Errorf("audit entry %d is a %q, want %q", i, entry.Action, want[i])
+		}
+	}
+}
+
+func TestAuditedWrites(t *testing.T) {
+	db := newTestDB(t)
+	ctx := dbmodel.WithAuditActor(context.Background(), 7, "admin@example.com")
+
+	owners := dbmodel.NewOwnerRepository(db)
+	owner, err := owners.Create(ctx, &dbmodel.Owner{Name: "Alice Durand"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner.Phone = "0612345678"
+	if _, err := owners.Update(ctx, owner); err != nil {
+		t.Fatal(err)
+	}
+	entries := auditEntries(t, db, "owner", owner.ID)
+	assertActions(t, entries, dbmodel.AuditActionCreate, dbmodel.AuditActionUpdate)
+	if change := entries[1].Changes["phone"]; change.Before != "" || change.After != "0612345678" {
+		t.Errorf("owner update recorded %+v, want the new phone", entries[1].Changes)
+	}
+	if entries[0].ActorID == nil || *entries[0].ActorID != 7 || entries[0].ActorEmail != "admin@example.com" {
+		t.Errorf("owner creation attributed to %v %q, want the actor of the context", entries[0].ActorID, entries[0].ActorEmail)
+	}
+
+	veterinarians := dbmodel.NewVeterinarianRepository(db)
+	veterinarian, err := veterinarians.Create(ctx, &dbmodel.Veterinarian{Name: "Dr Martin", Active: true, Specialties: []string{"surgery"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	veterinarian.Active = false
+	if _, err := veterinarians.Update(ctx, veterinarian); err != nil {
+		t.Fatal(err)
+	}
+	assertActions(t, auditEntries(t, db, "veterinarian", veterinarian.ID), dbmodel.AuditActionCreate, dbmodel.AuditActionUpdate)
+
+	cat, err := dbmodel.NewCatRepository(db).Create(ctx, &dbmodel.Cat{Name: "Felix", OwnerID: &owner.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appointments := dbmodel.NewAppointmentRepository(db)
+	appointment, err := appointments.Create(ctx, appointmentAt(cat.ID, veterinarian.ID, 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	appointment.Status = dbmodel.AppointmentCheckedIn
+	if _, err := appointments.Update(ctx, appointment); err != nil {
+		t.Fatal(err)
+	}
+	visit, err := dbmodel.NewVisitRepository(db).Create(ctx, &dbmodel.Visit{Date: time.Now(), Motif: "checkup", CatID: cat.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := appointments.LinkVisit(ctx, appointment, visit.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertActions(t, auditEntries(t, db, "appointment", appointment.ID),
+		dbmodel.AuditActionCreate, dbmodel.AuditActionUpdate, dbmodel.AuditActionUpdate)
+
+	weights := dbmodel.NewWeightMeasurementRepository(db)
+	weight, err := weights.Create(ctx, &dbmodel.WeightMeasurement{CatID: cat.ID, Value: 4.2, Unit: "kg", MeasuredAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertActions(t, auditEntries(t, db, "weight", weight.ID), dbmodel.AuditActionCreate)
+
+	vaccinations := dbmodel.NewVaccinationRepository(db)
+	vaccination, err := vaccinations.Create(ctx, &dbmodel.Vaccination{CatID: cat.ID, VaccineName: "Rabies", AdministeredAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vaccination.LotNumber = "LOT-42"
+	if _, err := vaccinations.Update(ctx, vaccination); err != nil {
+		t.Fatal(err)
+	}
+	if err := vaccinations.Delete(ctx, vaccination.ID, vaccination); err != nil {
+		t.Fatal(err)
+	}
+	assertActions(t, auditEntries(t, db, "vaccination", vaccination.ID),
+		dbmodel.AuditActionCreate, dbmodel.AuditActionUpdate, dbmodel.AuditActionDelete)
+}
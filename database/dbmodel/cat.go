@@ -1,6 +1,7 @@
 package dbmodel
 
 import (
+	"context"
 	"time"
 
 	"gorm.io/gorm"
@@ -10,21 +11,27 @@ type Cat struct {
 	ID        uint `gorm:"primarykey"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	DeletedAt *time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	Version   uint           `gorm:"not null;default:1"`
 	Name      string
 	Age       int `gorm:"type:int"`
 	Breed     string
-	Weigth    int     `gorm:"type:int"`
+	OwnerID   *uint   `gorm:"index"`
 	Visits    []Visit `gorm:"foreignKey:CatID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+
+	// DetachedOwnerID is the deleted owner the cat belonged to, to whom restoring the
+	// owner gives it back.
+	DetachedOwnerID *uint `gorm:"index"`
 }
 
 type CatRepository interface {
-	Create(cat *Cat) (*Cat, error)
-	FindAll() ([]*Cat, error)
-	FindById(id uint) (*Cat, error)
-	Update(cat *Cat) (*Cat, error)
-	Delete(id uint, cat *Cat) error
-	CatHistory(catID uint) ([]Visit, error)
+	Create(ctx context.Context, cat *Cat) (*Cat, error)
+	FindAll(ctx context.Context, opts QueryOptions) ([]*Cat, int64, error)
+	FindById(ctx context.Context, id uint) (*Cat, error)
+	Update(ctx context.Context, cat *Cat) (*Cat, error)
+	Delete(ctx context.Context, id uint, cat *Cat) error
+	Restore(ctx context.Context, id uint) (*Cat, error)
+	CatHistory(ctx context.Context, catID uint) ([]*Visit, error)
 }
 
 type catRepository struct {
@@ -35,45 +42,105 @@ func NewCatRepository(db *gorm.DB) CatRepository {
 	return &catRepository{db: db}
 }
 
-func (r *catRepository) Delete(id uint, cat *Cat) error {
-	return r.db.Delete(cat, id).Error
+// catRecords selects the medical records and the appointments of a cat, which are deleted
+// and restored with it.
+func catRecords(tx *gorm.DB, id uint) []cascade {
+	return []cascade{
+		{&Treatment{}, "treatment", "visit_id IN (?)", []interface{}{tx.Unscoped().Model(&Visit{}).Select("id").Where("cat_id = ?", id)}},
+		{&Appointment{}, "appointment", "cat_id = ?", []interface{}{id}},
+		{&Visit{}, "visit", "cat_id = ?", []interface{}{id}},
+		{&Vaccination{}, "vaccination", "cat_id = ?", []interface{}{id}},
+		{&WeightMeasurement{}, "weight", "cat_id = ?", []interface{}{id}},
+	}
+}
+
+// Delete marks the cat, its medical records and its appointments as deleted, provided the cat is still at
+// the version it was read with.
+func (r *catRepository) Delete(ctx context.Context, id uint, cat *Cat) error {
+	return audited(ctx, r.db, AuditActionDelete, "cat", id, cat, func(tx *gorm.DB) error {
+		now := tx.NowFunc()
+		if err := softDeleteCascade(ctx, tx, now, catRecords(tx, id)...); err != nil {
+			return err
+		}
+		return deleteVersioned(tx, &Cat{}, id, cat.Version, now)
+	})
+}
+
+// Restore brings back a deleted cat with the records that were deleted along with it.
+func (r *catRepository) Restore(ctx context.Context, id uint) (*Cat, error) {
+	var cat Cat
+	err := restoreDeleted(ctx, r.db, "cat", id, &cat, func(tx *gorm.DB, deletedAt time.Time) error {
+		parentDeletedAt := tx.Unscoped().Model(&Cat{}).Select("deleted_at").Where("id = ?", id)
+		return restoreCascade(ctx, tx, parentDeletedAt, deletedAt, catRecords(tx, id)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.FindById(ctx, id)
 }
 
-func (r *catRepository) FindById(id uint) (*Cat, error) {
+func (r *catRepository) FindById(ctx context.Context, id uint) (*Cat, error) {
 	var cat Cat
-	if err := r.db.First(&cat, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&cat, id).Error; err != nil {
 		return nil, err
 	}
 	return &cat, nil
 
 }
 
-func (r *catRepository) Update(cat *Cat) (*Cat, error) {
-	if err := r.db.Save(cat).Error; err != nil {
+func (r *catRepository) Update(ctx context.Context, cat *Cat) (*Cat, error) {
+	err := audited(ctx, r.db, AuditActionUpdate, "cat", cat.ID, cat, func(tx *gorm.DB) error {
+		return updateVersioned(tx, cat, &cat.Version)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return cat, nil
 }
 
-func (r *catRepository) Create(cat *Cat) (*Cat, error) {
-	if err := r.db.Create(cat).Error; err != nil {
+func (r *catRepository) Create(ctx context.Context, cat *Cat) (*Cat, error) {
+	err := audited(ctx, r.db, AuditActionCreate, "cat", 0, cat, func(tx *gorm.DB) error {
+		return tx.Create(cat).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return cat, nil
 }
 
-func (r *catRepository) FindAll() ([]*Cat, error) {
+var catListSpec = listSpec{
+	sortable: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"age":        "age",
+		"breed":      "breed",
+		"weight":     currentWeightSQL,
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"name":       containsFilter("name"),
+		"breed":      caseInsensitiveFilter("breed"),
+		"age_min":    numberFilter("age", ">="),
+		"age_max":    numberFilter("age", "<="),
+		"weight_min": numberFilter(currentWeightSQL, ">="),
+		"weight_max": numberFilter(currentWeightSQL, "<="),
+		"owner_id":   idFilter("owner_id"),
+	},
+}
+
+func (r *catRepository) FindAll(ctx context.Context, opts QueryOptions) ([]*Cat, int64, error) {
 	var cats []*Cat
-	if err := r.db.Find(&cats).Error; err != nil {
-		return nil, err
+	total, err := catListSpec.find(r.db.WithContext(ctx), &Cat{}, &cats, opts)
+	if err != nil {
+		return nil, 0, err
 	}
-	return cats, nil
+	return cats, total, nil
 }
 
-func (r *catRepository) CatHistory(catID uint) ([]Visit, error) {
-	var visits []Visit
-	if err := r.db.
-		Preload("Treatments").Where("cat_id = ?", catID).Find(&visits).Error; err != nil {
+func (r *catRepository) CatHistory(ctx context.Context, catID uint) ([]*Visit, error) {
+	var visits []*Visit
+	if err := r.db.WithContext(ctx).
+		Preload("Treatments").Preload("Veterinarian").Where("cat_id = ?", catID).Find(&visits).Error; err != nil {
 		return nil, err
 	}
 	return visits, nil
@@ -0,0 +1,211 @@
+package dbmodel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"gorm.io/gorm"
+)
+
+func TestCatRepositoryCRUD(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	cats := dbmodel.NewCatRepository(db)
+
+	cat, err := cats.Create(ctx, &dbmodel.Cat{Name: "Felix", Age: 3, Breed: "Siamese"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cat.ID == 0 || cat.Version != 1 {
+		t.Fatalf("created cat has ID %d and version %d, want an ID and version 1", cat.ID, cat.Version)
+	}
+
+	found, err := cats.FindById(ctx, cat.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Name != "Felix" || found.Breed != "Siamese" {
+		t.Errorf("FindById = %+v, want the created cat", found)
+	}
+
+	found.Name = "Garfield"
+	updated, err := cats.Update(ctx, found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("version after update = %d, want 2", updated.Version)
+	}
+	if reread, err := cats.FindById(ctx, cat.ID); err != nil || reread.Name != "Garfield" || reread.Version != 2 {
+		t.Errorf("FindById after update = %+v, %v, want Garfield at version 2", reread, err)
+	}
+
+	list, total, err := cats.FindAll(ctx, dbmodel.QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(list) != 1 {
+		t.Errorf("FindAll returned %d cats of %d, want 1", len(list), total)
+	}
+
+	if err := cats.Delete(ctx, cat.ID, updated); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cats.FindById(ctx, cat.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindById after delete: %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	if _, total, _ := cats.FindAll(ctx, dbmodel.QueryOptions{}); total != 0 {
+		t.Errorf("FindAll after delete found %d cats, want 0", total)
+	}
+	deleted, total, err := cats.FindAll(ctx, dbmodel.QueryOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || !deleted[0].DeletedAt.Valid {
+		t.Errorf("FindAll with deleted records = %+v, want the deleted cat", deleted)
+	}
+}
+
+func TestCatRepositoryVersionedWrites(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	cats := dbmodel.NewCatRepository(db)
+
+	cat, err := cats.Create(ctx, &dbmodel.Cat{Name: "Felix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := cats.FindById(ctx, cat.ID)
+	second, _ := cats.FindById(ctx, cat.ID)
+
+	first.Name = "Garfield"
+	if _, err := cats.Update(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	second.Name = "Tom"
+	if _, err := cats.Update(ctx, second); !errors.Is(err, dbmodel.ErrVersionConflict) {
+		t.Errorf("update of a stale cat: %v, want %v", err, dbmodel.ErrVersionConflict)
+	}
+	if second.Version != 1 {
+		t.Errorf("version of the rejected cat = %d, want it left at 1", second.Version)
+	}
+	if err := cats.Delete(ctx, cat.ID, second); !errors.Is(err, dbmodel.ErrVersionConflict) {
+		t.Errorf("delete of a stale cat: %v, want %v", err, dbmodel.ErrVersionConflict)
+	}
+
+	stored, err := cats.FindById(ctx, cat.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "Garfield" || stored.Version != 2 {
+		t.Errorf("stored cat = %+v, want the first update at version 2", stored)
+	}
+}
+
+func TestCatRepositoryDeleteCascade(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	cats := dbmodel.NewCatRepository(db)
+	visits := dbmodel.NewVisitRepository(db)
+	treatments := dbmodel.NewTreatmentRipository(db)
+	appointments := dbmodel.NewAppointmentRepository(db)
+
+	veterinarian, err := dbmodel.NewVeterinarianRepository(db).Create(ctx, &dbmodel.Veterinarian{Name: "Dr Martin", Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, err := cats.Create(ctx, &dbmodel.Cat{Name: "Felix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	visit, err := visits.Create(ctx, &dbmodel.Visit{Date: time.Now(), Motif: "checkup", CatID: cat.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	treatment, err := treatments.Create(ctx, &dbmodel.Treatment{Name: "Amoxicillin", VisitID: visit.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appointment, err := appointments.Create(ctx, appointmentAt(cat.ID, veterinarian.ID, 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cats.Delete(ctx, cat.ID, cat); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := visits.FindById(ctx, visit.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("visit of a deleted cat: %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	if _, err := treatments.FindById(ctx, treatment.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("treatment of a deleted cat: %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	if _, err := appointments.FindById(ctx, appointment.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("appointment of a deleted cat: %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	if _, err := visits.Restore(ctx, visit.ID); !errors.Is(err, dbmodel.ErrParentDeleted) {
+		t.Errorf("restore of a visit of a deleted cat: %v, want %v", err, dbmodel.ErrParentDeleted)
+	}
+
+	restored, err := cats.Restore(ctx, cat.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.DeletedAt.Valid {
+		t.Errorf("restored cat is still deleted: %+v", restored)
+	}
+	if _, err := visits.FindById(ctx, visit.ID); err != nil {
+		t.Errorf("visit after restoring its cat: %v", err)
+	}
+	if _, err := treatments.FindById(ctx, treatment.ID); err != nil {
+		t.Errorf("treatment after restoring its cat: %v", err)
+	}
+	if _, err := appointments.FindById(ctx, appointment.ID); err != nil {
+		t.Errorf("appointment after restoring its cat: %v", err)
+	}
+	if _, err := cats.Restore(ctx, cat.ID); !errors.Is(err, dbmodel.ErrNotDeleted) {
+		t.Errorf("second restore: %v, want %v", err, dbmodel.ErrNotDeleted)
+	}
+}
+
+func TestCatRepositoryRestoreKeepsEarlierDeletions(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	cats := dbmodel.NewCatRepository(db)
+	visits := dbmodel.NewVisitRepository(db)
+
+	cat, err := cats.Create(ctx, &dbmodel.Cat{Name: "Felix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := visits.Create(ctx, &dbmodel.Visit{Date: time.Now(), Motif: "checkup", CatID: cat.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := visits.Create(ctx, &dbmodel.Visit{Date: time.Now(), Motif: "mistake", CatID: cat.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := visits.Delete(ctx, removed.ID, removed); err != nil {
+		t.Fatal(err)
+	}
+	// The cascade is told apart from the earlier deletion by its timestamp.
+	time.Sleep(10 * time.Millisecond)
+
+	cat, _ = cats.FindById(ctx, cat.ID)
+	if err := cats.Delete(ctx, cat.ID, cat); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cats.Restore(ctx, cat.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := visits.FindById(ctx, kept.ID); err != nil {
+		t.Errorf("visit deleted with the cat: %v, want it restored", err)
+	}
+	if _, err := visits.FindById(ctx, removed.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("visit deleted before the cat: %v, want it still deleted", err)
+	}
+}
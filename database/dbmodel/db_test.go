@@ -0,0 +1,105 @@
+package dbmodel_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/database/migrations"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB returns a migrated database for one test. It is a fresh in-memory SQLite
+// database, unless VET_TEST_DATABASE_DSN is set: the tests then run against that database,
+// of the driver given by VET_TEST_DATABASE_DRIVER (sqlite, postgres or mysql), after
+// dropping all its tables. To keep a wrong DSN from wiping real data, the name of that
+// database must end in "_test".
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	settings := config.Settings{
+		DatabaseDriver: "sqlite",
+		DatabaseDSN:    ":memory:",
+		// Every connection to ":memory:" opens its own database.
+		DatabaseMaxOpenConns: 1,
+		DatabaseMaxIdleConns: 1,
+	}
+	dsn, external := os.LookupEnv("VET_TEST_DATABASE_DSN")
+	if external {
+		settings.DatabaseDriver = os.Getenv("VET_TEST_DATABASE_DRIVER")
+		settings.DatabaseDSN = dsn
+		settings.DatabaseMaxOpenConns = 0
+	}
+
+	db, err := config.OpenDatabase(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Logger = logger.Discard
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if external {
+		name := testDatabaseName(db, settings)
+		if !strings.HasSuffix(name, "_test") {
+			t.Fatalf("VET_TEST_DATABASE_DSN names the database %q: the tests drop all its tables, so its name must end in _test", name)
+		}
+		tables, err := db.Migrator().GetTables()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, table := range tables {
+			if strings.HasPrefix(table, "sqlite_") {
+				continue
+			}
+			if err := db.Migrator().DropTable(table); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// testDatabaseName returns the name of the database the tests run on: the name of the
+// file without its extension for SQLite, whose databases are all named "main".
+func testDatabaseName(db *gorm.DB, settings config.Settings) string {
+	if settings.DatabaseDriver != "sqlite" {
+		return db.Migrator().CurrentDatabase()
+	}
+	file, _, _ := strings.Cut(strings.TrimPrefix(settings.DatabaseDSN, "file:"), "?")
+	return strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+}
+
+// countUnscoped counts the rows of model, deleted or not, matching the condition.
+func countUnscoped(t *testing.T, db *gorm.DB, model interface{}, query string, args ...interface{}) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Unscoped().Model(model).Where(query, args...).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// appointmentAt returns a booked half-hour appointment of the cat with the veterinarian,
+// starting at the given hour of the next day.
+func appointmentAt(catID, veterinarianID uint, hour int) *dbmodel.Appointment {
+	day := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	startsAt := day.Add(time.Duration(hour) * time.Hour)
+	return &dbmodel.Appointment{
+		CatID:          catID,
+		VeterinarianID: veterinarianID,
+		StartsAt:       startsAt,
+		EndsAt:         startsAt.Add(30 * time.Minute),
+		Reason:         "checkup",
+	}
+}
@@ -0,0 +1,178 @@
+package dbmodel
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Owner struct {
+	ID               uint `gorm:"primarykey"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        gorm.DeletedAt `gorm:"index"`
+	Name             string
+	Phone            string `gorm:"type:varchar(30);index"`
+	Email            string `gorm:"type:varchar(255);index"`
+	Address          string
+	PreferredContact string `gorm:"type:varchar(20)"`
+	Notes            string `gorm:"type:text"`
+	Cats             []Cat  `gorm:"foreignKey:OwnerID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+}
+
+type OwnerRepository interface {
+	Create(ctx context.Context, owner *Owner) (*Owner, error)
+	FindAll(ctx context.Context, opts QueryOptions) ([]*Owner, int64, error)
+	FindById(ctx context.Context, id uint) (*Owner, error)
+	Update(ctx context.Context, owner *Owner) (*Owner, error)
+	Delete(ctx context.Context, id uint, owner *Owner) error
+	Restore(ctx context.Context, id uint) (*Owner, error)
+}
+
+type ownerRepository struct {
+	db *gorm.DB
+}
+
+func NewOwnerRepository(db *gorm.DB) OwnerRepository {
+	return &ownerRepository{db: db}
+}
+
+// Delete marks the owner as deleted. Their cats, including the deleted ones, are kept
+// without an owner until the owner is restored.
+func (r *ownerRepository) Delete(ctx context.Context, id uint, owner *Owner) error {
+	return audited(ctx, r.db, AuditActionDelete, "owner", id, owner, func(tx *gorm.DB) error {
+		var catIDs []uint
+		if err := tx.Unscoped().Model(&Cat{}).Where("owner_id = ?", id).Pluck("id", &catIDs).Error; err != nil {
+			return err
+		}
+		if err := setCatsOwner(ctx, tx, catIDs, nil, &id); err != nil {
+			return err
+		}
+		return tx.Delete(owner, id).Error
+	})
+}
+
+// Restore brings back a deleted owner with the cats they had, unless the cats were given
+// another owner in the meantime.
+func (r *ownerRepository) Restore(ctx context.Context, id uint) (*Owner, error) {
+	var owner Owner
+	err := restoreDeleted(ctx, r.db, "owner", id, &owner, func(tx *gorm.DB, deletedAt time.Time) error {
+		var catIDs []uint
+		if err := tx.Unscoped().Model(&Cat{}).Where("detached_owner_id = ? AND owner_id IS NULL", id).
+			Pluck("id", &catIDs).Error; err != nil {
+			return err
+		}
+		if err := setCatsOwner(ctx, tx, catIDs, &id, nil); err != nil {
+			return err
+		}
+		return forgetDetachedOwner(tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.FindById(ctx, id)
+}
+
+// setCatsOwner gives the cats, deleted or not, a new owner and records the change of each
+// of them. detachedOwnerID is the deleted owner they are taken from, if any.
+func setCatsOwner(ctx context.Context, tx *gorm.DB, catIDs []uint, ownerID, detachedOwnerID *uint) error {
+	if len(catIDs) == 0 {
+		return nil
+	}
+	var cats []Cat
+	if err := tx.Unscoped().Where("id IN ?", catIDs).Find(&cats).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(&Cat{}).Where("id IN ?", catIDs).Updates(map[string]interface{}{
+		"owner_id":          ownerID,
+		"detached_owner_id": detachedOwnerID,
+		"version":           nextVersion,
+	}).Error; err != nil {
+		return err
+	}
+	for _, cat := range cats {
+		changes := map[string]AuditChange{"owner_id": {Before: cat.OwnerID, After: ownerID}}
+		if err := recordAudit(ctx, tx, AuditActionUpdate, "cat", cat.ID, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forgetDetachedOwner clears the deleted owner the cats remember, once the owner is
+// restored or purged.
+func forgetDetachedOwner(tx *gorm.DB, ownerID uint) error {
+	return tx.Unscoped().Model(&Cat{}).Where("detached_owner_id = ?", ownerID).
+		UpdateColumn("detached_owner_id", nil).Error
+}
+
+func (r *ownerRepository) FindById(ctx context.Context, id uint) (*Owner, error) {
+	var owner Owner
+	if err := r.db.WithContext(ctx).First(&owner, id).Error; err != nil {
+		return nil, err
+	}
+	return &owner, nil
+}
+
+func (r *ownerRepository) Update(ctx context.Context, owner *Owner) (*Owner, error) {
+	err := audited(ctx, r.db, AuditActionUpdate, "owner", owner.ID, owner, func(tx *gorm.DB) error {
+		return tx.Save(owner).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return owner, nil
+}
+
+func (r *ownerRepository) Create(ctx context.Context, owner *Owner) (*Owner, error) {
+	err := audited(ctx, r.db, AuditActionCreate, "owner", 0, owner, func(tx *gorm.DB) error {
+		return tx.Create(owner).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return owner, nil
+}
+
+var ownerListSpec = listSpec{
+	sortable: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"email":      "email",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"name":  containsFilter("name"),
+		"email": caseInsensitiveFilter("email"),
+		"phone": phoneFilter("phone"),
+	},
+}
+
+func (r *ownerRepository) FindAll(ctx context.Context, opts QueryOptions) ([]*Owner, int64, error) {
+	var owners []*Owner
+	total, err := ownerListSpec.find(r.db.WithContext(ctx), &Owner{}, &owners, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return owners, total, nil
+}
+
+// NormalizePhone strips the separators people type in phone numbers so that
+// "06 12-34.56.78" and "0612345678" are stored and searched identically.
+func NormalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')', '/':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+}
+
+func phoneFilter(column string) filterFunc {
+	return func(query *gorm.DB, value string) (*gorm.DB, error) {
+		return query.Where(column+" LIKE ?", "%"+NormalizePhone(value)+"%"), nil
+	}
+}
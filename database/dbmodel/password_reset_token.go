@@ -0,0 +1,80 @@
+package dbmodel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrPasswordResetTokenInvalid is returned for a reset token that was used or has expired.
+var ErrPasswordResetTokenInvalid = errors.New("password reset token already used or expired")
+
+// PasswordResetToken lets a user choose a new password once, before ExpiresAt. Only the
+// SHA-256 hash of the token sent by email is stored.
+type PasswordResetToken struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UserID    uint      `gorm:"index"`
+	TokenHash string    `gorm:"type:varchar(64);uniqueIndex"`
+	ExpiresAt time.Time `gorm:"index"`
+	UsedAt    *time.Time
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, token *PasswordResetToken) (*PasswordResetToken, error)
+	FindByHash(ctx context.Context, hash string) (*PasswordResetToken, error)
+	Consume(ctx context.Context, id uint) error
+	DeleteForUser(ctx context.Context, userID uint) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+func (r *passwordResetRepository) Create(ctx context.Context, token *PasswordResetToken) (*PasswordResetToken, error) {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *passwordResetRepository) FindByHash(ctx context.Context, hash string) (*PasswordResetToken, error) {
+	var token PasswordResetToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Consume marks the token as used, unless it already was or has expired.
+func (r *passwordResetRepository) Consume(ctx context.Context, id uint) error {
+	now := time.Now().UTC()
+	result := r.db.WithContext(ctx).Model(&PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", id, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPasswordResetTokenInvalid
+	}
+	return nil
+}
+
+// DeleteForUser invalidates the pending tokens of the user, so that only the latest
+// requested link works.
+func (r *passwordResetRepository) DeleteForUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&PasswordResetToken{}).Error
+}
+
+func (r *passwordResetRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&PasswordResetToken{})
+	return result.RowsAffected, result.Error
+}
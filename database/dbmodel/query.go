@@ -0,0 +1,168 @@
+package dbmodel
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// ErrInvalidQuery is returned by list methods when a sort field or filter is unknown or malformed.
+var ErrInvalidQuery = errors.New("invalid query")
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// QueryOptions is accepted by every repository list method.
+// Page is 1-based; Filters maps a filter name (e.g. "breed", "age_min") to its raw value.
+// IncludeDeleted also lists the soft-deleted records.
+type QueryOptions struct {
+	Page           int
+	Limit          int
+	Sort           []SortField
+	Filters        map[string]string
+	IncludeDeleted bool
+}
+
+func (o QueryOptions) normalized() QueryOptions {
+	if o.Page < 1 {
+		o.Page = 1
+	}
+	if o.Limit <= 0 {
+		o.Limit = DefaultPageSize
+	}
+	if o.Limit > MaxPageSize {
+		o.Limit = MaxPageSize
+	}
+	return o
+}
+
+func (o QueryOptions) Offset() int {
+	o = o.normalized()
+	return (o.Page - 1) * o.Limit
+}
+
+type filterFunc func(query *gorm.DB, value string) (*gorm.DB, error)
+
+// listSpec whitelists the columns a list method may sort and filter on.
+type listSpec struct {
+	sortable map[string]string
+	filters  map[string]filterFunc
+}
+
+func (s listSpec) find(db *gorm.DB, model interface{}, dest interface{}, opts QueryOptions) (int64, error) {
+	opts = opts.normalized()
+	query := db.Model(model)
+	if opts.IncludeDeleted {
+		query = query.Unscoped()
+	}
+
+	for name, value := range opts.Filters {
+		filter, ok := s.filters[name]
+		if !ok {
+			return 0, fmt.Errorf("%w: unknown filter %q", ErrInvalidQuery, name)
+		}
+		var err error
+		if query, err = filter(query, value); err != nil {
+			return 0, fmt.Errorf("%w: filter %q: %v", ErrInvalidQuery, name, err)
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	for _, sort := range opts.Sort {
+		column, ok := s.sortable[sort.Field]
+		if !ok {
+			return 0, fmt.Errorf("%w: cannot sort on %q", ErrInvalidQuery, sort.Field)
+		}
+		if sort.Desc {
+			column += " DESC"
+		}
+		query = query.Order(column)
+	}
+	query = query.Order("id")
+
+	if err := query.Limit(opts.Limit).Offset(opts.Offset()).Find(dest).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func equalFilter(column string) filterFunc {
+	return func(query *gorm.DB, value string) (*gorm.DB, error) {
+		return query.Where(column+" = ?", value), nil
+	}
+}
+
+func caseInsensitiveFilter(column string) filterFunc {
+	return func(query *gorm.DB, value string) (*gorm.DB, error) {
+		return query.Where("LOWER("+column+") = LOWER(?)", value), nil
+	}
+}
+
+func containsFilter(column string) filterFunc {
+	return func(query *gorm.DB, value string) (*gorm.DB, error) {
+		return query.Where("LOWER("+column+") LIKE ?", "%"+strings.ToLower(value)+"%"), nil
+	}
+}
+
+func boolFilter(column string) filterFunc {
+	return func(query *gorm.DB, value string) (*gorm.DB, error) {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return query.Where(column+" = ?", b), nil
+	}
+}
+
+func idFilter(column string) filterFunc {
+	return func(query *gorm.DB, value string) (*gorm.DB, error) {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, errors.New("must be a positive integer")
+		}
+		return query.Where(column+" = ?", uint(id)), nil
+	}
+}
+
+func numberFilter(column, operator string) filterFunc {
+	return func(query *gorm.DB, value string) (*gorm.DB, error) {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return query.Where(column+" "+operator+" ?", number), nil
+	}
+}
+
+// dateFilter accepts RFC 3339 timestamps or plain dates; a plain date used as an
+// upper bound covers the whole day.
+func dateFilter(column, operator string) filterFunc {
+	return func(query *gorm.DB, value string) (*gorm.DB, error) {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return query.Where(column+" "+operator+" ?", t.UTC()), nil
+		}
+		day, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, errors.New("must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+		}
+		if operator == "<=" {
+			return query.Where(column+" < ?", day.AddDate(0, 0, 1)), nil
+		}
+		return query.Where(column+" "+operator+" ?", day), nil
+	}
+}
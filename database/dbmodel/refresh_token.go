@@ -0,0 +1,93 @@
+package dbmodel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrRefreshTokenConsumed is returned when a refresh token was already used or revoked.
+var ErrRefreshTokenConsumed = errors.New("refresh token already used or revoked")
+
+// RefreshToken is an issued refresh token. Only its SHA-256 hash is stored. Every
+// token obtained by rotation shares the FamilyID of the login that started the session.
+type RefreshToken struct {
+	ID              uint `gorm:"primarykey"`
+	CreatedAt       time.Time
+	UserID          uint      `gorm:"index"`
+	FamilyID        string    `gorm:"type:varchar(64);index"`
+	TokenHash       string    `gorm:"type:varchar(64);uniqueIndex"`
+	ExpiresAt       time.Time `gorm:"index"`
+	FamilyExpiresAt time.Time
+	UsedAt          *time.Time
+	RevokedAt       *time.Time
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *RefreshToken) (*RefreshToken, error)
+	FindByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	Consume(ctx context.Context, id uint) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeUser(ctx context.Context, userID uint) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *RefreshToken) (*RefreshToken, error) {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *refreshTokenRepository) FindByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	var token RefreshToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Consume marks the token as used. The update is conditional so that two concurrent
+// refreshes with the same token cannot both succeed.
+func (r *refreshTokenRepository) Consume(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Model(&RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", time.Now().UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRefreshTokenConsumed
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.db.WithContext(ctx).Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now().UTC()).Error
+}
+
+// RevokeUser ends every session of the user, e.g. after a password change.
+func (r *refreshTokenRepository) RevokeUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now().UTC()).Error
+}
+
+// DeleteExpired removes the tokens that expired before the given time; they can no
+// longer be used, so they are not needed for reuse detection either.
+func (r *refreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&RefreshToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,206 @@
+package dbmodel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Permissions checked by the routes. A permission grants one kind of access to one
+// resource; roles are sets of permissions and users get the permissions of their role.
+const (
+	PermissionCatsRead           = "cats:read"
+	PermissionCatsWrite          = "cats:write"
+	PermissionOwnersRead         = "owners:read"
+	PermissionOwnersWrite        = "owners:write"
+	PermissionVisitsRead         = "visits:read"
+	PermissionVisitsWrite        = "visits:write"
+	PermissionTreatmentsRead     = "treatments:read"
+	PermissionTreatmentsWrite    = "treatments:write"
+	PermissionVaccinationsRead   = "vaccinations:read"
+	PermissionVaccinationsWrite  = "vaccinations:write"
+	PermissionAppointmentsRead   = "appointments:read"
+	PermissionAppointmentsWrite  = "appointments:write"
+	PermissionVeterinariansRead  = "veterinarians:read"
+	PermissionVeterinariansWrite = "veterinarians:write"
+	PermissionUsersManage        = "users:manage"
+	PermissionRolesManage        = "roles:manage"
+	PermissionAuditRead          = "audit:read"
+	PermissionDeletedManage      = "deleted:manage"
+)
+
+// PermissionCatalog describes every permission the API knows about, in display order.
+var PermissionCatalog = []Permission{
+	{Name: PermissionCatsRead, Description: "Read cats and their weights"},
+	{Name: PermissionCatsWrite, Description: "Create, update and delete cats and their weights"},
+	{Name: PermissionOwnersRead, Description: "Read owners"},
+	{Name: PermissionOwnersWrite, Description: "Create, update and delete owners"},
+	{Name: PermissionVisitsRead, Description: "Read visits and medical histories"},
+	{Name: PermissionVisitsWrite, Description: "Create, update and delete visits"},
+	{Name: PermissionTreatmentsRead, Description: "Read treatments"},
+	{Name: PermissionTreatmentsWrite, Description: "Create, update and delete treatments"},
+	{Name: PermissionVaccinationsRead, Description: "Read vaccinations and due boosters"},
+	{Name: PermissionVaccinationsWrite, Description: "Create, update and delete vaccinations"},
+	{Name: PermissionAppointmentsRead, Description: "Read the calendar and free slots"},
+	{Name: PermissionAppointmentsWrite, Description: "Book, update and cancel appointments"},
+	{Name: PermissionVeterinariansRead, Description: "Read veterinarians and their working hours"},
+	{Name: PermissionVeterinariansWrite, Description: "Manage veterinarians and their working hours"},
+	{Name: PermissionUsersManage, Description: "Manage user accounts"},
+	{Name: PermissionRolesManage, Description: "Manage roles and their permissions"},
+	{Name: PermissionAuditRead, Description: "Read the audit trail"},
+	{Name: PermissionDeletedManage, Description: "Read, restore and purge deleted records"},
+}
+
+// DefaultRoles are the roles created on first start, with the access the hardcoded admin
+// and user roles had: admin can do everything, user can read the clinic data.
+var DefaultRoles = map[string][]string{
+	"admin": {
+		PermissionCatsRead, PermissionCatsWrite,
+		PermissionOwnersRead, PermissionOwnersWrite,
+		PermissionVisitsRead, PermissionVisitsWrite,
+		PermissionTreatmentsRead, PermissionTreatmentsWrite,
+		PermissionVaccinationsRead, PermissionVaccinationsWrite,
+		PermissionAppointmentsRead, PermissionAppointmentsWrite,
+		PermissionVeterinariansRead, PermissionVeterinariansWrite,
+		PermissionUsersManage, PermissionRolesManage,
+		PermissionAuditRead, PermissionDeletedManage,
+	},
+	"user": {
+		PermissionCatsRead,
+		PermissionOwnersRead,
+		PermissionVisitsRead,
+		PermissionTreatmentsRead,
+		PermissionVaccinationsRead,
+		PermissionAppointmentsRead,
+		PermissionVeterinariansRead,
+	},
+}
+
+// ErrRoleInUse is returned when deleting a role that users still have.
+var ErrRoleInUse = errors.New("users still have this role; assign them another role first")
+
+type Permission struct {
+	ID          uint   `gorm:"primarykey"`
+	Name        string `gorm:"type:varchar(100);uniqueIndex;not null"`
+	Description string
+}
+
+type Role struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Name        string `gorm:"type:varchar(50);uniqueIndex;not null"`
+	Description string
+	Permissions []Permission `gorm:"many2many:role_permissions;constraint:OnDelete:CASCADE;"`
+}
+
+// PermissionNames lists the names of the role's permissions.
+func (r *Role) PermissionNames() []string {
+	names := make([]string, 0, len(r.Permissions))
+	for _, permission := range r.Permissions {
+		names = append(names, permission.Name)
+	}
+	return names
+}
+
+type RoleRepository interface {
+	Create(ctx context.Context, role *Role) (*Role, error)
+	FindAll(ctx context.Context) ([]*Role, error)
+	FindById(ctx context.Context, id uint) (*Role, error)
+	FindByName(ctx context.Context, name string) (*Role, error)
+	Update(ctx context.Context, role *Role) (*Role, error)
+	Delete(ctx context.Context, id uint, role *Role) error
+	FindPermissions(ctx context.Context, names []string) ([]Permission, error)
+	AllPermissions(ctx context.Context) ([]*Permission, error)
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *Role) (*Role, error) {
+	if err := r.db.WithContext(ctx).Create(role).Error; err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (r *roleRepository) FindAll(ctx context.Context) ([]*Role, error) {
+	var roles []*Role
+	if err := r.db.WithContext(ctx).Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) FindById(ctx context.Context, id uint) (*Role, error) {
+	var role Role
+	if err := r.db.WithContext(ctx).Preload("Permissions").First(&role, id).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) FindByName(ctx context.Context, name string) (*Role, error) {
+	var role Role
+	if err := r.db.WithContext(ctx).Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// Update saves the role and replaces its permissions with role.Permissions.
+func (r *roleRepository) Update(ctx context.Context, role *Role) (*Role, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Permissions").Save(role).Error; err != nil {
+			return err
+		}
+		return tx.Model(role).Association("Permissions").Replace(role.Permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (r *roleRepository) Delete(ctx context.Context, id uint, role *Role) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var users int64
+		if err := tx.Model(&User{}).Where("role = ?", role.Name).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return ErrRoleInUse
+		}
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(role, id).Error
+	})
+}
+
+// FindPermissions returns the permissions with the given names; unknown names are skipped.
+func (r *roleRepository) FindPermissions(ctx context.Context, names []string) ([]Permission, error) {
+	var permissions []Permission
+	if len(names) == 0 {
+		return permissions, nil
+	}
+	if err := r.db.WithContext(ctx).Where("name IN ?", names).Order("id").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *roleRepository) AllPermissions(ctx context.Context) ([]*Permission, error) {
+	var permissions []*Permission
+	if err := r.db.WithContext(ctx).Order("id").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
@@ -0,0 +1,208 @@
+package dbmodel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotDeleted is returned when restoring a record that is not deleted.
+	ErrNotDeleted = errors.New("record is not deleted")
+	// ErrParentDeleted is returned when restoring a record whose cat or visit is still deleted.
+	ErrParentDeleted = errors.New("the record it belongs to is deleted; restore it first")
+)
+
+// cascade selects the rows of a model that are deleted and restored with their parent.
+type cascade struct {
+	model      interface{}
+	entityType string
+	query      string
+	args       []interface{}
+}
+
+// softDeleteCascade marks the live rows selected by each cascade, in order, as deleted
+// at the given time, and records a deletion for each of them.
+func softDeleteCascade(ctx context.Context, tx *gorm.DB, deletedAt time.Time, cascades ...cascade) error {
+	for _, c := range cascades {
+		var ids []uint
+		if err := tx.Model(c.model).Where(c.query, c.args...).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		if err := tx.Model(c.model).Where("id IN ?", ids).
+			UpdateColumns(withVersion(tx, c.model, map[string]interface{}{"deleted_at": deletedAt})).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			changes := map[string]AuditChange{"deleted_at": {After: deletedAt}}
+			if err := recordAudit(ctx, tx, AuditActionDelete, c.entityType, id, changes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restoreCascade restores the rows selected by each cascade that were deleted along with
+// their parent, that is at the time selected by parentDeletedAt.
+func restoreCascade(ctx context.Context, tx *gorm.DB, parentDeletedAt *gorm.DB, deletedAt time.Time, cascades ...cascade) error {
+	for _, c := range cascades {
+		var ids []uint
+		if err := tx.Unscoped().Model(c.model).Where(c.query, c.args...).
+			Where("deleted_at = (?)", parentDeletedAt).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		if err := tx.Unscoped().Model(c.model).Where("id IN ?", ids).
+			UpdateColumns(withVersion(tx, c.model, map[string]interface{}{"deleted_at": nil})).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			changes := map[string]AuditChange{"deleted_at": {Before: deletedAt}}
+			if err := recordAudit(ctx, tx, AuditActionRestore, c.entityType, id, changes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restoreDeleted clears the deletion mark of the row of type T with the given ID, after
+// children has restored what was deleted with it. entity receives the stored row.
+func restoreDeleted[T any](ctx context.Context, db *gorm.DB, entityType string, id uint, entity *T, children func(tx *gorm.DB, deletedAt time.Time) error) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().First(entity, id).Error; err != nil {
+			return err
+		}
+		snapshot, err := auditSnapshot(tx, entity)
+		if err != nil {
+			return err
+		}
+		deletedAt, _ := snapshot["deleted_at"].(gorm.DeletedAt)
+		if !deletedAt.Valid {
+			return ErrNotDeleted
+		}
+
+		return audited(ctx, tx, AuditActionRestore, entityType, id, entity, func(tx *gorm.DB) error {
+			if children != nil {
+				if err := children(tx, deletedAt.Time); err != nil {
+					return err
+				}
+			}
+			return tx.Unscoped().Model(entity).
+				UpdateColumns(withVersion(tx, entity, map[string]interface{}{"deleted_at": nil})).Error
+		})
+	})
+}
+
+// withVersion adds the increment of the version column to the columns of an update, for
+// the models that have one: owners, veterinarians and appointments do not.
+func withVersion(tx *gorm.DB, model interface{}, columns map[string]interface{}) map[string]interface{} {
+	statement := &gorm.Statement{DB: tx}
+	if err := statement.Parse(model); err == nil && statement.Schema.LookUpField("version") != nil {
+		columns["version"] = nextVersion
+	}
+	return columns
+}
+
+// requireLive returns ErrParentDeleted unless the row of model with the given ID exists
+// and is not deleted.
+func requireLive(tx *gorm.DB, model interface{}, id uint) error {
+	var count int64
+	if err := tx.Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrParentDeleted
+	}
+	return nil
+}
+
+// PurgeResult counts the records a purge removed, by entity type.
+type PurgeResult map[string]int64
+
+// PurgeRepository permanently removes the records deleted long enough ago.
+type PurgeRepository interface {
+	Purge(ctx context.Context, deletedBefore time.Time) (PurgeResult, error)
+}
+
+type purgeRepository struct {
+	db *gorm.DB
+}
+
+func NewPurgeRepository(db *gorm.DB) PurgeRepository {
+	return &purgeRepository{db: db}
+}
+
+// purgeOrder lists the soft-deleted models, children before the records they belong to.
+// detach, when set, removes the references to a record that would keep it from being
+// deleted.
+var purgeOrder = []struct {
+	model      interface{}
+	entityType string
+	detach     func(tx *gorm.DB, id uint) error
+}{
+	{&Treatment{}, "treatment", nil},
+	{&Vaccination{}, "vaccination", nil},
+	{&WeightMeasurement{}, "weight", nil},
+	{&Appointment{}, "appointment", nil},
+	{&Visit{}, "visit", func(tx *gorm.DB, id uint) error {
+		// The appointment stays, without the visit recorded for it.
+		return tx.Unscoped().Model(&Appointment{}).Where("visit_id = ?", id).Update("visit_id", nil).Error
+	}},
+	{&Cat{}, "cat", nil},
+	{&Owner{}, "owner", forgetDetachedOwner},
+	{&Veterinarian{}, "veterinarian", func(tx *gorm.DB, id uint) error {
+		return tx.Where("veterinarian_id = ?", id).Delete(&WorkingHours{}).Error
+	}},
+}
+
+// Purge removes the records deleted before deletedBefore and records a purge entry in the
+// audit trail for each of them. Each record is removed in its own transaction, so that one
+// that cannot be removed yet, e.g. a cat with a visit deleted later than itself, does not
+// keep the others from being purged: the error lists those records, and the result counts
+// the records that were removed, error or not.
+func (r *purgeRepository) Purge(ctx context.Context, deletedBefore time.Time) (PurgeResult, error) {
+	result := PurgeResult{}
+	db := r.db.WithContext(ctx)
+	var errs []error
+	for _, p := range purgeOrder {
+		var ids []uint
+		if err := db.Unscoped().Model(p.model).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+			Pluck("id", &ids).Error; err != nil {
+			return result, errors.Join(append(errs, err)...)
+		}
+		for _, id := range ids {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if p.detach != nil {
+					if err := p.detach(tx, id); err != nil {
+						return err
+					}
+				}
+				if err := tx.Unscoped().Delete(p.model, id).Error; err != nil {
+					return err
+				}
+				return recordAudit(ctx, tx, AuditActionPurge, p.entityType, id, nil)
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("purging %s %d: %w", p.entityType, id, err))
+				if ctx.Err() != nil {
+					return result, errors.Join(errs...)
+				}
+				continue
+			}
+			result[p.entityType]++
+		}
+	}
+	return result, errors.Join(errs...)
+}
@@ -0,0 +1,288 @@
+package dbmodel_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"gorm.io/gorm"
+)
+
+func TestOwnerRepositorySoftDelete(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	owners := dbmodel.NewOwnerRepository(db)
+	cats := dbmodel.NewCatRepository(db)
+
+	owner, err := owners.Create(ctx, &dbmodel.Owner{Name: "Alice Durand", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, err := cats.Create(ctx, &dbmodel.Cat{Name: "Felix", OwnerID: &owner.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rehomed, err := cats.Create(ctx, &dbmodel.Cat{Name: "Garfield", OwnerID: &owner.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deletedCat, err := cats.Create(ctx, &dbmodel.Cat{Name: "Tom", OwnerID: &owner.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cats.Delete(ctx, deletedCat.ID, deletedCat); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := owners.Delete(ctx, owner.ID, owner); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := owners.FindById(ctx, owner.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindById after delete: %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	if count := countUnscoped(t, db, &dbmodel.Owner{}, "id = ?", owner.ID); count != 1 {
+		t.Errorf("deleted owner has %d rows, want it kept until purged", count)
+	}
+	detached, err := cats.FindById(ctx, cat.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detached.OwnerID != nil || detached.Version != cat.Version+1 {
+		t.Errorf("cat of the deleted owner = %+v, want it detached at a new version", detached)
+	}
+
+	// One of the cats finds a new owner before the owner is restored.
+	newOwner, err := owners.Create(ctx, &dbmodel.Owner{Name: "Bruno Petit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rehomed, _ = cats.FindById(ctx, rehomed.ID)
+	rehomed.OwnerID = &newOwner.ID
+	if _, err := cats.Update(ctx, rehomed); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := owners.Restore(ctx, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := owners.FindById(ctx, owner.ID); err != nil {
+		t.Errorf("FindById after restore: %v", err)
+	}
+	if reattached, err := cats.FindById(ctx, cat.ID); err != nil || reattached.OwnerID == nil || *reattached.OwnerID != owner.ID {
+		t.Errorf("cat after restoring its owner = %+v, %v, want it given back", reattached, err)
+	}
+	if kept, err := cats.FindById(ctx, rehomed.ID); err != nil || kept.OwnerID == nil || *kept.OwnerID != newOwner.ID {
+		t.Errorf("rehomed cat after restoring its former owner = %+v, %v, want it kept by its new owner", kept, err)
+	}
+	if count := countUnscoped(t, db, &dbmodel.Cat{}, "id = ? AND owner_id = ?", deletedCat.ID, owner.ID); count != 1 {
+		t.Error("deleted cat was not given back to its restored owner")
+	}
+	if count := countUnscoped(t, db, &dbmodel.Cat{}, "detached_owner_id IS NOT NULL"); count != 0 {
+		t.Errorf("%d cats still remember a detached owner after the restore", count)
+	}
+	if _, err := owners.Restore(ctx, owner.ID); !errors.Is(err, dbmodel.ErrNotDeleted) {
+		t.Errorf("second restore: %v, want %v", err, dbmodel.ErrNotDeleted)
+	}
+}
+
+func TestVeterinarianRepositorySoftDelete(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	veterinarians := dbmodel.NewVeterinarianRepository(db)
+	appointments := dbmodel.NewAppointmentRepository(db)
+
+	veterinarian, err := veterinarians.Create(ctx, &dbmodel.Veterinarian{Name: "Dr Martin", Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, err := dbmodel.NewCatRepository(db).Create(ctx, &dbmodel.Cat{Name: "Felix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appointment, err := appointments.Create(ctx, appointmentAt(cat.ID, veterinarian.ID, 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := veterinarians.Delete(ctx, veterinarian.ID, veterinarian); !errors.Is(err, dbmodel.ErrVeterinarianInUse) {
+		t.Errorf("delete with an appointment: %v, want %v", err, dbmodel.ErrVeterinarianInUse)
+	}
+	if err := appointments.Delete(ctx, appointment.ID, appointment); err != nil {
+		t.Fatal(err)
+	}
+	// The deleted appointment still points at the veterinarian until it is purged.
+	if err := veterinarians.Delete(ctx, veterinarian.ID, veterinarian); !errors.Is(err, dbmodel.ErrVeterinarianInUse) {
+		t.Errorf("delete with a deleted appointment: %v, want %v", err, dbmodel.ErrVeterinarianInUse)
+	}
+	if _, err := dbmodel.NewPurgeRepository(db).Purge(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := veterinarians.Delete(ctx, veterinarian.ID, veterinarian); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := veterinarians.FindById(ctx, veterinarian.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindById after delete: %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	restored, err := veterinarians.Restore(ctx, veterinarian.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.DeletedAt.Valid {
+		t.Errorf("restored veterinarian is still deleted: %+v", restored)
+	}
+}
+
+func TestAppointmentRepositoryRestore(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	appointments := dbmodel.NewAppointmentRepository(db)
+
+	veterinarian, err := dbmodel.NewVeterinarianRepository(db).Create(ctx, &dbmodel.Veterinarian{Name: "Dr Martin", Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, err := dbmodel.NewCatRepository(db).Create(ctx, &dbmodel.Cat{Name: "Felix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appointment, err := appointments.Create(ctx, appointmentAt(cat.ID, veterinarian.ID, 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := appointments.Delete(ctx, appointment.ID, appointment); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := appointments.FindById(ctx, appointment.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindById after delete: %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	// The slot was freed by the deletion and booked again in the meantime.
+	if _, err := appointments.Create(ctx, appointmentAt(cat.ID, veterinarian.ID, 9)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := appointments.Restore(ctx, appointment.ID); !errors.Is(err, dbmodel.ErrAppointmentConflict) {
+		t.Errorf("restore over a new booking: %v, want %v", err, dbmodel.ErrAppointmentConflict)
+	}
+}
+
+func TestPurgeRepositoryPurge(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	cats := dbmodel.NewCatRepository(db)
+	visits := dbmodel.NewVisitRepository(db)
+	appointments := dbmodel.NewAppointmentRepository(db)
+	purge := dbmodel.NewPurgeRepository(db)
+
+	veterinarian, err := dbmodel.NewVeterinarianRepository(db).Create(ctx, &dbmodel.Veterinarian{Name: "Dr Martin", Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbmodel.NewWorkingHoursRepository(db).Replace(ctx, veterinarian.ID, []dbmodel.WorkingHours{
+		{Weekday: time.Monday, Start: "08:00", End: "18:00"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A deleted cat with a visit, a treatment and an appointment.
+	deletedCat, err := cats.Create(ctx, &dbmodel.Cat{Name: "Felix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	visit, err := visits.Create(ctx, &dbmodel.Visit{Date: time.Now(), Motif: "checkup", CatID: deletedCat.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbmodel.NewTreatmentRipository(db).Create(ctx, &dbmodel.Treatment{Name: "Amoxicillin", VisitID: visit.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := appointments.Create(ctx, appointmentAt(deletedCat.ID, veterinarian.ID, 9)); err != nil {
+		t.Fatal(err)
+	}
+	if err := cats.Delete(ctx, deletedCat.ID, deletedCat); err != nil {
+		t.Fatal(err)
+	}
+
+	// A live cat whose appointment was recorded as a visit that was deleted afterwards.
+	liveCat, err := cats.Create(ctx, &dbmodel.Cat{Name: "Garfield"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deletedVisit, err := visits.Create(ctx, &dbmodel.Visit{Date: time.Now(), Motif: "mistake", CatID: liveCat.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked := appointmentAt(liveCat.ID, veterinarian.ID, 10)
+	linked.Status = dbmodel.AppointmentCompleted
+	linked.VisitID = &deletedVisit.ID
+	if _, err := appointments.Create(ctx, linked); err != nil {
+		t.Fatal(err)
+	}
+	if err := visits.Delete(ctx, deletedVisit.ID, deletedVisit); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, err := purge.Purge(ctx, time.Now().Add(-time.Hour)); err != nil || len(result) != 0 {
+		t.Errorf("purge of the records deleted over an hour ago = %v, %v, want nothing purged", result, err)
+	}
+
+	result, err := purge.Purge(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := dbmodel.PurgeResult{"cat": 1, "visit": 2, "treatment": 1, "appointment": 1}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Purge = %v, want %v", result, want)
+	}
+	if count := countUnscoped(t, db, &dbmodel.Cat{}, "id = ?", deletedCat.ID); count != 0 {
+		t.Errorf("purged cat still has %d rows", count)
+	}
+	if count := countUnscoped(t, db, &dbmodel.Treatment{}, "1 = 1"); count != 0 {
+		t.Errorf("%d treatments left after the purge, want 0", count)
+	}
+	kept, err := appointments.FindById(ctx, linked.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept.VisitID != nil {
+		t.Errorf("appointment still points at purged visit %d", *kept.VisitID)
+	}
+	if _, err := cats.FindById(ctx, liveCat.ID); err != nil {
+		t.Errorf("live cat after the purge: %v", err)
+	}
+
+	entries, total, err := dbmodel.NewAuditLogRepository(db).FindAll(ctx, dbmodel.QueryOptions{
+		Filters: map[string]string{"action": dbmodel.AuditActionPurge},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 5 || len(entries) != 5 {
+		t.Errorf("purge recorded %d audit entries, want 5", total)
+	}
+
+	// Once its appointments are purged, the veterinarian can be deleted and purged with
+	// their working hours.
+	if err := appointments.Delete(ctx, linked.ID, kept); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := purge.Purge(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbmodel.NewVeterinarianRepository(db).Delete(ctx, veterinarian.ID, veterinarian); err != nil {
+		t.Fatal(err)
+	}
+	result, err = purge.Purge(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, dbmodel.PurgeResult{"veterinarian": 1}) {
+		t.Errorf("Purge = %v, want the veterinarian", result)
+	}
+	if count := countUnscoped(t, db, &dbmodel.WorkingHours{}, "veterinarian_id = ?", veterinarian.ID); count != 0 {
+		t.Errorf("purged veterinarian still has %d working hours", count)
+	}
+}
@@ -1,6 +1,7 @@
 package dbmodel
 
 import (
+	"context"
 	"time"
 
 	"gorm.io/gorm"
@@ -10,19 +11,28 @@ type Treatment struct {
 	ID        uint `gorm:"primarykey"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	DeletedAt *time.Time
-	Name      string 
-	VisitID   uint 
-	Visit     Visit `gorm:"foreignKey:VisitID"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	Version   uint           `gorm:"not null;default:1"`
+	Name      string
+	Dosage    float64
+	Unit      string `gorm:"type:varchar(20)"`
+	Route     string `gorm:"type:varchar(50)"`
+	Frequency string
+	StartDate *time.Time
+	EndDate   *time.Time
+	Notes     string `gorm:"type:text"`
+	VisitID   uint   `gorm:"index"`
+	Visit     Visit  `gorm:"foreignKey:VisitID"`
 }
 
 type TreatmentRepository interface {
-	Create(treatment *Treatment) (*Treatment, error)
-	FindAll() ([]*Treatment, error)
-	FindById(id uint) (*Treatment, error)
-	Update(treatment *Treatment) (*Treatment, error)
-	Delete(id uint, treatment *Treatment) error
-	FindByVisitID(visitID uint) ([]Treatment, error)
+	Create(ctx context.Context, treatment *Treatment) (*Treatment, error)
+	FindAll(ctx context.Context, opts QueryOptions) ([]*Treatment, int64, error)
+	FindById(ctx context.Context, id uint) (*Treatment, error)
+	Update(ctx context.Context, treatment *Treatment) (*Treatment, error)
+	Delete(ctx context.Context, id uint, treatment *Treatment) error
+	Restore(ctx context.Context, id uint) (*Treatment, error)
+	FindByVisitID(ctx context.Context, visitID uint) ([]Treatment, error)
 }
 
 type treatmentRepository struct {
@@ -33,44 +43,83 @@ func NewTreatmentRipository(db *gorm.DB) TreatmentRepository {
 	return &treatmentRepository{db: db}
 }
 
-func (r *treatmentRepository) Delete(id uint, treatment *Treatment) error {
+// Delete marks the treatment as deleted, provided it is still at the version it was read with.
+func (r *treatmentRepository) Delete(ctx context.Context, id uint, treatment *Treatment) error {
+	return audited(ctx, r.db, AuditActionDelete, "treatment", id, treatment, func(tx *gorm.DB) error {
+		return deleteVersioned(tx, &Treatment{}, id, treatment.Version, tx.NowFunc())
+	})
+}
 
-	return r.db.Delete(treatment, id).Error
+// Restore brings back a deleted treatment. Its visit must not be deleted.
+func (r *treatmentRepository) Restore(ctx context.Context, id uint) (*Treatment, error) {
+	var treatment Treatment
+	err := restoreDeleted(ctx, r.db, "treatment", id, &treatment, func(tx *gorm.DB, deletedAt time.Time) error {
+		return requireLive(tx, &Visit{}, treatment.VisitID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.FindById(ctx, id)
 }
 
-func (r *treatmentRepository) FindById(id uint) (*Treatment, error) {
+func (r *treatmentRepository) FindById(ctx context.Context, id uint) (*Treatment, error) {
 	var treatment Treatment
-	if err := r.db.First(&treatment, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&treatment, id).Error; err != nil {
 		return nil, err
 	}
 	return &treatment, nil
 }
 
-func (r *treatmentRepository) Update(treatment *Treatment) (*Treatment, error) {
-	if err := r.db.Save(treatment).Error; err != nil {
+func (r *treatmentRepository) Update(ctx context.Context, treatment *Treatment) (*Treatment, error) {
+	err := audited(ctx, r.db, AuditActionUpdate, "treatment", treatment.ID, treatment, func(tx *gorm.DB) error {
+		return updateVersioned(tx, treatment, &treatment.Version)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return treatment, nil
 }
 
-func (r *treatmentRepository) Create(treatment *Treatment) (*Treatment, error) {
-	if err := r.db.Create(treatment).Error; err != nil {
+func (r *treatmentRepository) Create(ctx context.Context, treatment *Treatment) (*Treatment, error) {
+	err := audited(ctx, r.db, AuditActionCreate, "treatment", 0, treatment, func(tx *gorm.DB) error {
+		return tx.Create(treatment).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return treatment, nil
 }
 
-func (r *treatmentRepository) FindAll() ([]*Treatment, error) {
+var treatmentListSpec = listSpec{
+	sortable: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"visit_id":   "visit_id",
+		"start_date": "start_date",
+		"end_date":   "end_date",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"visit_id":   idFilter("visit_id"),
+		"name":       containsFilter("name"),
+		"route":      caseInsensitiveFilter("route"),
+		"start_from": dateFilter("start_date", ">="),
+		"start_to":   dateFilter("start_date", "<="),
+	},
+}
+
+func (r *treatmentRepository) FindAll(ctx context.Context, opts QueryOptions) ([]*Treatment, int64, error) {
 	var treatments []*Treatment
-	if err := r.db.Find(&treatments).Error; err != nil {
-		return nil, err
+	total, err := treatmentListSpec.find(r.db.WithContext(ctx), &Treatment{}, &treatments, opts)
+	if err != nil {
+		return nil, 0, err
 	}
-	return treatments, nil
+	return treatments, total, nil
 }
 
-func (r *treatmentRepository) FindByVisitID(visitID uint) ([]Treatment, error) {
+func (r *treatmentRepository) FindByVisitID(ctx context.Context, visitID uint) ([]Treatment, error) {
 	var treatments []Treatment
-	if err := r.db.Where("visit_id = ?", visitID).Find(&treatments).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("visit_id = ?", visitID).Find(&treatments).Error; err != nil {
 		return nil, err
 	}
 	return treatments, nil
@@ -0,0 +1,55 @@
+package dbmodel
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Repositories groups repositories that share one database session.
+type Repositories struct {
+	Cats          CatRepository
+	Visits        VisitRepository
+	Treatments    TreatmentRepository
+	Veterinarians VeterinarianRepository
+	Vaccinations  VaccinationRepository
+	Weights       WeightMeasurementRepository
+	Appointments  AppointmentRepository
+	Users         UserRepository
+	RefreshTokens RefreshTokenRepository
+}
+
+func newRepositories(db *gorm.DB) Repositories {
+	return Repositories{
+		Cats:          NewCatRepository(db),
+		Visits:        NewVisitRepository(db),
+		Treatments:    NewTreatmentRipository(db),
+		Veterinarians: NewVeterinarianRepository(db),
+		Vaccinations:  NewVaccinationRepository(db),
+		Weights:       NewWeightMeasurementRepository(db),
+		Appointments:  NewAppointmentRepository(db),
+		Users:         NewUserRepository(db),
+		RefreshTokens: NewRefreshTokenRepository(db),
+	}
+}
+
+// UnitOfWork runs several repository calls in one database transaction.
+type UnitOfWork interface {
+	// Do calls fn with repositories bound to a new transaction, which is committed if fn
+	// returns nil and rolled back otherwise.
+	Do(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+type unitOfWork struct {
+	db *gorm.DB
+}
+
+func NewUnitOfWork(db *gorm.DB) UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(repos Repositories) error) error {
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(newRepositories(tx))
+	})
+}
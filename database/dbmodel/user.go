@@ -1,7 +1,9 @@
 package dbmodel
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -9,19 +11,41 @@ import (
 
 type User struct {
 	gorm.Model
-	Email        string `gorm:"type:varchar(255);unique;not null"`
-	Password     string `gorm:"type:varchar(255);not null"`
-	Role         string `gorm:"type:varchar(50);default:'user'"`
-	RefreshToken string
+	Email    string `gorm:"type:varchar(255);unique;not null"`
+	Password string `gorm:"type:varchar(255);not null"`
+	Role     string `gorm:"type:varchar(50);default:'user'"`
+
+	FailedLoginAttempts int
+	LockedUntil         *time.Time
+	LastLoginAt         *time.Time
+	LastFailedLoginAt   *time.Time
+
+	DisabledAt *time.Time
+}
+
+// IsDisabled tells whether an admin disabled the account; a disabled user cannot log in
+// and their tokens are refused.
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+// IsLocked tells whether logins are refused after too many failed attempts.
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
 }
 
 type UserRepository interface {
-	Create(user *User) (*User, error)
-	FindAll() ([]*User, error)
-	FindById(id uint) (*User, error)
-	Update(user *User) (*User, error)
-	Delete(id uint, user *User) error
-	GetUserByEmail(email string) (*User, error)
+	Create(ctx context.Context, user *User) (*User, error)
+	FindAll(ctx context.Context, opts QueryOptions) ([]*User, int64, error)
+	FindById(ctx context.Context, id uint) (*User, error)
+	Update(ctx context.Context, user *User) (*User, error)
+	UpdatePassword(ctx context.Context, user *User, password string) error
+	Delete(ctx context.Context, id uint, user *User) error
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	RecordLoginSuccess(ctx context.Context, user *User) error
+	RecordLoginFailure(ctx context.Context, user *User, maxAttempts int, lockout time.Duration) error
+	Unlock(ctx context.Context, user *User) error
+	SetDisabled(ctx context.Context, user *User, disabled bool) error
 }
 
 type userRepository struct {
@@ -32,49 +56,158 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) Delete(id uint, user *User) error {
-	return r.db.Delete(user, id).Error
+func (r *userRepository) Delete(ctx context.Context, id uint, user *User) error {
+	return audited(ctx, r.db, AuditActionDelete, "user", id, user, func(tx *gorm.DB) error {
+		return tx.Delete(user, id).Error
+	})
 }
 
-func (r *userRepository) FindById(id uint) (*User, error) {
+func (r *userRepository) FindById(ctx context.Context, id uint) (*User, error) {
 	var user User
-	if err := r.db.First(&user, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 
 }
 
-func (r *userRepository) Update(user *User) (*User, error) {
-	if err := r.db.Save(user).Error; err != nil {
+// Update saves the fields an administrator edits, the email and the role, then reloads
+// the user. The password, the login counters, the lockout and the disabling are only
+// changed by their own methods, so that an edit based on an earlier read does not undo
+// them.
+func (r *userRepository) Update(ctx context.Context, user *User) (*User, error) {
+	err := audited(ctx, r.db, AuditActionUpdate, "user", user.ID, user, func(tx *gorm.DB) error {
+		if err := tx.Model(user).Select("Email", "Role").Updates(user).Error; err != nil {
+			return err
+		}
+		return tx.First(user, user.ID).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (r *userRepository) Create(user *User) (*User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+// UpdatePassword hashes and stores a new password for the user.
+func (r *userRepository) UpdatePassword(ctx context.Context, user *User, password string) error {
+	hashedPassword, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	err = audited(ctx, r.db, AuditActionUpdate, "user", user.ID, user, func(tx *gorm.DB) error {
+		return tx.Model(user).Update("password", hashedPassword).Error
+	})
+	if err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+	return nil
+}
+
+func (r *userRepository) Create(ctx context.Context, user *User) (*User, error) {
+	hashedPassword, err := HashPassword(user.Password)
 	if err != nil {
 		return nil, err
 	}
-	user.Password = string(hashedPassword)
-	if err := r.db.Create(user).Error; err != nil {
+	user.Password = hashedPassword
+	err = audited(ctx, r.db, AuditActionCreate, "user", 0, user, func(tx *gorm.DB) error {
+		return tx.Create(user).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (r *userRepository) FindAll() ([]*User, error) {
+func (r *userRepository) RecordLoginSuccess(ctx context.Context, user *User) error {
+	now := time.Now().UTC()
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	user.LastLoginAt = &now
+	return r.db.WithContext(ctx).Model(user).Select("FailedLoginAttempts", "LockedUntil", "LastLoginAt").Updates(user).Error
+}
+
+// RecordLoginFailure counts a failed attempt; the maxAttempts-th failure in a row locks
+// the account for the lockout duration and starts a new count. Like RecordLoginSuccess,
+// it only touches the login counters and is not audited: the audit log would otherwise
+// fill up with logins.
+func (r *userRepository) RecordLoginFailure(ctx context.Context, user *User, maxAttempts int, lockout time.Duration) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"failed_login_attempts": gorm.Expr("failed_login_attempts + 1"),
+			"last_failed_login_at":  now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.First(user, user.ID).Error; err != nil {
+			return err
+		}
+		if user.FailedLoginAttempts < maxAttempts {
+			return nil
+		}
+
+		lockedUntil := now.Add(lockout)
+		user.FailedLoginAttempts = 0
+		user.LockedUntil = &lockedUntil
+		return tx.Model(user).Select("FailedLoginAttempts", "LockedUntil").Updates(user).Error
+	})
+}
+
+func (r *userRepository) Unlock(ctx context.Context, user *User) error {
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	return audited(ctx, r.db, AuditActionUpdate, "user", user.ID, user, func(tx *gorm.DB) error {
+		return tx.Model(user).Select("FailedLoginAttempts", "LockedUntil").Updates(user).Error
+	})
+}
+
+// SetDisabled disables or enables the account; it is the only write of DisabledAt.
+func (r *userRepository) SetDisabled(ctx context.Context, user *User, disabled bool) error {
+	user.DisabledAt = nil
+	if disabled {
+		now := time.Now().UTC()
+		user.DisabledAt = &now
+	}
+	return audited(ctx, r.db, AuditActionUpdate, "user", user.ID, user, func(tx *gorm.DB) error {
+		return tx.Model(user).Select("DisabledAt").Updates(user).Error
+	})
+}
+
+// HashPassword is the only way passwords are stored.
+func HashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
+
+var userListSpec = listSpec{
+	sortable: map[string]string{
+		"id":         "id",
+		"email":      "email",
+		"role":       "role",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"email": containsFilter("email"),
+		"role":  equalFilter("role"),
+	},
+}
+
+func (r *userRepository) FindAll(ctx context.Context, opts QueryOptions) ([]*User, int64, error) {
 	var users []*User
-	if err := r.db.Find(&users).Error; err != nil {
-		return nil, err
+	total, err := userListSpec.find(r.db.WithContext(ctx), &User{}, &users, opts)
+	if err != nil {
+		return nil, 0, err
 	}
-	return users, nil
+	return users, total, nil
 }
 
-func (r *userRepository) GetUserByEmail(email string) (*User, error) {
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	var user User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, fmt.Errorf("user with email %s not found: %w", email, err)
 	}
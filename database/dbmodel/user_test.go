@@ -0,0 +1,199 @@
+package dbmodel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func TestUserRepositoryCRUD(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	users := dbmodel.NewUserRepository(db)
+
+	user, err := users.Create(ctx, &dbmodel.User{Email: "alice@example.com", Password: "Str0ng-Passw0rd", Role: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("Str0ng-Passw0rd")) != nil {
+		t.Error("the created user does not have the hash of their password")
+	}
+	if _, err := users.Create(ctx, &dbmodel.User{Email: "bob@example.com", Password: "Str0ng-Passw0rd", Role: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := users.GetUserByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ID != user.ID {
+		t.Errorf("GetUserByEmail = user %d, want %d", found.ID, user.ID)
+	}
+	if _, err := users.GetUserByEmail(ctx, "nobody@example.com"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetUserByEmail of an unknown email: %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	list, total, err := users.FindAll(ctx, dbmodel.QueryOptions{Filters: map[string]string{"role": "admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || list[0].Email != "bob@example.com" {
+		t.Errorf("FindAll by role = %+v, want bob", list)
+	}
+
+	if err := users.UpdatePassword(ctx, found, "An0ther-Passw0rd"); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := users.FindById(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte("An0ther-Passw0rd")) != nil {
+		t.Error("the stored password is not the hash of the new one")
+	}
+
+	if err := users.Delete(ctx, user.ID, stored); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.FindById(ctx, user.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindById after delete: %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	assertActions(t, auditEntries(t, db, "user", user.ID),
+		dbmodel.AuditActionCreate, dbmodel.AuditActionUpdate, dbmodel.AuditActionDelete)
+}
+
+func TestUserRepositoryLoginFailures(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	users := dbmodel.NewUserRepository(db)
+
+	user, err := users.Create(ctx, &dbmodel.User{Email: "alice@example.com", Password: "Str0ng-Passw0rd", Role: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < 3; i++ {
+		if err := users.RecordLoginFailure(ctx, user, 3, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if user.FailedLoginAttempts != i || user.IsLocked(time.Now()) {
+			t.Fatalf("after %d failures: %d attempts, locked %v, want the count and no lockout", i, user.FailedLoginAttempts, user.IsLocked(time.Now()))
+		}
+	}
+	if err := users.RecordLoginFailure(ctx, user, 3, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := users.FindById(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.IsLocked(time.Now()) || stored.IsLocked(time.Now().Add(2*time.Hour)) || stored.FailedLoginAttempts != 0 {
+		t.Errorf("after 3 failures: %+v, want a lockout of an hour and a new count", stored)
+	}
+
+	if err := users.Unlock(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	if unlocked, _ := users.FindById(ctx, user.ID); unlocked.IsLocked(time.Now()) {
+		t.Error("the user is still locked after Unlock")
+	}
+
+	if err := users.RecordLoginFailure(ctx, user, 3, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.RecordLoginSuccess(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ = users.FindById(ctx, user.ID)
+	if stored.FailedLoginAttempts != 0 || stored.LastLoginAt == nil {
+		t.Errorf("after a login: %+v, want the count reset and the login time", stored)
+	}
+}
+
+func TestUserRepositoryUpdateKeepsLockout(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	users := dbmodel.NewUserRepository(db)
+
+	user, err := users.Create(ctx, &dbmodel.User{Email: "alice@example.com", Password: "Str0ng-Passw0rd", Role: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An administrator opens the user while someone keeps failing to log in as them.
+	edited, err := users.FindById(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := users.RecordLoginFailure(ctx, user, 3, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	edited.Email = "alice.durand@example.com"
+	edited.Role = "admin"
+	updated, err := users.Update(ctx, edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Email != "alice.durand@example.com" || updated.Role != "admin" {
+		t.Errorf("updated user = %+v, want the new email and role", updated)
+	}
+	if !updated.IsLocked(time.Now()) {
+		t.Error("the update returned the user unlocked")
+	}
+	stored, err := users.FindById(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.IsLocked(time.Now()) || stored.LastFailedLoginAt == nil {
+		t.Errorf("stored user = %+v, want the lockout kept by the update", stored)
+	}
+	if stored.Password != user.Password {
+		t.Error("the update changed the password")
+	}
+}
+
+func TestUserRepositoryUpdateKeepsDisabling(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	users := dbmodel.NewUserRepository(db)
+
+	user, err := users.Create(ctx, &dbmodel.User{Email: "alice@example.com", Password: "Str0ng-Passw0rd", Role: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An administrator edits the user while another one disables them.
+	edited, err := users.FindById(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.SetDisabled(ctx, user, true); err != nil {
+		t.Fatal(err)
+	}
+
+	edited.Email = "alice.durand@example.com"
+	if _, err := users.Update(ctx, edited); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := users.FindById(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.IsDisabled() {
+		t.Error("the update enabled the disabled user")
+	}
+	if stored.Email != "alice.durand@example.com" {
+		t.Errorf("stored email = %q, want the edited one", stored.Email)
+	}
+
+	if err := users.SetDisabled(ctx, stored, false); err != nil {
+		t.Fatal(err)
+	}
+	if enabled, err := users.FindById(ctx, user.ID); err != nil || enabled.IsDisabled() {
+		t.Errorf("user after enabling = %+v, %v, want them enabled", enabled, err)
+	}
+}
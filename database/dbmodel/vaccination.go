@@ -0,0 +1,142 @@
+package dbmodel
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Vaccination struct {
+	ID             uint `gorm:"primarykey"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+	Version        uint           `gorm:"not null;default:1"`
+	CatID          uint           `gorm:"index"`
+	Cat            *Cat           `gorm:"foreignKey:CatID"`
+	VaccineName    string         `gorm:"index"`
+	LotNumber      string         `gorm:"type:varchar(50)"`
+	Manufacturer   string
+	AdministeredAt time.Time
+	VeterinarianID *uint         `gorm:"index"`
+	Veterinarian   *Veterinarian `gorm:"foreignKey:VeterinarianID"`
+	NextDueAt      *time.Time    `gorm:"index"`
+	VisitID        *uint         `gorm:"index"`
+	Notes          string        `gorm:"type:text"`
+}
+
+type VaccinationRepository interface {
+	Create(ctx context.Context, vaccination *Vaccination) (*Vaccination, error)
+	FindAll(ctx context.Context, opts QueryOptions) ([]*Vaccination, int64, error)
+	FindById(ctx context.Context, id uint) (*Vaccination, error)
+	Update(ctx context.Context, vaccination *Vaccination) (*Vaccination, error)
+	Delete(ctx context.Context, id uint, vaccination *Vaccination) error
+	Restore(ctx context.Context, id uint) (*Vaccination, error)
+	FindDue(ctx context.Context, before time.Time) ([]Vaccination, error)
+}
+
+type vaccinationRepository struct {
+	db *gorm.DB
+}
+
+func NewVaccinationRepository(db *gorm.DB) VaccinationRepository {
+	return &vaccinationRepository{db: db}
+}
+
+// Delete marks the vaccination as deleted, provided it is still at the version it was read with.
+func (r *vaccinationRepository) Delete(ctx context.Context, id uint, vaccination *Vaccination) error {
+	return audited(ctx, r.db, AuditActionDelete, "vaccination", id, vaccination, func(tx *gorm.DB) error {
+		return deleteVersioned(tx, &Vaccination{}, id, vaccination.Version, tx.NowFunc())
+	})
+}
+
+// Restore brings back a deleted vaccination. Its cat must not be deleted.
+func (r *vaccinationRepository) Restore(ctx context.Context, id uint) (*Vaccination, error) {
+	var vaccination Vaccination
+	err := restoreDeleted(ctx, r.db, "vaccination", id, &vaccination, func(tx *gorm.DB, deletedAt time.Time) error {
+		return requireLive(tx, &Cat{}, vaccination.CatID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.FindById(ctx, id)
+}
+
+func (r *vaccinationRepository) FindById(ctx context.Context, id uint) (*Vaccination, error) {
+	var vaccination Vaccination
+	if err := r.db.WithContext(ctx).Preload("Veterinarian").First(&vaccination, id).Error; err != nil {
+		return nil, err
+	}
+	return &vaccination, nil
+}
+
+func (r *vaccinationRepository) Update(ctx context.Context, vaccination *Vaccination) (*Vaccination, error) {
+	err := audited(ctx, r.db, AuditActionUpdate, "vaccination", vaccination.ID, vaccination, func(tx *gorm.DB) error {
+		return updateVersioned(tx, vaccination, &vaccination.Version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vaccination, nil
+}
+
+func (r *vaccinationRepository) Create(ctx context.Context, vaccination *Vaccination) (*Vaccination, error) {
+	err := audited(ctx, r.db, AuditActionCreate, "vaccination", 0, vaccination, func(tx *gorm.DB) error {
+		return tx.Omit(clause.Associations).Create(vaccination).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vaccination, nil
+}
+
+var vaccinationListSpec = listSpec{
+	sortable: map[string]string{
+		"id":              "id",
+		"vaccine_name":    "vaccine_name",
+		"administered_at": "administered_at",
+		"next_due_at":     "next_due_at",
+		"cat_id":          "cat_id",
+		"created_at":      "created_at",
+	},
+	filters: map[string]filterFunc{
+		"cat_id":            idFilter("cat_id"),
+		"veterinarian_id":   idFilter("veterinarian_id"),
+		"visit_id":          idFilter("visit_id"),
+		"vaccine_name":      containsFilter("vaccine_name"),
+		"lot_number":        caseInsensitiveFilter("lot_number"),
+		"administered_from": dateFilter("administered_at", ">="),
+		"administered_to":   dateFilter("administered_at", "<="),
+		"due_before":        dateFilter("next_due_at", "<="),
+	},
+}
+
+func (r *vaccinationRepository) FindAll(ctx context.Context, opts QueryOptions) ([]*Vaccination, int64, error) {
+	var vaccinations []*Vaccination
+	total, err := vaccinationListSpec.find(r.db.WithContext(ctx).Preload("Veterinarian"), &Vaccination{}, &vaccinations, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return vaccinations, total, nil
+}
+
+// FindDue returns, for every cat and vaccine, the latest shot when its next due date
+// is before the given time. Shots followed by a booster of the same vaccine are ignored.
+func (r *vaccinationRepository) FindDue(ctx context.Context, before time.Time) ([]Vaccination, error) {
+	var vaccinations []Vaccination
+	err := r.db.WithContext(ctx).Preload("Cat").
+		Where("next_due_at IS NOT NULL AND next_due_at < ?", before).
+		Where(`NOT EXISTS (SELECT 1 FROM vaccinations later
+			WHERE later.cat_id = vaccinations.cat_id
+			AND LOWER(later.vaccine_name) = LOWER(vaccinations.vaccine_name)
+			AND later.administered_at > vaccinations.administered_at
+			AND later.deleted_at IS NULL)`).
+		Order("next_due_at, cat_id").
+		Find(&vaccinations).Error
+	if err != nil {
+		return nil, err
+	}
+	return vaccinations, nil
+}
@@ -0,0 +1,122 @@
+package dbmodel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"gorm.io/gorm"
+)
+
+func TestVaccinationRepositoryCRUD(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	vaccinations := dbmodel.NewVaccinationRepository(db)
+
+	veterinarian, err := dbmodel.NewVeterinarianRepository(db).Create(ctx, &dbmodel.Veterinarian{Name: "Dr Martin", Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, err := dbmodel.NewCatRepository(db).Create(ctx, &dbmodel.Cat{Name: "Felix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vaccination, err := vaccinations.Create(ctx, &dbmodel.Vaccination{
+		CatID: cat.ID, VaccineName: "Rabies", LotNumber: "LOT-42", AdministeredAt: time.Now(), VeterinarianID: &veterinarian.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := vaccinations.FindById(ctx, vaccination.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Veterinarian == nil || found.Veterinarian.ID != veterinarian.ID || found.Version != 1 {
+		t.Errorf("FindById = %+v, want the vaccination at version 1 with its veterinarian", found)
+	}
+
+	found.Notes = "no reaction"
+	updated, err := vaccinations.Update(ctx, found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("version after update = %d, want 2", updated.Version)
+	}
+	vaccination.Notes = "stale"
+	if _, err := vaccinations.Update(ctx, vaccination); !errors.Is(err, dbmodel.ErrVersionConflict) {
+		t.Errorf("update of a stale vaccination: %v, want %v", err, dbmodel.ErrVersionConflict)
+	}
+
+	list, total, err := vaccinations.FindAll(ctx, dbmodel.QueryOptions{Filters: map[string]string{"lot_number": "lot-42"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || list[0].ID != vaccination.ID {
+		t.Errorf("FindAll by lot number = %+v, want the vaccination", list)
+	}
+
+	if err := vaccinations.Delete(ctx, vaccination.ID, updated); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vaccinations.FindById(ctx, vaccination.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindById after delete: %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	if _, err := vaccinations.Restore(ctx, vaccination.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVaccinationRepositoryFindDue(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	vaccinations := dbmodel.NewVaccinationRepository(db)
+	cats := dbmodel.NewCatRepository(db)
+
+	felix, err := cats.Create(ctx, &dbmodel.Cat{Name: "Felix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	garfield, err := cats.Create(ctx, &dbmodel.Cat{Name: "Garfield"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	shot := func(catID uint, vaccine string, administeredAgo, dueIn time.Duration) *dbmodel.Vaccination {
+		t.Helper()
+		nextDueAt := now.Add(dueIn)
+		vaccination, err := vaccinations.Create(ctx, &dbmodel.Vaccination{
+			CatID: catID, VaccineName: vaccine, AdministeredAt: now.Add(-administeredAgo), NextDueAt: &nextDueAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return vaccination
+	}
+	month := 30 * 24 * time.Hour
+
+	// Felix got a booster of his rabies shot, which is not due yet; his typhus shot is.
+	shot(felix.ID, "Rabies", 13*month, -month)
+	shot(felix.ID, "rabies", month, 11*month)
+	typhus := shot(felix.ID, "Typhus", 12*month, -time.Hour)
+	// Garfield's booster was deleted, so his first shot is due again.
+	first := shot(garfield.ID, "Rabies", 13*month, -month)
+	booster := shot(garfield.ID, "Rabies", month, 11*month)
+	if err := vaccinations.Delete(ctx, booster.ID, booster); err != nil {
+		t.Fatal(err)
+	}
+
+	due, err := vaccinations.FindDue(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 2 || due[0].ID != first.ID || due[1].ID != typhus.ID {
+		t.Fatalf("FindDue = %+v, want Garfield's first rabies shot then Felix's typhus shot", due)
+	}
+	if due[0].Cat == nil || due[0].Cat.Name != "Garfield" {
+		t.Errorf("due vaccination has cat %+v, want Garfield", due[0].Cat)
+	}
+}
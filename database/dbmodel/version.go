@@ -0,0 +1,54 @@
+package dbmodel
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrVersionConflict is returned when a record was changed since the version the write
+// was based on was read.
+var ErrVersionConflict = errors.New("record was modified since it was read")
+
+// Cats, visits, treatments, vaccinations and weight measurements have a Version column,
+// incremented by every write: updates and deletions only apply to the version the
+// caller read, so that two users editing the same record cannot overwrite each other.
+
+// nextVersion increments the version column in an update.
+var nextVersion = gorm.Expr("version + 1")
+
+// updateVersioned saves every column of entity but its associations, provided the stored
+// row still has the given version, which is then incremented.
+func updateVersioned(tx *gorm.DB, entity interface{}, version *uint) error {
+	read := *version
+	*version = read + 1
+	result := tx.Model(entity).Omit(clause.Associations).Select("*").Where("version = ?", read).Updates(entity)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		*version = read
+	}
+	return result.Error
+}
+
+// deleteVersioned marks the live row of model with the given ID and version as deleted.
+func deleteVersioned(tx *gorm.DB, model interface{}, id, version uint, deletedAt time.Time) error {
+	result := tx.Model(model).Where("id = ? AND version = ?", id, version).
+		UpdateColumns(map[string]interface{}{"deleted_at": deletedAt, "version": nextVersion})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// touchCat gives the cat a new version when its weight measurements change, as the
+// current weight is part of what is read about the cat.
+func touchCat(tx *gorm.DB, catID uint) error {
+	return tx.Model(&Cat{}).Where("id = ?", catID).UpdateColumn("version", nextVersion).Error
+}
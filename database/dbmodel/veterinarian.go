@@ -0,0 +1,166 @@
+package dbmodel
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+type Veterinarian struct {
+	ID            uint `gorm:"primarykey"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+	Name          string
+	LicenseNumber *string  `gorm:"type:varchar(50);uniqueIndex"`
+	Specialties   []string `gorm:"serializer:json"`
+	Active        bool
+	UserID        *uint `gorm:"uniqueIndex"`
+	User          *User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// ErrVeterinarianInUse is returned when deleting a veterinarian still referenced by visits, appointments or vaccinations,
+// deleted or not.
+var ErrVeterinarianInUse = errors.New("the veterinarian still has visits, appointments or vaccinations; deactivate them instead")
+
+type VeterinarianRepository interface {
+	Create(ctx context.Context, veterinarian *Veterinarian) (*Veterinarian, error)
+	FindAll(ctx context.Context, opts QueryOptions) ([]*Veterinarian, int64, error)
+	FindById(ctx context.Context, id uint) (*Veterinarian, error)
+	FindByUserID(ctx context.Context, userID uint) (*Veterinarian, error)
+	FindByLicenseNumber(ctx context.Context, licenseNumber string) (*Veterinarian, error)
+	Update(ctx context.Context, veterinarian *Veterinarian) (*Veterinarian, error)
+	Delete(ctx context.Context, id uint, veterinarian *Veterinarian) error
+	Restore(ctx context.Context, id uint) (*Veterinarian, error)
+}
+
+type veterinarianRepository struct {
+	db *gorm.DB
+}
+
+func NewVeterinarianRepository(db *gorm.DB) VeterinarianRepository {
+	return &veterinarianRepository{db: db}
+}
+
+// Delete marks the veterinarian as deleted, unless a record refers to them. Deleted records
+// count too, as they may be restored. The working hours are kept until the purge.
+func (r *veterinarianRepository) Delete(ctx context.Context, id uint, veterinarian *Veterinarian) error {
+	return audited(ctx, r.db, AuditActionDelete, "veterinarian", id, veterinarian, func(tx *gorm.DB) error {
+		var visits, appointments, vaccinations int64
+		if err := tx.Unscoped().Model(&Visit{}).Where("veterinarian_id = ?", id).Count(&visits).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&Appointment{}).Where("veterinarian_id = ?", id).Count(&appointments).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&Vaccination{}).Where("veterinarian_id = ?", id).Count(&vaccinations).Error; err != nil {
+			return err
+		}
+		if visits+appointments+vaccinations > 0 {
+			return ErrVeterinarianInUse
+		}
+		return tx.Delete(veterinarian, id).Error
+	})
+}
+
+// Restore brings back a deleted veterinarian with their working hours.
+func (r *veterinarianRepository) Restore(ctx context.Context, id uint) (*Veterinarian, error) {
+	var veterinarian Veterinarian
+	if err := restoreDeleted(ctx, r.db, "veterinarian", id, &veterinarian, nil); err != nil {
+		return nil, err
+	}
+	return r.FindById(ctx, id)
+}
+
+func (r *veterinarianRepository) FindById(ctx context.Context, id uint) (*Veterinarian, error) {
+	var veterinarian Veterinarian
+	if err := r.db.WithContext(ctx).First(&veterinarian, id).Error; err != nil {
+		return nil, err
+	}
+	return &veterinarian, nil
+}
+
+// FindByUserID and FindByLicenseNumber also find a deleted veterinarian, who keeps their
+// user and license number until purged.
+func (r *veterinarianRepository) FindByUserID(ctx context.Context, userID uint) (*Veterinarian, error) {
+	var veterinarian Veterinarian
+	if err := r.db.WithContext(ctx).Unscoped().Where("user_id = ?", userID).First(&veterinarian).Error; err != nil {
+		return nil, err
+	}
+	return &veterinarian, nil
+}
+
+func (r *veterinarianRepository) FindByLicenseNumber(ctx context.Context, licenseNumber string) (*Veterinarian, error) {
+	var veterinarian Veterinarian
+	if err := r.db.WithContext(ctx).Unscoped().Where("license_number = ?", licenseNumber).First(&veterinarian).Error; err != nil {
+		return nil, err
+	}
+	return &veterinarian, nil
+}
+
+func (r *veterinarianRepository) Update(ctx context.Context, veterinarian *Veterinarian) (*Veterinarian, error) {
+	err := audited(ctx, r.db, AuditActionUpdate, "veterinarian", veterinarian.ID, veterinarian, func(tx *gorm.DB) error {
+		return tx.Save(veterinarian).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return veterinarian, nil
+}
+
+func (r *veterinarianRepository) Create(ctx context.Context, veterinarian *Veterinarian) (*Veterinarian, error) {
+	err := audited(ctx, r.db, AuditActionCreate, "veterinarian", 0, veterinarian, func(tx *gorm.DB) error {
+		return tx.Create(veterinarian).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return veterinarian, nil
+}
+
+var veterinarianListSpec = listSpec{
+	sortable: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"name":      containsFilter("name"),
+		"active":    boolFilter("active"),
+		"specialty": containsFilter("specialties"),
+	},
+}
+
+func (r *veterinarianRepository) FindAll(ctx context.Context, opts QueryOptions) ([]*Veterinarian, int64, error) {
+	var veterinarians []*Veterinarian
+	total, err := veterinarianListSpec.find(r.db.WithContext(ctx), &Veterinarian{}, &veterinarians, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return veterinarians, total, nil
+}
+
+// veterinarianNameFilter matches records whose veterinarian's name matches the value,
+// ignoring case and "Dr" prefixes.
+func veterinarianNameFilter(column string) filterFunc {
+	return func(query *gorm.DB, value string) (*gorm.DB, error) {
+		key := "%" + VeterinarianKey(value) + "%"
+		return query.Where(column+" IN (SELECT id FROM veterinarians WHERE LOWER(name) LIKE ?)", key), nil
+	}
+}
+
+// VeterinarianKey reduces a free-text veterinarian name to a comparison key, so that
+// "Dr Martin", "dr. martin" and "Docteur  MARTIN" all map to "martin".
+func VeterinarianKey(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for len(fields) > 1 && (fields[0] == "dr" || fields[0] == "docteur" || fields[0] == "doctor") {
+		fields = fields[1:]
+	}
+	return strings.Join(fields, " ")
+}
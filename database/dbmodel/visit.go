@@ -1,19 +1,24 @@
 package dbmodel
 
 import (
+	"context"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Visit struct {
-	ID          uint `gorm:"primarykey"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	DeletedAt   *time.Time
-	Date        time.Time
-	Motif       string `gorm:"varchar(255)"`
-	Veterinaire string
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	Version   uint           `gorm:"not null;default:1"`
+	Date      time.Time
+	Motif     string `gorm:"varchar(255)"`
+
+	VeterinarianID *uint         `gorm:"index"`
+	Veterinarian   *Veterinarian `gorm:"foreignKey:VeterinarianID"`
 
 	CatID      uint
 	Cat        Cat         `gorm:"foreignKey:CatID"`
@@ -21,13 +26,14 @@ type Visit struct {
 }
 
 type VisitRepository interface {
-	Create(visit *Visit) (*Visit, error)
-	FindAll() ([]*Visit, error)
-	FindByCatID(catID uint) ([]Visit, error)
-	FindById(id uint) (*Visit, error)
-	Update(visit *Visit) (*Visit, error)
-	Delete(id uint, visit *Visit) error
-	FilterByMotifOrVeterinaire(motif string, veterinaire string) ([]Visit, error)
+	Create(ctx context.Context, visit *Visit) (*Visit, error)
+	FindAll(ctx context.Context, opts QueryOptions) ([]*Visit, int64, error)
+	FindByCatID(ctx context.Context, catID uint) ([]Visit, error)
+	FindById(ctx context.Context, id uint) (*Visit, error)
+	Update(ctx context.Context, visit *Visit) (*Visit, error)
+	Delete(ctx context.Context, id uint, visit *Visit) error
+	Restore(ctx context.Context, id uint) (*Visit, error)
+	FilterByMotifOrVeterinaire(ctx context.Context, motif string, veterinarianID uint, opts QueryOptions) ([]*Visit, int64, error)
 }
 
 type visitRepository struct {
@@ -38,43 +44,101 @@ func NewVisitRepository(db *gorm.DB) VisitRepository {
 	return &visitRepository{db: db}
 }
 
-func (r *visitRepository) Delete(id uint, visit *Visit) error {
-	return r.db.Delete(visit, id).Error
+// visitRecords selects the treatments of a visit, which are deleted and restored with it.
+func visitRecords(id uint) []cascade {
+	return []cascade{
+		{&Treatment{}, "treatment", "visit_id = ?", []interface{}{id}},
+	}
+}
+
+// Delete marks the visit and its treatments as deleted, provided the visit is still at
+// the version it was read with.
+func (r *visitRepository) Delete(ctx context.Context, id uint, visit *Visit) error {
+	return audited(ctx, r.db, AuditActionDelete, "visit", id, visit, func(tx *gorm.DB) error {
+		now := tx.NowFunc()
+		if err := softDeleteCascade(ctx, tx, now, visitRecords(id)...); err != nil {
+			return err
+		}
+		return deleteVersioned(tx, &Visit{}, id, visit.Version, now)
+	})
+}
+
+// Restore brings back a deleted visit with the treatments that were deleted along with
+// it. The cat of the visit must not be deleted.
+func (r *visitRepository) Restore(ctx context.Context, id uint) (*Visit, error) {
+	var visit Visit
+	err := restoreDeleted(ctx, r.db, "visit", id, &visit, func(tx *gorm.DB, deletedAt time.Time) error {
+		if err := requireLive(tx, &Cat{}, visit.CatID); err != nil {
+			return err
+		}
+		parentDeletedAt := tx.Unscoped().Model(&Visit{}).Select("deleted_at").Where("id = ?", id)
+		return restoreCascade(ctx, tx, parentDeletedAt, deletedAt, visitRecords(id)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.FindById(ctx, id)
 }
 
-func (r *visitRepository) FindById(id uint) (*Visit, error) {
+func (r *visitRepository) FindById(ctx context.Context, id uint) (*Visit, error) {
 	var visit Visit
-	if err := r.db.First(&visit, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Veterinarian").First(&visit, id).Error; err != nil {
 		return nil, err
 	}
 	return &visit, nil
 }
 
-func (r *visitRepository) Update(visit *Visit) (*Visit, error) {
-	if err := r.db.Save(visit).Error; err != nil {
+func (r *visitRepository) Update(ctx context.Context, visit *Visit) (*Visit, error) {
+	err := audited(ctx, r.db, AuditActionUpdate, "visit", visit.ID, visit, func(tx *gorm.DB) error {
+		return updateVersioned(tx, visit, &visit.Version)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return visit, nil
 }
 
-func (r *visitRepository) Create(visit *Visit) (*Visit, error) {
-	if err := r.db.Create(visit).Error; err != nil {
+func (r *visitRepository) Create(ctx context.Context, visit *Visit) (*Visit, error) {
+	err := audited(ctx, r.db, AuditActionCreate, "visit", 0, visit, func(tx *gorm.DB) error {
+		return tx.Omit(clause.Associations).Create(visit).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return visit, nil
 }
 
-func (r *visitRepository) FindAll() ([]*Visit, error) {
+var visitListSpec = listSpec{
+	sortable: map[string]string{
+		"id":              "id",
+		"date":            "date",
+		"motif":           "motif",
+		"veterinarian_id": "veterinarian_id",
+		"cat_id":          "cat_id",
+		"created_at":      "created_at",
+	},
+	filters: map[string]filterFunc{
+		"cat_id":          idFilter("cat_id"),
+		"motif":           containsFilter("motif"),
+		"veterinarian_id": idFilter("veterinarian_id"),
+		"veterinaire":     veterinarianNameFilter("veterinarian_id"),
+		"date_from":       dateFilter("date", ">="),
+		"date_to":         dateFilter("date", "<="),
+	},
+}
+
+func (r *visitRepository) FindAll(ctx context.Context, opts QueryOptions) ([]*Visit, int64, error) {
 	var visits []*Visit
-	if err := r.db.Find(&visits).Error; err != nil {
-		return nil, err
+	total, err := visitListSpec.find(r.db.WithContext(ctx).Preload("Veterinarian"), &Visit{}, &visits, opts)
+	if err != nil {
+		return nil, 0, err
 	}
-	return visits, nil
+	return visits, total, nil
 }
 
-func (r *visitRepository) FindByCatID(catID uint) ([]Visit, error) {
+func (r *visitRepository) FindByCatID(ctx context.Context, catID uint) ([]Visit, error) {
 	var visits []Visit
-	if err := r.db.
+	if err := r.db.WithContext(ctx).Preload("Veterinarian").
 		Where("cat_id = ?", catID).
 		Find(&visits).Error; err != nil {
 		return nil, err
@@ -82,17 +146,23 @@ func (r *visitRepository) FindByCatID(catID uint) ([]Visit, error) {
 	return visits, nil
 }
 
-func (r *visitRepository) FilterByMotifOrVeterinaire(motif string, veterinaire string) ([]Visit, error) {
-	var visits []Visit
-	query := r.db.Model(&Visit{})
+// FilterByMotifOrVeterinaire lists the visits with exactly the given motif and
+// veterinarian, either of which may be left empty; opts only paginates and sorts them.
+func (r *visitRepository) FilterByMotifOrVeterinaire(ctx context.Context, motif string, veterinarianID uint, opts QueryOptions) ([]*Visit, int64, error) {
+	query := r.db.WithContext(ctx).Preload("Veterinarian")
 	if motif != "" {
 		query = query.Where("motif = ?", motif)
 	}
-	if veterinaire != "" {
-		query = query.Where("veterinaire = ?", veterinaire)
+	if veterinarianID != 0 {
+		query = query.Where("veterinarian_id = ?", veterinarianID)
 	}
-	if err := query.Find(&visits).Error; err != nil {
-		return nil, err
+	opts.Filters = nil
+	opts.IncludeDeleted = false
+
+	var visits []*Visit
+	total, err := visitListSpec.find(query, &Visit{}, &visits, opts)
+	if err != nil {
+		return nil, 0, err
 	}
-	return visits, nil
+	return visits, total, nil
 }
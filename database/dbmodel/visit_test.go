@@ -0,0 +1,196 @@
+package dbmodel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"gorm.io/gorm"
+)
+
+func TestVisitRepositoryCRUD(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	visits := dbmodel.NewVisitRepository(db)
+
+	veterinarian, err := dbmodel.NewVeterinarianRepository(db).Create(ctx, &dbmodel.Veterinarian{Name: "Dr Martin", Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, err := dbmodel.NewCatRepository(db).Create(ctx, &dbmodel.Cat{Name: "Felix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	visit, err := visits.Create(ctx, &dbmodel.Visit{Date: date, Motif: "checkup", CatID: cat.ID, VeterinarianID: &veterinarian.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if visit.ID == 0 || visit.Version != 1 {
+		t.Fatalf("created visit has ID %d and version %d, want an ID and version 1", visit.ID, visit.Version)
+	}
+	if _, err := visits.Create(ctx, &dbmodel.Visit{Date: date.AddDate(0, 1, 0), Motif: "vaccine", CatID: cat.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := visits.FindById(ctx, visit.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Veterinarian == nil || found.Veterinarian.Name != "Dr Martin" || !found.Date.Equal(date) {
+		t.Errorf("FindById = %+v, want the visit with its veterinarian", found)
+	}
+
+	found.Motif = "limping"
+	updated, err := visits.Update(ctx, found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("version after update = %d, want 2", updated.Version)
+	}
+	visit.Motif = "stale"
+	if _, err := visits.Update(ctx, visit); !errors.Is(err, dbmodel.ErrVersionConflict) {
+		t.Errorf("update of a stale visit: %v, want %v", err, dbmodel.ErrVersionConflict)
+	}
+
+	byCat, err := visits.FindByCatID(ctx, cat.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byCat) != 2 {
+		t.Errorf("FindByCatID found %d visits, want 2", len(byCat))
+	}
+	list, total, err := visits.FindAll(ctx, dbmodel.QueryOptions{
+		Filters: map[string]string{"veterinaire": "dr. MARTIN"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || list[0].ID != visit.ID {
+		t.Errorf("FindAll by veterinarian name = %d visits, want the visit with Dr Martin", total)
+	}
+	list, total, err = visits.FindAll(ctx, dbmodel.QueryOptions{Sort: []dbmodel.SortField{{Field: "date", Desc: true}}, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(list) != 1 || list[0].Motif != "vaccine" {
+		t.Errorf("first page of the visits by date = %+v of %d, want the latest visit of 2", list, total)
+	}
+	if _, _, err := visits.FindAll(ctx, dbmodel.QueryOptions{Sort: []dbmodel.SortField{{Field: "password"}}}); !errors.Is(err, dbmodel.ErrInvalidQuery) {
+		t.Errorf("sort on an unknown field: %v, want %v", err, dbmodel.ErrInvalidQuery)
+	}
+	filtered, total, err := visits.FilterByMotifOrVeterinaire(ctx, "limping", veterinarian.ID, dbmodel.QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || filtered[0].ID != visit.ID {
+		t.Errorf("FilterByMotifOrVeterinaire = %d visits, want the limping visit", total)
+	}
+
+	if err := visits.Delete(ctx, visit.ID, updated); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := visits.FindById(ctx, visit.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindById after delete: %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	if _, err := visits.Restore(ctx, visit.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := visits.FindById(ctx, visit.ID); err != nil {
+		t.Errorf("FindById after restore: %v", err)
+	}
+}
+
+func TestTreatmentRepositoryCRUD(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	visits := dbmodel.NewVisitRepository(db)
+	treatments := dbmodel.NewTreatmentRipository(db)
+
+	cat, err := dbmodel.NewCatRepository(db).Create(ctx, &dbmodel.Cat{Name: "Felix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	visit, err := visits.Create(ctx, &dbmodel.Visit{Date: time.Now(), Motif: "infection", CatID: cat.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	treatment, err := treatments.Create(ctx, &dbmodel.Treatment{
+		Name: "Amoxicillin", Dosage: 50, Unit: "mg", Route: "oral", StartDate: &start, VisitID: visit.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := treatments.Create(ctx, &dbmodel.Treatment{Name: "Meloxicam", Route: "Injection", VisitID: visit.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := treatments.FindById(ctx, treatment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found.Dosage = 75
+	updated, err := treatments.Update(ctx, found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("version after update = %d, want 2", updated.Version)
+	}
+	if err := treatments.Delete(ctx, treatment.ID, treatment); !errors.Is(err, dbmodel.ErrVersionConflict) {
+		t.Errorf("delete of a stale treatment: %v, want %v", err, dbmodel.ErrVersionConflict)
+	}
+
+	byVisit, err := treatments.FindByVisitID(ctx, visit.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byVisit) != 2 {
+		t.Errorf("FindByVisitID found %d treatments, want 2", len(byVisit))
+	}
+	list, total, err := treatments.FindAll(ctx, dbmodel.QueryOptions{Filters: map[string]string{"route": "injection"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || list[0].Name != "Meloxicam" {
+		t.Errorf("FindAll by route = %+v, want the injected treatment", list)
+	}
+	list, total, err = treatments.FindAll(ctx, dbmodel.QueryOptions{Filters: map[string]string{"start_from": "2026-03-01"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || list[0].ID != treatment.ID {
+		t.Errorf("FindAll by start date = %+v, want the treatment started in March", list)
+	}
+
+	if err := treatments.Delete(ctx, treatment.ID, updated); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := treatments.FindById(ctx, treatment.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindById after delete: %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+
+	// A treatment deleted with its visit comes back with it; one deleted before does not.
+	visit, _ = visits.FindById(ctx, visit.ID)
+	if err := visits.Delete(ctx, visit.ID, visit); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := treatments.Restore(ctx, treatment.ID); !errors.Is(err, dbmodel.ErrParentDeleted) {
+		t.Errorf("restore of a treatment of a deleted visit: %v, want %v", err, dbmodel.ErrParentDeleted)
+	}
+	if _, err := visits.Restore(ctx, visit.ID); err != nil {
+		t.Fatal(err)
+	}
+	if byVisit, _ := treatments.FindByVisitID(ctx, visit.ID); len(byVisit) != 1 || byVisit[0].Name != "Meloxicam" {
+		t.Errorf("treatments after restoring the visit = %+v, want only the one deleted with it", byVisit)
+	}
+	if _, err := treatments.Restore(ctx, treatment.ID); err != nil {
+		t.Errorf("restore of a treatment of a live visit: %v", err)
+	}
+}
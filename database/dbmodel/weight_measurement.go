@@ -0,0 +1,145 @@
+package dbmodel
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Units accepted for a weight measurement, with their value in kilograms.
+var WeightUnits = map[string]float64{
+	"kg": 1,
+	"g":  0.001,
+	"lb": 0.45359237,
+}
+
+type WeightMeasurement struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	Version    uint           `gorm:"not null;default:1"`
+	CatID      uint           `gorm:"index:idx_weight_cat_measured"`
+	Value      float64
+	Unit       string    `gorm:"type:varchar(5)"`
+	Kilograms  float64   `json:"-"`
+	MeasuredAt time.Time `gorm:"index:idx_weight_cat_measured"`
+	VisitID    *uint     `gorm:"index"`
+	Notes      string    `gorm:"type:text"`
+}
+
+// BeforeSave keeps the normalized weight used for trends and filters in sync with the value.
+func (m *WeightMeasurement) BeforeSave(tx *gorm.DB) error {
+	m.Kilograms = m.Value * WeightUnits[m.Unit]
+	return nil
+}
+
+type WeightMeasurementRepository interface {
+	Create(ctx context.Context, measurement *WeightMeasurement) (*WeightMeasurement, error)
+	FindAll(ctx context.Context, opts QueryOptions) ([]*WeightMeasurement, int64, error)
+	FindById(ctx context.Context, id uint) (*WeightMeasurement, error)
+	Delete(ctx context.Context, id uint, measurement *WeightMeasurement) error
+	Restore(ctx context.Context, catID, id uint) (*WeightMeasurement, error)
+	Latest(ctx context.Context, catID uint) (*WeightMeasurement, error)
+	LatestBefore(ctx context.Context, catID uint, before time.Time) (*WeightMeasurement, error)
+}
+
+type weightMeasurementRepository struct {
+	db *gorm.DB
+}
+
+func NewWeightMeasurementRepository(db *gorm.DB) WeightMeasurementRepository {
+	return &weightMeasurementRepository{db: db}
+}
+
+// Delete marks the measurement as deleted, provided it is still at the version it was read with.
+func (r *weightMeasurementRepository) Delete(ctx context.Context, id uint, measurement *WeightMeasurement) error {
+	return audited(ctx, r.db, AuditActionDelete, "weight", id, measurement, func(tx *gorm.DB) error {
+		if err := deleteVersioned(tx, &WeightMeasurement{}, id, measurement.Version, tx.NowFunc()); err != nil {
+			return err
+		}
+		return touchCat(tx, measurement.CatID)
+	})
+}
+
+// Restore brings back a deleted measurement of the cat. The cat must not be deleted.
+func (r *weightMeasurementRepository) Restore(ctx context.Context, catID, id uint) (*WeightMeasurement, error) {
+	var measurement WeightMeasurement
+	if err := r.db.WithContext(ctx).Unscoped().Where("cat_id = ?", catID).First(&measurement, id).Error; err != nil {
+		return nil, err
+	}
+	err := restoreDeleted(ctx, r.db, "weight", id, &measurement, func(tx *gorm.DB, deletedAt time.Time) error {
+		if err := requireLive(tx, &Cat{}, measurement.CatID); err != nil {
+			return err
+		}
+		return touchCat(tx, measurement.CatID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.FindById(ctx, id)
+}
+
+func (r *weightMeasurementRepository) FindById(ctx context.Context, id uint) (*WeightMeasurement, error) {
+	var measurement WeightMeasurement
+	if err := r.db.WithContext(ctx).First(&measurement, id).Error; err != nil {
+		return nil, err
+	}
+	return &measurement, nil
+}
+
+func (r *weightMeasurementRepository) Create(ctx context.Context, measurement *WeightMeasurement) (*WeightMeasurement, error) {
+	err := audited(ctx, r.db, AuditActionCreate, "weight", 0, measurement, func(tx *gorm.DB) error {
+		if err := tx.Create(measurement).Error; err != nil {
+			return err
+		}
+		return touchCat(tx, measurement.CatID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return measurement, nil
+}
+
+var weightMeasurementListSpec = listSpec{
+	sortable: map[string]string{
+		"id":          "id",
+		"measured_at": "measured_at",
+		"value":       "kilograms",
+		"created_at":  "created_at",
+	},
+	filters: map[string]filterFunc{
+		"cat_id":        idFilter("cat_id"),
+		"visit_id":      idFilter("visit_id"),
+		"measured_from": dateFilter("measured_at", ">="),
+		"measured_to":   dateFilter("measured_at", "<="),
+	},
+}
+
+func (r *weightMeasurementRepository) FindAll(ctx context.Context, opts QueryOptions) ([]*WeightMeasurement, int64, error) {
+	var measurements []*WeightMeasurement
+	total, err := weightMeasurementListSpec.find(r.db.WithContext(ctx), &WeightMeasurement{}, &measurements, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return measurements, total, nil
+}
+
+func (r *weightMeasurementRepository) Latest(ctx context.Context, catID uint) (*WeightMeasurement, error) {
+	return r.LatestBefore(ctx, catID, time.Now())
+}
+
+// LatestBefore returns the last measurement taken at or before the given time.
+func (r *weightMeasurementRepository) LatestBefore(ctx context.Context, catID uint, before time.Time) (*WeightMeasurement, error) {
+	var measurement WeightMeasurement
+	if err := r.db.WithContext(ctx).Where("cat_id = ? AND measured_at <= ?", catID, before.UTC()).
+		Order("measured_at DESC, id DESC").
+		First(&measurement).Error; err != nil {
+		return nil, err
+	}
+	return &measurement, nil
+}
+
+// currentWeightSQL selects the latest weight of the cat in kilograms, for sorting and filtering cats.
+const currentWeightSQL = "(SELECT kilograms FROM weight_measurements WHERE weight_measurements.cat_id = cats.id AND weight_measurements.deleted_at IS NULL ORDER BY measured_at DESC, id DESC LIMIT 1)"
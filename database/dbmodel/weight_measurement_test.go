@@ -0,0 +1,82 @@
+package dbmodel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"gorm.io/gorm"
+)
+
+func TestWeightMeasurementRepository(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	cats := dbmodel.NewCatRepository(db)
+	weights := dbmodel.NewWeightMeasurementRepository(db)
+
+	cat, err := cats.Create(ctx, &dbmodel.Cat{Name: "Felix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	first, err := weights.Create(ctx, &dbmodel.WeightMeasurement{CatID: cat.ID, Value: 4200, Unit: "g", MeasuredAt: now.Add(-48 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Kilograms != 4.2 {
+		t.Errorf("kilograms of 4200 g = %v, want 4.2", first.Kilograms)
+	}
+	second, err := weights.Create(ctx, &dbmodel.WeightMeasurement{CatID: cat.ID, Value: 10, Unit: "lb", MeasuredAt: now.Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if touched, _ := cats.FindById(ctx, cat.ID); touched.Version != cat.Version+2 {
+		t.Errorf("cat version after two measurements = %d, want %d", touched.Version, cat.Version+2)
+	}
+
+	latest, err := weights.Latest(ctx, cat.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.ID != second.ID {
+		t.Errorf("Latest = measurement %d, want %d", latest.ID, second.ID)
+	}
+	before, err := weights.LatestBefore(ctx, cat.ID, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.ID != first.ID {
+		t.Errorf("LatestBefore a day ago = measurement %d, want %d", before.ID, first.ID)
+	}
+
+	// A measurement read before another write of it cannot be deleted.
+	stale, _ := weights.FindById(ctx, second.ID)
+	current, _ := weights.FindById(ctx, second.ID)
+	if err := db.Model(&dbmodel.WeightMeasurement{}).Where("id = ?", second.ID).
+		UpdateColumn("version", gorm.Expr("version + 1")).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := weights.Delete(ctx, stale.ID, stale); !errors.Is(err, dbmodel.ErrVersionConflict) {
+		t.Errorf("delete of a stale measurement: %v, want %v", err, dbmodel.ErrVersionConflict)
+	}
+	current.Version++
+	if err := weights.Delete(ctx, current.ID, current); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := weights.FindById(ctx, second.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindById after delete: %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	if latest, err := weights.Latest(ctx, cat.ID); err != nil || latest.ID != first.ID {
+		t.Errorf("Latest after deleting the last measurement = %+v, %v, want measurement %d", latest, err, first.ID)
+	}
+	assertActions(t, auditEntries(t, db, "weight", second.ID), dbmodel.AuditActionCreate, dbmodel.AuditActionDelete)
+
+	if _, err := weights.Restore(ctx, cat.ID, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if latest, err := weights.Latest(ctx, cat.ID); err != nil || latest.ID != second.ID {
+		t.Errorf("Latest after the restore = %+v, %v, want measurement %d", latest, err, second.ID)
+	}
+}
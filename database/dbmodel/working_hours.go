@@ -0,0 +1,78 @@
+package dbmodel
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WorkingHours is one opening window of a veterinarian on a weekday.
+// Start and End are "HH:MM" wall-clock times in the clinic timezone.
+type WorkingHours struct {
+	ID             uint `gorm:"primarykey"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	VeterinarianID uint         `gorm:"index"`
+	Weekday        time.Weekday `gorm:"type:int" swaggertype:"integer"`
+	Start          string       `gorm:"type:varchar(5)"`
+	End            string       `gorm:"type:varchar(5)"`
+}
+
+type WorkingHoursRepository interface {
+	FindByVeterinarian(ctx context.Context, veterinarianID uint) ([]WorkingHours, error)
+	FindVeterinarianIDs(ctx context.Context) ([]uint, error)
+	Replace(ctx context.Context, veterinarianID uint, hours []WorkingHours) ([]WorkingHours, error)
+}
+
+type workingHoursRepository struct {
+	db *gorm.DB
+}
+
+func NewWorkingHoursRepository(db *gorm.DB) WorkingHoursRepository {
+	return &workingHoursRepository{db: db}
+}
+
+func (r *workingHoursRepository) FindByVeterinarian(ctx context.Context, veterinarianID uint) ([]WorkingHours, error) {
+	var hours []WorkingHours
+	if err := r.db.WithContext(ctx).
+		Where("veterinarian_id = ?", veterinarianID).
+		Order("weekday, start").
+		Find(&hours).Error; err != nil {
+		return nil, err
+	}
+	return hours, nil
+}
+
+// FindVeterinarianIDs lists the active veterinarians that have working hours.
+func (r *workingHoursRepository) FindVeterinarianIDs(ctx context.Context) ([]uint, error) {
+	var ids []uint
+	if err := r.db.WithContext(ctx).Model(&WorkingHours{}).
+		Where("veterinarian_id IN (SELECT id FROM veterinarians WHERE active = ?)", true).
+		Distinct("veterinarian_id").
+		Order("veterinarian_id").
+		Pluck("veterinarian_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Replace swaps the whole weekly schedule of a veterinarian.
+func (r *workingHoursRepository) Replace(ctx context.Context, veterinarianID uint, hours []WorkingHours) ([]WorkingHours, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("veterinarian_id = ?", veterinarianID).Delete(&WorkingHours{}).Error; err != nil {
+			return err
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		for i := range hours {
+			hours[i].VeterinarianID = veterinarianID
+		}
+		return tx.Create(&hours).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hours, nil
+}
@@ -0,0 +1,222 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The models below are frozen copies of the dbmodel types as they were when the schema
+// became versioned. Later schema changes go in new migrations, never here, so that this
+// migration keeps creating the same tables. On a database created before migrations
+// existed, AutoMigrate only adds what is missing.
+
+type owner struct {
+	ID               uint `gorm:"primarykey"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        *time.Time
+	Name             string
+	Phone            string `gorm:"type:varchar(30);index"`
+	Email            string `gorm:"type:varchar(255);index"`
+	Address          string
+	PreferredContact string `gorm:"type:varchar(20)"`
+	Notes            string `gorm:"type:text"`
+	Cats             []cat  `gorm:"foreignKey:OwnerID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+}
+
+type cat struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	Name      string
+	Age       int `gorm:"type:int"`
+	Breed     string
+	OwnerID   *uint   `gorm:"index"`
+	Visits    []visit `gorm:"foreignKey:CatID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+}
+
+type visit struct {
+	ID             uint `gorm:"primarykey"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+	Date           time.Time
+	Motif          string        `gorm:"varchar(255)"`
+	VeterinarianID *uint         `gorm:"index"`
+	Veterinarian   *veterinarian `gorm:"foreignKey:VeterinarianID"`
+	CatID          uint
+	Treatments     []treatment `gorm:"constraint:OnDelete:CASCADE;"`
+}
+
+type treatment struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	Name      string
+	Dosage    float64
+	Unit      string `gorm:"type:varchar(20)"`
+	Route     string `gorm:"type:varchar(50)"`
+	Frequency string
+	StartDate *time.Time
+	EndDate   *time.Time
+	Notes     string `gorm:"type:text"`
+	VisitID   uint   `gorm:"index"`
+}
+
+type user struct {
+	gorm.Model
+	Email               string `gorm:"type:varchar(255);unique;not null"`
+	Password            string `gorm:"type:varchar(255);not null"`
+	Role                string `gorm:"type:varchar(50);default:'user'"`
+	FailedLoginAttempts int
+	LockedUntil         *time.Time
+	LastLoginAt         *time.Time
+	LastFailedLoginAt   *time.Time
+	DisabledAt          *time.Time
+}
+
+type veterinarian struct {
+	ID            uint `gorm:"primarykey"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     *time.Time
+	Name          string
+	LicenseNumber *string  `gorm:"type:varchar(50);uniqueIndex"`
+	Specialties   []string `gorm:"serializer:json"`
+	Active        bool
+	UserID        *uint `gorm:"uniqueIndex"`
+	User          *user `gorm:"foreignKey:UserID"`
+}
+
+type appointment struct {
+	ID             uint `gorm:"primarykey"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      *time.Time
+	CatID          uint      `gorm:"index"`
+	Cat            cat       `gorm:"foreignKey:CatID"`
+	VeterinarianID uint      `gorm:"index"`
+	StartsAt       time.Time `gorm:"index"`
+	EndsAt         time.Time
+	Status         string `gorm:"type:varchar(20);default:'booked'"`
+	Reason         string `gorm:"varchar(255)"`
+	Notes          string `gorm:"type:text"`
+	VisitID        *uint
+}
+
+type workingHours struct {
+	ID             uint `gorm:"primarykey"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	VeterinarianID uint   `gorm:"index"`
+	Weekday        int    `gorm:"type:int"`
+	Start          string `gorm:"type:varchar(5)"`
+	End            string `gorm:"type:varchar(5)"`
+}
+
+type vaccination struct {
+	ID             uint `gorm:"primarykey"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+	CatID          uint           `gorm:"index"`
+	Cat            *cat           `gorm:"foreignKey:CatID"`
+	VaccineName    string         `gorm:"index"`
+	LotNumber      string         `gorm:"type:varchar(50)"`
+	Manufacturer   string
+	AdministeredAt time.Time
+	VeterinarianID *uint         `gorm:"index"`
+	Veterinarian   *veterinarian `gorm:"foreignKey:VeterinarianID"`
+	NextDueAt      *time.Time    `gorm:"index"`
+	VisitID        *uint         `gorm:"index"`
+	Notes          string        `gorm:"type:text"`
+}
+
+type weightMeasurement struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	CatID      uint           `gorm:"index:idx_weight_cat_measured"`
+	Value      float64
+	Unit       string `gorm:"type:varchar(5)"`
+	Kilograms  float64
+	MeasuredAt time.Time `gorm:"index:idx_weight_cat_measured"`
+	VisitID    *uint     `gorm:"index"`
+	Notes      string    `gorm:"type:text"`
+}
+
+type refreshToken struct {
+	ID              uint `gorm:"primarykey"`
+	CreatedAt       time.Time
+	UserID          uint      `gorm:"index"`
+	FamilyID        string    `gorm:"type:varchar(64);index"`
+	TokenHash       string    `gorm:"type:varchar(64);uniqueIndex"`
+	ExpiresAt       time.Time `gorm:"index"`
+	FamilyExpiresAt time.Time
+	UsedAt          *time.Time
+	RevokedAt       *time.Time
+}
+
+type passwordResetToken struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UserID    uint      `gorm:"index"`
+	TokenHash string    `gorm:"type:varchar(64);uniqueIndex"`
+	ExpiresAt time.Time `gorm:"index"`
+	UsedAt    *time.Time
+}
+
+type permission struct {
+	ID          uint   `gorm:"primarykey"`
+	Name        string `gorm:"type:varchar(100);uniqueIndex;not null"`
+	Description string
+}
+
+type role struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Name        string `gorm:"type:varchar(50);uniqueIndex;not null"`
+	Description string
+	Permissions []permission `gorm:"many2many:role_permissions;constraint:OnDelete:CASCADE;"`
+}
+
+type auditLog struct {
+	ID         uint      `gorm:"primarykey"`
+	CreatedAt  time.Time `gorm:"index"`
+	ActorID    *uint     `gorm:"index"`
+	ActorEmail string
+	Action     string                 `gorm:"type:varchar(20)"`
+	EntityType string                 `gorm:"type:varchar(50);index:idx_audit_entity"`
+	EntityID   uint                   `gorm:"index:idx_audit_entity"`
+	Changes    map[string]interface{} `gorm:"serializer:json"`
+	RequestID  string                 `gorm:"type:varchar(100)"`
+}
+
+// initialSchemaModels are listed in the order AutoMigrate used to create them.
+var initialSchemaModels = []interface{}{
+	&cat{}, &visit{}, &treatment{}, &user{}, &owner{}, &appointment{}, &workingHours{},
+	&veterinarian{}, &vaccination{}, &weightMeasurement{}, &refreshToken{},
+	&passwordResetToken{}, &permission{}, &role{}, &auditLog{},
+}
+
+func init() {
+	register("create the tables cats, visits, treatments, users, owners, appointments, "+
+		"working_hours, veterinarians, vaccinations, weight_measurements, refresh_tokens, "+
+		"password_reset_tokens, permissions, roles, role_permissions and audit_logs of the "+
+		"models above, or add their missing columns and indexes",
+		func(tx *gorm.DB) error {
+			return tx.AutoMigrate(initialSchemaModels...)
+		},
+		func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("role_permissions", &auditLog{}, &role{}, &permission{},
+				&passwordResetToken{}, &refreshToken{}, &weightMeasurement{}, &vaccination{},
+				&workingHours{}, &appointment{}, &treatment{}, &visit{}, &cat{}, &owner{},
+				&veterinarian{}, &user{})
+		},
+	)
+}
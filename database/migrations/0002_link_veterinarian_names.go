@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"log"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// tables that stored the veterinarian as a free-text "veterinaire" column before
+// veterinarians became records.
+var legacyVeterinaireTables = []string{"visits", "appointments", "working_hours"}
+
+func init() {
+	register("create one active veterinarian per distinct veterinarianKey of the legacy "+
+		"veterinaire column of visits, appointments and working_hours, and set the missing "+
+		"veterinarian_id of their rows", linkVeterinarianNames, noop)
+}
+
+// linkVeterinarianNames creates one veterinarian per distinct legacy name (ignoring
+// case and "Dr" prefixes) and points the rows that still lack a veterinarian_id at it.
+func linkVeterinarianNames(tx *gorm.DB) error {
+	byKey := map[string]uint{}
+
+	var existing []veterinarian
+	if err := tx.Find(&existing).Error; err != nil {
+		return err
+	}
+	for _, vet := range existing {
+		byKey[veterinarianKey(vet.Name)] = vet.ID
+	}
+
+	mapped := 0
+	for _, table := range legacyVeterinaireTables {
+		if !tx.Migrator().HasColumn(table, "veterinaire") {
+			continue
+		}
+
+		var names []string
+		if err := tx.Table(table).
+			Where("veterinarian_id IS NULL OR veterinarian_id = 0").
+			Where("veterinaire IS NOT NULL AND veterinaire <> ''").
+			Distinct("veterinaire").
+			Pluck("veterinaire", &names).Error; err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			key := veterinarianKey(name)
+			if key == "" {
+				continue
+			}
+			id, ok := byKey[key]
+			if !ok {
+				vet := &veterinarian{Name: name, Active: true}
+				if err := tx.Create(vet).Error; err != nil {
+					return err
+				}
+				id = vet.ID
+				byKey[key] = id
+			}
+
+			result := tx.Table(table).
+				Where("veterinarian_id IS NULL OR veterinarian_id = 0").
+				Where("veterinaire = ?", name).
+				Update("veterinarian_id", id)
+			if result.Error != nil {
+				return result.Error
+			}
+			mapped += int(result.RowsAffected)
+		}
+	}
+
+	if mapped > 0 {
+		log.Printf("Linked %d legacy rows to veterinarian records", mapped)
+	}
+	return nil
+}
+
+// veterinarianKey reduces a free-text veterinarian name to a comparison key, so that
+// "Dr Martin", "dr. martin" and "Docteur  MARTIN" all map to "martin". It is a copy of
+// dbmodel.VeterinarianKey as it was when this migration was written.
+func veterinarianKey(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for len(fields) > 1 && (fields[0] == "dr" || fields[0] == "docteur" || fields[0] == "doctor") {
+		fields = fields[1:]
+	}
+	return strings.Join(fields, " ")
+}
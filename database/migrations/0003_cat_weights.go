@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register("create a weight measurement in grams, measured at updated_at, from the "+
+		"positive cats.weigth of the cats without measurements", migrateCatWeights, noop)
+}
+
+// migrateCatWeights turns the legacy cats.weigth column (grams) into a first weight
+// measurement for every cat that has no measurement yet.
+func migrateCatWeights(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn("cats", "weigth") {
+		return nil
+	}
+
+	var legacy []struct {
+		ID        uint
+		Weigth    int
+		UpdatedAt time.Time
+	}
+	if err := tx.Table("cats").
+		Select("id, weigth, updated_at").
+		Where("weigth > 0").
+		Where("NOT EXISTS (SELECT 1 FROM weight_measurements WHERE weight_measurements.cat_id = cats.id)").
+		Find(&legacy).Error; err != nil {
+		return err
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+
+	for _, cat := range legacy {
+		measurement := &weightMeasurement{
+			CatID:      cat.ID,
+			Value:      float64(cat.Weigth),
+			Unit:       "g",
+			Kilograms:  float64(cat.Weigth) / 1000,
+			MeasuredAt: cat.UpdatedAt.UTC(),
+			Notes:      "Poids repris de l'ancienne fiche",
+		}
+		if err := tx.Create(measurement).Error; err != nil {
+			return err
+		}
+	}
+	log.Printf("Created %d weight measurements from legacy cat weights", len(legacy))
+	return nil
+}
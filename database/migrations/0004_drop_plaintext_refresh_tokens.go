@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register("drop the users.refresh_token column", dropLegacyRefreshTokens, noop)
+}
+
+// dropLegacyRefreshTokens removes the users.refresh_token column, which stored the last
+// refresh token in clear text. Refresh tokens now live in their own table, so users
+// holding one of these tokens have to log in again.
+func dropLegacyRefreshTokens(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&user{}, "refresh_token") {
+		return nil
+	}
+	if err := tx.Migrator().DropColumn(&user{}, "refresh_token"); err != nil {
+		return err
+	}
+	log.Println("Dropped legacy users.refresh_token column")
+	return nil
+}
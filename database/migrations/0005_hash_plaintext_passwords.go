@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"log"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func init() {
+	register("replace the users.password values that are not bcrypt hashes by their bcrypt "+
+		"hash at the default cost", hashPlaintextPasswords, noop)
+}
+
+// hashPlaintextPasswords hashes the passwords that earlier versions stored in clear text
+// when an administrator updated a user, so that these users can log in again.
+func hashPlaintextPasswords(tx *gorm.DB) error {
+	var users []user
+	if err := tx.Where("password NOT LIKE ?", "$2_$%").Find(&users).Error; err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	for i := range users {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(users[i].Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&users[i]).Update("password", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+	}
+	log.Printf("Hashed %d plaintext user passwords", len(users))
+	return nil
+}
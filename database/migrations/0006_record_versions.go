@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// recordVersion is the column counting the writes to a clinical record, which updates
+// and deletions compare with the version the client read.
+type recordVersion struct {
+	Version uint `gorm:"not null;default:1"`
+}
+
+var versionedTables = []string{"cats", "visits", "treatments", "vaccinations", "weight_measurements"}
+
+func init() {
+	register("add the column version, not null and 1 by default, to cats, visits, "+
+		"treatments, vaccinations and weight_measurements",
+		func(tx *gorm.DB) error {
+			for _, table := range versionedTables {
+				migrator := tx.Table(table).Migrator()
+				if migrator.HasColumn(&recordVersion{}, "Version") {
+					continue
+				}
+				if err := migrator.AddColumn(&recordVersion{}, "Version"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		func(tx *gorm.DB) error {
+			for _, table := range versionedTables {
+				if err := tx.Table(table).Migrator().DropColumn(&recordVersion{}, "Version"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
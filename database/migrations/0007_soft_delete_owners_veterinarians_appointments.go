@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// Owners, veterinarians and appointments already had a deleted_at column, which deletions
+// did not use. They are now soft-deleted like the clinical records, which needs the same
+// index on deleted_at.
+var softDeletedTables = []string{"owners", "veterinarians", "appointments"}
+
+// Appointments are now deleted with their cat: those of the cats deleted before are
+// deleted at the same time as the cat, so that restoring the cat brings them back and
+// purging it no longer fails on them.
+const deleteAppointmentsOfDeletedCats = "UPDATE appointments SET deleted_at = " +
+	"(SELECT cats.deleted_at FROM cats WHERE cats.id = appointments.cat_id) " +
+	"WHERE deleted_at IS NULL AND cat_id IN (SELECT id FROM cats WHERE deleted_at IS NOT NULL)"
+
+func init() {
+	register("create the indexes idx_<table>_deleted_at of owners, veterinarians and "+
+		"appointments, then "+deleteAppointmentsOfDeletedCats,
+		func(tx *gorm.DB) error {
+			for _, table := range softDeletedTables {
+				index := "idx_" + table + "_deleted_at"
+				if tx.Migrator().HasIndex(table, index) {
+					continue
+				}
+				if err := tx.Exec("CREATE INDEX " + index + " ON " + table + " (deleted_at)").Error; err != nil {
+					return err
+				}
+			}
+			return tx.Exec(deleteAppointmentsOfDeletedCats).Error
+		},
+		func(tx *gorm.DB) error {
+			for _, table := range softDeletedTables {
+				if err := tx.Migrator().DropIndex(table, "idx_"+table+"_deleted_at"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// catDetachedOwner is the column keeping the deleted owner a cat belonged to, so that
+// restoring the owner gives the cat back. Owners deleted before it have already lost
+// their cats.
+type catDetachedOwner struct {
+	DetachedOwnerID *uint
+}
+
+const detachedOwnerIndex = "CREATE INDEX idx_cats_detached_owner_id ON cats (detached_owner_id)"
+
+func init() {
+	register("add the nullable column detached_owner_id to cats, then "+detachedOwnerIndex,
+		func(tx *gorm.DB) error {
+			migrator := tx.Table("cats").Migrator()
+			if !migrator.HasColumn(&catDetachedOwner{}, "DetachedOwnerID") {
+				if err := migrator.AddColumn(&catDetachedOwner{}, "DetachedOwnerID"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasIndex("cats", "idx_cats_detached_owner_id") {
+				return nil
+			}
+			return tx.Exec(detachedOwnerIndex).Error
+		},
+		func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex("cats", "idx_cats_detached_owner_id"); err != nil {
+				return err
+			}
+			return tx.Table("cats").Migrator().DropColumn(&catDetachedOwner{}, "DetachedOwnerID")
+		},
+	)
+}
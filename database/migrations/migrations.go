@@ -0,0 +1,248 @@
+// Package migrations versions the database schema. Each migration lives in its own file,
+// named NNNN_description.go, and registers from init a definition stating what it does,
+// an up and a down step. The version and the name come from the file name; they are
+// stored in the schema_migrations table when the migration is applied, with the checksum
+// of the version, the name and the definition.
+//
+// A migration that has been released must never be edited: add a new one instead. Changing
+// what a migration does means changing its definition, and a database on which the
+// migration ran with another checksum is refused. Reformatting the file or fixing its
+// comments does not change the checksum. For the same reason, migrations work on their own
+// copies of the models and helpers they need and never call dbmodel, whose later changes
+// would silently change what they do.
+package migrations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSchemaBehind is returned by Check when migrations are pending.
+	ErrSchemaBehind = errors.New("database schema is behind")
+	// ErrChecksumMismatch is returned when an applied migration was edited afterwards.
+	ErrChecksumMismatch = errors.New("applied migration was modified")
+	// ErrUnknownVersion is returned when the database has migrations this binary does not
+	// know, that is when it was migrated by a newer release.
+	ErrUnknownVersion = errors.New("database schema is ahead of this release")
+	// ErrIrreversible is returned when reverting a migration that has no down step.
+	ErrIrreversible = errors.New("migration cannot be reverted")
+)
+
+// Migration is one versioned step of the schema.
+type Migration struct {
+	Version  int
+	Name     string
+	Checksum string
+	up       func(tx *gorm.DB) error
+	down     func(tx *gorm.DB) error
+}
+
+var registry []*Migration
+
+// register adds the migration defined in the calling file. definition states what the
+// migration does, and changes whenever it does something else. A nil down step makes the
+// migration irreversible; noop is for the data migrations that have nothing to undo.
+func register(definition string, up, down func(tx *gorm.DB) error) {
+	_, file, _, _ := runtime.Caller(1)
+	file = filepath.Base(file)
+
+	prefix, name, ok := strings.Cut(strings.TrimSuffix(file, ".go"), "_")
+	version, err := strconv.Atoi(prefix)
+	if !ok || err != nil || version < 1 {
+		panic(fmt.Sprintf("migrations: %s is not named NNNN_description.go", file))
+	}
+	if definition == "" {
+		panic(fmt.Sprintf("migrations: %s has no definition", file))
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%04d_%s\n%s", version, name, definition)))
+
+	for _, existing := range registry {
+		if existing.Version == version {
+			panic(fmt.Sprintf("migrations: version %d is used twice", version))
+		}
+	}
+	registry = append(registry, &Migration{
+		Version:  version,
+		Name:     name,
+		Checksum: hex.EncodeToString(sum[:]),
+		up:       up,
+		down:     down,
+	})
+	sort.Slice(registry, func(i, j int) bool { return registry[i].Version < registry[j].Version })
+}
+
+func noop(tx *gorm.DB) error { return nil }
+
+// All lists the migrations of this release, oldest first.
+func All() []*Migration {
+	return registry
+}
+
+// schemaMigration records an applied migration.
+type schemaMigration struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"type:varchar(255);not null"`
+	Checksum  string `gorm:"type:varchar(64);not null"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// State tells whether a migration is applied, and when.
+type State struct {
+	*Migration
+	AppliedAt *time.Time
+}
+
+// Applied tells whether the migration has been run on the database.
+func (s State) Applied() bool {
+	return s.AppliedAt != nil
+}
+
+// Status lists every migration with its state. It fails if an applied migration was
+// modified or is unknown to this release. It only reads the database.
+func Status(db *gorm.DB) ([]State, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]State, 0, len(registry))
+	for _, migration := range registry {
+		state := State{Migration: migration}
+		if record, ok := applied[migration.Version]; ok {
+			if record.Checksum != migration.Checksum {
+				return nil, fmt.Errorf("%w: %04d_%s (stored checksum %s, release checksum %s)",
+					ErrChecksumMismatch, migration.Version, migration.Name, record.Checksum, migration.Checksum)
+			}
+			state.AppliedAt = &record.AppliedAt
+			delete(applied, migration.Version)
+		}
+		states = append(states, state)
+	}
+	if len(applied) > 0 {
+		versions := make([]int, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Ints(versions)
+		record := applied[versions[0]]
+		return nil, fmt.Errorf("%w: migration %04d_%s is applied but unknown", ErrUnknownVersion, record.Version, record.Name)
+	}
+	return states, nil
+}
+
+// Check returns ErrSchemaBehind if migrations are pending, and the errors of Status.
+func Check(db *gorm.DB) error {
+	states, err := Status(db)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, state := range states {
+		if !state.Applied() {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d migration(s) pending", ErrSchemaBehind, pending)
+	}
+	return nil
+}
+
+// Up applies the pending migrations in order, each in its own transaction, and returns
+// those it applied.
+func Up(db *gorm.DB) ([]*Migration, error) {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+	states, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []*Migration
+	for _, state := range states {
+		if state.Applied() {
+			continue
+		}
+		migration := state.Migration
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.Checksum,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("applying %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations, most recent first, and returns those
+// it reverted.
+func Down(db *gorm.DB, steps int) ([]*Migration, error) {
+	states, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []*Migration
+	for i := len(states) - 1; i >= 0 && len(done) < steps; i-- {
+		if !states[i].Applied() {
+			continue
+		}
+		migration := states[i].Migration
+		if migration.down == nil {
+			return done, fmt.Errorf("%w: %04d_%s", ErrIrreversible, migration.Version, migration.Name)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("reverting %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// appliedMigrations reads the schema_migrations table, which Up creates: a database
+// without it has no migration applied.
+func appliedMigrations(db *gorm.DB) (map[int]schemaMigration, error) {
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return map[int]schemaMigration{}, nil
+	}
+	var records []schemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]schemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
@@ -0,0 +1,106 @@
+package migrations
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to ":memory:" opens its own database.
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func TestStatusOnlyReads(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := Check(db); !errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("Check of an empty database: %v, want %v", err, ErrSchemaBehind)
+	}
+	if db.Migrator().HasTable(&schemaMigration{}) {
+		t.Error("Check created the schema_migrations table")
+	}
+}
+
+func TestUpRecordsChecksums(t *testing.T) {
+	db := newTestDB(t)
+
+	applied, err := Up(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(All()) {
+		t.Errorf("Up applied %d migrations, want %d", len(applied), len(All()))
+	}
+	if err := Check(db); err != nil {
+		t.Errorf("Check after Up: %v", err)
+	}
+
+	var record schemaMigration
+	if err := db.First(&record, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if record.Checksum != All()[0].Checksum || len(record.Checksum) != 64 {
+		t.Errorf("stored checksum %q, want %q", record.Checksum, All()[0].Checksum)
+	}
+
+	if err := db.Model(&record).Update("checksum", "edited").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(db); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Check of an edited migration: %v, want %v", err, ErrChecksumMismatch)
+	}
+	if _, err := Up(db); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Up over an edited migration: %v, want %v", err, ErrChecksumMismatch)
+	}
+}
+
+func TestStatusRefusesUnknownMigrations(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := Up(db); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Create(&schemaMigration{Version: 9999, Name: "from_the_future", Checksum: "unknown"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Status(db); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Status with an unknown migration: %v, want %v", err, ErrUnknownVersion)
+	}
+}
+
+func TestDownRevertsTheLastMigrations(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := Up(db); err != nil {
+		t.Fatal(err)
+	}
+
+	reverted, err := Down(db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := All()[len(All())-1]
+	if len(reverted) != 1 || reverted[0] != last {
+		t.Errorf("Down(1) reverted %v, want %04d_%s", reverted, last.Version, last.Name)
+	}
+	if err := Check(db); !errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("Check after Down: %v, want %v", err, ErrSchemaBehind)
+	}
+	if _, err := Up(db); err != nil {
+		t.Errorf("Up after Down: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"log"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"gorm.io/gorm"
+)
+
+// SeedRoles creates the permissions of the catalog, refreshes their descriptions, and
+// creates the default roles that do not exist yet. A permission added by a new release is also granted to the default roles
+// that should have it, but roles already in the database are otherwise left as the
+// admins configured them.
+func SeedRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var added []dbmodel.Permission
+		for _, entry := range dbmodel.PermissionCatalog {
+			permission := entry
+			result := tx.Where("name = ?", permission.Name).FirstOrCreate(&permission)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				added = append(added, permission)
+			} else if permission.Description != entry.Description {
+				if err := tx.Model(&permission).Update("description", entry.Description).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for name, permissionNames := range dbmodel.DefaultRoles {
+			var permissions []dbmodel.Permission
+			if err := tx.Where("name IN ?", permissionNames).Find(&permissions).Error; err != nil {
+				return err
+			}
+
+			var role dbmodel.Role
+			if err := tx.Where("name = ?", name).Limit(1).Find(&role).Error; err != nil {
+				return err
+			}
+			if role.ID == 0 {
+				role = dbmodel.Role{Name: name, Permissions: permissions}
+				if err := tx.Create(&role).Error; err != nil {
+					return err
+				}
+				log.Printf("Created default role %q", name)
+				continue
+			}
+
+			var granted []dbmodel.Permission
+			for _, permission := range added {
+				for _, wanted := range permissionNames {
+					if permission.Name == wanted {
+						granted = append(granted, permission)
+					}
+				}
+			}
+			if len(granted) > 0 {
+				if err := tx.Model(&role).Association("Permissions").Append(granted); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
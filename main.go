@@ -1,82 +1,88 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
 	_ "github.com/emmanuelYohore/vet-clinic-api/docs"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/appointment"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/audit"
 	"github.com/emmanuelYohore/vet-clinic-api/pkg/authentification"
 	"github.com/emmanuelYohore/vet-clinic-api/pkg/cat"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/owner"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/purge"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/role"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/timeout"
 	"github.com/emmanuelYohore/vet-clinic-api/pkg/treatment"
 	"github.com/emmanuelYohore/vet-clinic-api/pkg/user"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/vaccination"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/veterinarian"
 	"github.com/emmanuelYohore/vet-clinic-api/pkg/visit"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
 func Routes(configuration *config.Config) *chi.Mux {
 	router := chi.NewRouter()
+	router.Use(middleware.RequestID, problem.RequestIDHeader, audit.RequestID)
+	router.Use(timeout.Middleware(router, configuration))
+	if configuration.TrustProxyHeaders {
+		router.Use(middleware.RealIP)
+	}
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, http.StatusNotFound, "no route matches "+r.URL.Path)
+	})
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, http.StatusMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+	})
+
+	if len(configuration.CORSOrigins) > 0 {
+		router.Use(cors.Handler(cors.Options{
+			AllowedOrigins:   configuration.CORSOrigins,
+			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "If-Match", "If-None-Match", "X-Request-Id"},
+			ExposedHeaders:   []string{"ETag", "Link", "X-Request-Id", "X-Total-Count"},
+			AllowCredentials: true,
+			MaxAge:           300,
+		}))
+	}
 
 	router.Mount("/login", authentification.Routes(configuration))
 
 	router.Group(func(r chi.Router) {
-		r.Use(authentification.AuthMiddleware("your_secret_key"))
-		catRoutes := cat.Routes(configuration)
-		r.Group(func(cr chi.Router) {
-			cr.Use(authentification.RequireRole("admin", "user"))
-			cr.Get("/api/v1/cats", catRoutes.ServeHTTP)
-			cr.Get("/api/v1/cats/{id}", catRoutes.ServeHTTP)
-			cr.Get("/api/v1/cats/{id}/history", catRoutes.ServeHTTP)
-		})
-
-		r.Group(func(cr chi.Router) {
-			cr.Use(authentification.RequireRole("admin"))
-			cr.Post("/api/v1/cats", catRoutes.ServeHTTP)
-			cr.Put("/api/v1/cats/{id}", catRoutes.ServeHTTP)
-			cr.Delete("/api/v1/cats/{id}", catRoutes.ServeHTTP)
-		})
-
-		visitRoutes := visit.Routes(configuration)
-		r.Group(func(vr chi.Router) {
-			vr.Use(authentification.RequireRole("admin", "user"))
-			vr.Get("/api/v1/visits", visitRoutes.ServeHTTP)
-			vr.Get("/api/v1/visits/{id}", visitRoutes.ServeHTTP)
-		})
-
-		r.Group(func(vr chi.Router) {
-			vr.Use(authentification.RequireRole("admin"))
-			vr.Post("/api/v1/visits", visitRoutes.ServeHTTP)
-			vr.Put("/api/v1/visits/{id}", visitRoutes.ServeHTTP)
-			vr.Delete("/api/v1/visits/{id}", visitRoutes.ServeHTTP)
-		})
-
-		treatmentRoutes := treatment.Routes(configuration)
-		r.Group(func(tr chi.Router) {
-			tr.Use(authentification.RequireRole("admin", "user"))
-			tr.Get("/api/v1/treatments", treatmentRoutes.ServeHTTP)
-			tr.Get("/api/v1/treatments/{id}", treatmentRoutes.ServeHTTP)
-		})
-
-		r.Group(func(tr chi.Router) {
-			tr.Use(authentification.RequireRole("admin"))
-			tr.Post("/api/v1/treatments", treatmentRoutes.ServeHTTP)
-			tr.Put("/api/v1/treatments/{id}", treatmentRoutes.ServeHTTP)
-			tr.Delete("/api/v1/treatments/{id}", treatmentRoutes.ServeHTTP)
-		})
+		r.Use(authentification.AuthMiddleware(configuration))
+		r.Use(authentification.RequirePermissionToIncludeDeleted(dbmodel.PermissionDeletedManage))
+		r.Mount("/api/v1/cats", cat.Routes(configuration))
+		r.Mount("/api/v1/cats/{id}/visits", visit.CatRoutes(configuration))
+		r.Mount("/api/v1/cats/{id}/vaccinations", vaccination.CatRoutes(configuration))
+		r.Mount("/api/v1/visits", visit.Routes(configuration))
+		r.Mount("/api/v1/visits/{id}/treatments", treatment.VisitRoutes(configuration))
+		r.Mount("/api/v1/treatments", treatment.Routes(configuration))
+		r.Mount("/api/v1/owners", owner.Routes(configuration))
+		r.Mount("/api/v1/appointments", appointment.Routes(configuration))
+		r.Mount("/api/v1/veterinarians", veterinarian.Routes(configuration))
+		r.Mount("/api/v1/vaccinations", vaccination.Routes(configuration))
 
 		r.Group(func(ur chi.Router) {
-			ur.Use(authentification.RequireRole("admin"))
+			ur.Use(authentification.RequirePermission(dbmodel.PermissionUsersManage))
 			ur.Mount("/api/v1/users", user.Routes(configuration))
 		})
-
-		r.Get("/protected", func(w http.ResponseWriter, req *http.Request) {
-			userEmail := authentification.GetUserFromContext(req.Context())
-			userRole := authentification.GetRoleFromContext(req.Context())
-			w.Write([]byte(fmt.Sprintf("Welcome, %s! Your role: %s", userEmail, userRole)))
+		r.Group(func(rr chi.Router) {
+			rr.Use(authentification.RequirePermission(dbmodel.PermissionRolesManage))
+			rr.Mount("/api/v1/roles", role.Routes(configuration))
+			rr.Mount("/api/v1/permissions", role.PermissionRoutes(configuration))
 		})
+		r.With(authentification.RequirePermission(dbmodel.PermissionAuditRead)).Mount("/api/v1/audit", audit.Routes(configuration))
+		r.With(authentification.RequirePermission(dbmodel.PermissionDeletedManage)).Mount("/api/v1/purge", purge.Routes(configuration))
+		r.Mount("/api/v1/me", user.MeRoutes(configuration))
 	})
 
 	router.Get("/swagger", func(w http.ResponseWriter, r *http.Request) {
@@ -84,20 +90,28 @@ func Routes(configuration *config.Config) *chi.Mux {
 	})
 
 	router.Get("/swagger/*", httpSwagger.Handler(
-		httpSwagger.URL("http://localhost:8080/swagger/doc.json"),
+		httpSwagger.URL(configuration.SwaggerURL),
 	))
 	return router
 
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalln("Migration error:", err)
+		}
+		return
+	}
+
 	configuration, err := config.New()
 	if err != nil {
 		log.Panicln("Configuration error:", err)
 	}
 
 	router := Routes(configuration)
+	purge.Start(context.Background(), configuration)
 
-	log.Println("Serving on :8080")
-	log.Fatal(http.ListenAndServe(":8080", router))
+	log.Println("Serving on", configuration.ListenAddr)
+	log.Fatal(http.ListenAndServe(configuration.ListenAddr, router))
 }
@@ -0,0 +1,449 @@
+package appointment
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/etag"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultSlotDuration = 30 * time.Minute
+	maxSlotSearchDays   = 31
+)
+
+type AppointmentConfig struct {
+	*config.Config
+}
+
+func New(configuration *config.Config) *AppointmentConfig {
+	return &AppointmentConfig{configuration}
+}
+
+// CreateAppointmentHandler godoc
+// @Summary Book an appointment
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Param appointment body models.AppointmentRequest true "Appointment payload"
+// @Success 201 {object} models.AppointmentResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /appointments [post]
+func (config *AppointmentConfig) CreateAppointmentHandler(w http.ResponseWriter, r *http.Request) {
+	req := &models.AppointmentRequest{}
+
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	appointment := &dbmodel.Appointment{Status: dbmodel.AppointmentBooked}
+	applyAppointmentRequest(appointment, req)
+
+	config.saveAppointment(w, r, appointment, http.StatusCreated)
+}
+
+// GetAllAppointmentsHandler godoc
+// @Summary List appointments
+// @Tags appointments
+// @Produce json
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. starts_at (id, starts_at, veterinarian_id, status, cat_id, created_at)"
+// @Param cat_id query int false "Cat ID"
+// @Param veterinarian_id query int false "Veterinarian ID"
+// @Param status query string false "Status (booked, checked_in, cancelled, no_show, completed)"
+// @Param from query string false "Starting on or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param to query string false "Starting on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Success 200 {array} models.AppointmentResponse
+// @Header 200 {integer} X-Total-Count "Total number of matching appointments"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /appointments [get]
+func (config *AppointmentConfig) GetAllAppointmentsHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := models.ParseQueryOptions(r)
+	if err != nil {
+		problem.InvalidQuery(w, r, err)
+		return
+	}
+
+	appointments, total, err := config.AppointmentRepository.FindAll(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch appointments")
+		return
+	}
+	models.SetPaginationHeaders(w, r, opts, total)
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, models.NewAppointmentResponses(appointments))
+}
+
+// GetAppointmentByIDHandler godoc
+// @Summary Get an appointment by ID
+// @Tags appointments
+// @Produce json
+// @Param id path int true "Appointment ID"
+// @Success 200 {object} models.AppointmentResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /appointments/{id} [get]
+func (config *AppointmentConfig) GetAppointmentByIDHandler(w http.ResponseWriter, r *http.Request) {
+	appointment, ok := config.findAppointment(w, r)
+	if !ok {
+		return
+	}
+
+	render.JSON(w, r, models.NewAppointmentResponse(appointment))
+}
+
+// UpdateAppointmentHandler godoc
+// @Summary Reschedule or edit a booked appointment
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Param id path int true "Appointment ID"
+// @Param appointment body models.AppointmentRequest true "Appointment payload"
+// @Success 200 {object} models.AppointmentResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /appointments/{id} [put]
+func (config *AppointmentConfig) UpdateAppointmentHandler(w http.ResponseWriter, r *http.Request) {
+	req := &models.AppointmentRequest{}
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	existing, ok := config.findAppointment(w, r)
+	if !ok {
+		return
+	}
+
+	if existing.Status != dbmodel.AppointmentBooked {
+		problem.Write(w, r, http.StatusConflict, "only booked appointments can be modified")
+		return
+	}
+
+	applyAppointmentRequest(existing, req)
+
+	config.saveAppointment(w, r, existing, http.StatusOK)
+}
+
+// UpdateAppointmentStatusHandler godoc
+// @Summary Change the status of an appointment
+// @Description booked → checked_in, cancelled or no_show; checked_in → completed or cancelled.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Param id path int true "Appointment ID"
+// @Param status body models.AppointmentStatusRequest true "New status"
+// @Success 200 {object} models.AppointmentResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /appointments/{id}/status [post]
+func (config *AppointmentConfig) UpdateAppointmentStatusHandler(w http.ResponseWriter, r *http.Request) {
+	req := &models.AppointmentStatusRequest{}
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	existing, ok := config.findAppointment(w, r)
+	if !ok {
+		return
+	}
+
+	if !existing.CanTransitionTo(req.Status) {
+		problem.Write(w, r, http.StatusConflict, "cannot move appointment from "+existing.Status+" to "+req.Status)
+		return
+	}
+
+	existing.Status = req.Status
+	updated, err := config.AppointmentRepository.Update(r.Context(), existing)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to update appointment")
+		return
+	}
+
+	render.JSON(w, r, models.NewAppointmentResponse(updated))
+}
+
+// CreateVisitFromAppointmentHandler godoc
+// @Summary Record the visit of a completed appointment
+// @Tags appointments
+// @Produce json
+// @Param id path int true "Appointment ID"
+// @Success 201 {object} models.VisitResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /appointments/{id}/visit [post]
+func (config *AppointmentConfig) CreateVisitFromAppointmentHandler(w http.ResponseWriter, r *http.Request) {
+	appointment, ok := config.findAppointment(w, r)
+	if !ok {
+		return
+	}
+
+	visit, err := config.VisitService.FromAppointment(r.Context(), appointment.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAppointmentNotCompleted), errors.Is(err, dbmodel.ErrAppointmentHasVisit):
+			problem.Write(w, r, http.StatusConflict, err.Error())
+		case errors.Is(err, service.ErrCatNotFound), errors.Is(err, service.ErrVeterinarianUnavailable):
+			problem.Write(w, r, http.StatusUnprocessableEntity, err.Error())
+		default:
+			problem.Write(w, r, http.StatusInternalServerError, "unable to save visit")
+		}
+		return
+	}
+
+	etag.Set(w, visit.Version)
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, models.NewVisitResponse(visit))
+}
+
+// DeleteAppointmentHandler godoc
+// @Summary Delete an appointment
+// @Tags appointments
+// @Param id path int true "Appointment ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /appointments/{id} [delete]
+func (config *AppointmentConfig) DeleteAppointmentHandler(w http.ResponseWriter, r *http.Request) {
+	appointment, ok := config.findAppointment(w, r)
+	if !ok {
+		return
+	}
+
+	if err := config.AppointmentRepository.Delete(r.Context(), appointment.ID, appointment); err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to delete appointment")
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+// RestoreAppointmentHandler godoc
+// @Summary Restore a deleted appointment
+// @Description The cat of the appointment must not be deleted, and a booked, checked-in or completed appointment must not overlap another one of its veterinarian.
+// @Tags appointments
+// @Produce json
+// @Param id path int true "Appointment ID"
+// @Success 200 {object} models.AppointmentResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /appointments/{id}/restore [post]
+func (config *AppointmentConfig) RestoreAppointmentHandler(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid appointment ID")
+		return
+	}
+
+	restored, err := config.AppointmentRepository.Restore(r.Context(), uint(id64))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			problem.Write(w, r, http.StatusNotFound, "appointment not found")
+		case errors.Is(err, dbmodel.ErrNotDeleted), errors.Is(err, dbmodel.ErrParentDeleted), errors.Is(err, dbmodel.ErrAppointmentConflict):
+			problem.Write(w, r, http.StatusConflict, err.Error())
+		default:
+			problem.Write(w, r, http.StatusInternalServerError, "failed to restore appointment")
+		}
+		return
+	}
+
+	render.JSON(w, r, models.NewAppointmentResponse(restored))
+}
+
+// GetFreeSlotsHandler godoc
+// @Summary Search free appointment slots
+// @Description Cuts the veterinarians' working hours into slots of the requested duration and removes the booked ones.
+// @Tags appointments
+// @Produce json
+// @Param veterinarian_id query int false "Veterinarian ID (all active veterinarians with working hours when omitted)"
+// @Param from query string false "First day (YYYY-MM-DD, default today)"
+// @Param to query string false "Last day (YYYY-MM-DD, default from)"
+// @Param duration query string false "Slot duration (default 30m)"
+// @Success 200 {array} models.SlotResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /appointments/slots [get]
+func (config *AppointmentConfig) GetFreeSlotsHandler(w http.ResponseWriter, r *http.Request) {
+	loc := config.Location()
+	now := time.Now()
+	query := r.URL.Query()
+
+	y, m, d := now.In(loc).Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	if value := query.Get("from"); value != "" {
+		day, err := time.ParseInLocation(time.DateOnly, value, loc)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, "from must be a date (YYYY-MM-DD)")
+			return
+		}
+		from = day
+	}
+
+	to := from
+	if value := query.Get("to"); value != "" {
+		day, err := time.ParseInLocation(time.DateOnly, value, loc)
+		if err != nil || day.Before(from) {
+			problem.Write(w, r, http.StatusBadRequest, "to must be a date (YYYY-MM-DD) on or after from")
+			return
+		}
+		to = day
+	}
+	if to.Sub(from) > maxSlotSearchDays*24*time.Hour {
+		problem.Write(w, r, http.StatusBadRequest, "the search range cannot exceed "+strconv.Itoa(maxSlotSearchDays)+" days")
+		return
+	}
+
+	duration := defaultSlotDuration
+	if value := query.Get("duration"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 5*time.Minute {
+			problem.Write(w, r, http.StatusBadRequest, "duration must be a duration of at least 5m (e.g. 30m, 1h)")
+			return
+		}
+		duration = parsed
+	}
+
+	var veterinarianIDs []uint
+	if value := query.Get("veterinarian_id"); value != "" {
+		id64, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, "invalid veterinarian ID")
+			return
+		}
+		veterinarianIDs = []uint{uint(id64)}
+	} else {
+		var err error
+		veterinarianIDs, err = config.WorkingHoursRepository.FindVeterinarianIDs(r.Context())
+		if err != nil {
+			problem.Write(w, r, http.StatusInternalServerError, "failed to load working hours")
+			return
+		}
+	}
+
+	slots := []models.SlotResponse{}
+	for _, veterinarianID := range veterinarianIDs {
+		hours, err := config.WorkingHoursRepository.FindByVeterinarian(r.Context(), veterinarianID)
+		if err != nil {
+			problem.Write(w, r, http.StatusInternalServerError, "failed to load working hours")
+			return
+		}
+
+		booked, err := config.AppointmentRepository.FindActiveBetween(r.Context(), veterinarianID, from, to.AddDate(0, 0, 1))
+		if err != nil {
+			problem.Write(w, r, http.StatusInternalServerError, "failed to load appointments")
+			return
+		}
+
+		slots = append(slots, freeSlots(veterinarianID, hours, booked, from, to, duration, loc, now)...)
+	}
+
+	render.JSON(w, r, slots)
+}
+
+func (config *AppointmentConfig) findAppointment(w http.ResponseWriter, r *http.Request) (*dbmodel.Appointment, bool) {
+	idParam := chi.URLParam(r, "id")
+	id64, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid appointment ID")
+		return nil, false
+	}
+
+	appointment, err := config.AppointmentRepository.FindById(r.Context(), uint(id64))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "appointment not found")
+		return nil, false
+	}
+	return appointment, true
+}
+
+// saveAppointment checks the cat, the veterinarian and the working hours, then creates or updates the appointment.
+func (config *AppointmentConfig) saveAppointment(w http.ResponseWriter, r *http.Request, appointment *dbmodel.Appointment, status int) {
+	if _, err := config.CatRepository.FindById(r.Context(), appointment.CatID); err != nil {
+		problem.LookupError(w, r, err, http.StatusUnprocessableEntity, "cat not found")
+		return
+	}
+
+	veterinarian, err := config.VeterinarianRepository.FindById(r.Context(), appointment.VeterinarianID)
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusUnprocessableEntity, "veterinarian not found or inactive")
+		return
+	}
+	if !veterinarian.Active {
+		problem.Write(w, r, http.StatusUnprocessableEntity, "veterinarian not found or inactive")
+		return
+	}
+
+	hours, err := config.WorkingHoursRepository.FindByVeterinarian(r.Context(), appointment.VeterinarianID)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to load working hours")
+		return
+	}
+	if !fitsWorkingHours(hours, appointment.StartsAt, appointment.EndsAt, config.Location()) {
+		problem.Write(w, r, http.StatusUnprocessableEntity, "the appointment is outside the veterinarian's working hours")
+		return
+	}
+
+	var saved *dbmodel.Appointment
+	if appointment.ID == 0 {
+		saved, err = config.AppointmentRepository.Create(r.Context(), appointment)
+	} else {
+		saved, err = config.AppointmentRepository.Update(r.Context(), appointment)
+	}
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrAppointmentConflict) {
+			problem.Write(w, r, http.StatusConflict, err.Error())
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "unable to save appointment")
+		return
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, models.NewAppointmentResponse(saved))
+}
+
+func applyAppointmentRequest(appointment *dbmodel.Appointment, req *models.AppointmentRequest) {
+	appointment.CatID = req.CatID
+	appointment.VeterinarianID = req.VeterinarianID
+	appointment.StartsAt = req.StartsAt
+	appointment.EndsAt = req.EndsAt
+	appointment.Reason = req.Reason
+	appointment.Notes = req.Notes
+}
@@ -0,0 +1,32 @@
+package appointment
+
+import (
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/authentification"
+	"github.com/go-chi/chi/v5"
+)
+
+func Routes(configuration *config.Config) *chi.Mux {
+	appointmentConfig := New(configuration)
+	router := chi.NewRouter()
+
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionAppointmentsRead))
+		r.Get("/", appointmentConfig.GetAllAppointmentsHandler)
+		r.Get("/slots", appointmentConfig.GetFreeSlotsHandler)
+		r.Get("/{id}", appointmentConfig.GetAppointmentByIDHandler)
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionAppointmentsWrite))
+		r.Post("/", appointmentConfig.CreateAppointmentHandler)
+		r.Put("/{id}", appointmentConfig.UpdateAppointmentHandler)
+		r.Post("/{id}/status", appointmentConfig.UpdateAppointmentStatusHandler)
+		r.Post("/{id}/visit", appointmentConfig.CreateVisitFromAppointmentHandler)
+		r.Delete("/{id}", appointmentConfig.DeleteAppointmentHandler)
+		r.With(authentification.RequirePermission(dbmodel.PermissionDeletedManage)).Post("/{id}/restore", appointmentConfig.RestoreAppointmentHandler)
+	})
+
+	return router
+}
@@ -0,0 +1,73 @@
+package appointment
+
+import (
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+)
+
+// window returns the working-hours window on the given day, in loc.
+func window(hours dbmodel.WorkingHours, day time.Time, loc *time.Location) (time.Time, time.Time) {
+	start, _ := time.Parse("15:04", hours.Start)
+	end, _ := time.Parse("15:04", hours.End)
+	y, m, d := day.In(loc).Date()
+	return time.Date(y, m, d, start.Hour(), start.Minute(), 0, 0, loc),
+		time.Date(y, m, d, end.Hour(), end.Minute(), 0, 0, loc)
+}
+
+// fitsWorkingHours reports whether [start, end) lies inside one working window.
+// A veterinarian without configured working hours accepts any time.
+func fitsWorkingHours(hours []dbmodel.WorkingHours, start, end time.Time, loc *time.Location) bool {
+	if len(hours) == 0 {
+		return true
+	}
+	weekday := start.In(loc).Weekday()
+	for _, h := range hours {
+		if h.Weekday != weekday {
+			continue
+		}
+		windowStart, windowEnd := window(h, start, loc)
+		if !start.Before(windowStart) && !end.After(windowEnd) {
+			return true
+		}
+	}
+	return false
+}
+
+// freeSlots cuts the working windows between the from and to days (inclusive) into
+// slots of the given duration and drops those that are past or already booked. Days,
+// like working hours, are counted in loc.
+func freeSlots(veterinarianID uint, hours []dbmodel.WorkingHours, booked []dbmodel.Appointment,
+	from, to time.Time, duration time.Duration, loc *time.Location, now time.Time) []models.SlotResponse {
+	slots := []models.SlotResponse{}
+	for day := from.In(loc); !day.After(to); day = day.AddDate(0, 0, 1) {
+		for _, h := range hours {
+			if h.Weekday != day.Weekday() {
+				continue
+			}
+			windowStart, windowEnd := window(h, day, loc)
+			for start := windowStart; !start.Add(duration).After(windowEnd); start = start.Add(duration) {
+				end := start.Add(duration)
+				if start.Before(now) || overlapsAny(booked, start, end) {
+					continue
+				}
+				slots = append(slots, models.SlotResponse{
+					VeterinarianID: veterinarianID,
+					StartsAt:       start.UTC(),
+					EndsAt:         end.UTC(),
+				})
+			}
+		}
+	}
+	return slots
+}
+
+func overlapsAny(appointments []dbmodel.Appointment, start, end time.Time) bool {
+	for _, a := range appointments {
+		if a.StartsAt.Before(end) && a.EndsAt.After(start) {
+			return true
+		}
+	}
+	return false
+}
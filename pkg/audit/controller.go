@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"github.com/go-chi/render"
+)
+
+type AuditConfig struct {
+	*config.Config
+}
+
+func New(configuration *config.Config) *AuditConfig {
+	return &AuditConfig{configuration}
+}
+
+// GetAuditLogHandler godoc
+// @Summary List the audit trail
+// @Description Every create, update and delete of cats, visits, treatments and users, the deletions of owners, veterinarians and appointments, the restores and purges of deleted records, most recent first.
+// @Tags audit
+// @Produce json
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. created_at (id, created_at); default -id"
+// @Param entity_type query string false "Entity type (cat, visit, treatment, vaccination, weight, appointment, owner, veterinarian, user)"
+// @Param entity_id query int false "Entity ID"
+// @Param actor_id query int false "ID of the user who made the change"
+// @Param action query string false "create, update, delete, restore or purge"
+// @Param from query string false "Changes made at or after this date or timestamp"
+// @Param to query string false "Changes made at or before this date or timestamp"
+// @Success 200 {array} models.AuditLogResponse
+// @Header 200 {integer} X-Total-Count "Total number of matching entries"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /audit [get]
+func (config *AuditConfig) GetAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := models.ParseQueryOptions(r)
+	if err != nil {
+		problem.InvalidQuery(w, r, err)
+		return
+	}
+
+	entries, total, err := config.AuditLogRepository.FindAll(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch audit log")
+		return
+	}
+	models.SetPaginationHeaders(w, r, opts, total)
+
+	render.JSON(w, r, models.NewAuditLogResponses(entries))
+}
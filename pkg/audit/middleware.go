@@ -0,0 +1,17 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestID tags the writes made while serving the request with its request ID, so that
+// audit entries can be matched with the server logs. It must run after middleware.RequestID.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := dbmodel.WithAuditRequestID(r.Context(), middleware.GetReqID(r.Context()))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,15 @@
+package audit
+
+import (
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/go-chi/chi/v5"
+)
+
+func Routes(configuration *config.Config) *chi.Mux {
+	auditConfig := New(configuration)
+	router := chi.NewRouter()
+
+	router.Get("/", auditConfig.GetAuditLogHandler)
+
+	return router
+}
@@ -1,90 +1,252 @@
 package authentification
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
 	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
 	"github.com/go-chi/render"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type AuthConfig struct {
 	*config.Config
+	ipFailures    *ipThrottle
+	resetRequests *ipThrottle
 }
 
 func New(configuration *config.Config) *AuthConfig {
-	return &AuthConfig{configuration}
+	return &AuthConfig{configuration, newIPThrottle(), newIPThrottle()}
 }
 
+// dummyPasswordHash is compared with the password of a login to an unknown address, so
+// that it takes as long as a login to an existing account.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("no account has this password"), bcrypt.DefaultCost)
+
+// LoginHandler checks the credentials and opens a new session. Failed attempts are
+// counted per account and per client IP: the account has to wait longer after each
+// failure and is locked once LoginMaxAttempts is reached, and an IP reaching
+// LoginIPMaxAttempts is refused whatever the account.
+//
+// An unknown address, a wrong password, and an account locked or waiting after its last
+// failure all get the same 401 in the same time, so that the answer reveals neither which
+// addresses have an account nor whether a guess made during a lockout was right.
 func (c *AuthConfig) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	payload := &models.UserRequest{}
 
 	if err := render.Bind(r, payload); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid request payload",
-		})
+		problem.BindError(w, r, err)
 		return
 	}
 
-	user, err := c.UserRepository.GetUserByEmail(payload.Email)
+	now := time.Now().UTC()
+	ip := clientIP(r)
+	if wait := c.ipFailures.blockedFor(ip, c.LoginIPMaxAttempts, c.LoginLockout, now); wait > 0 {
+		retryAfter(w, wait)
+		problem.Write(w, r, http.StatusTooManyRequests, "Too many failed login attempts, try again later")
+		return
+	}
+
+	user, err := c.UserRepository.GetUserByEmail(r.Context(), payload.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(payload.Password))
+		c.ipFailures.recordFailure(ip, c.LoginLockout, now)
+		problem.Write(w, r, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(payload.Password)); err != nil {
-		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+	passwordErr := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(payload.Password))
+	waiting := user.IsLocked(now)
+	if user.LastFailedLoginAt != nil && user.LastFailedLoginAt.Add(loginDelay(user.FailedLoginAttempts)).After(now) {
+		waiting = true
+	}
+	if waiting {
+		// Attempts made while the account waits are refused without being counted.
+		c.ipFailures.recordFailure(ip, c.LoginLockout, now)
+		problem.Write(w, r, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
 
-	userRole := user.Role
-	if userRole == "" {
-		userRole = "user"
+	if passwordErr != nil {
+		c.ipFailures.recordFailure(ip, c.LoginLockout, now)
+		if err := c.UserRepository.RecordLoginFailure(r.Context(), user, c.LoginMaxAttempts, c.LoginLockout); err != nil {
+			log.Println("Failed to record failed login:", err)
+		} else if user.IsLocked(now) {
+			log.Printf("Account %d locked until %s after too many failed logins", user.ID, user.LockedUntil.Format(time.RFC3339))
+		}
+		problem.Write(w, r, http.StatusUnauthorized, "Invalid email or password")
+		return
+	}
+
+	if user.IsDisabled() {
+		problem.Write(w, r, http.StatusForbidden, "Account disabled")
+		return
 	}
 
-	token, err := GenerateToken("your_secret_key", payload.Email, userRole)
-	refreshToken, _ := GenerateRefreshToken(payload.Email, userRole)
+	if err := c.UserRepository.RecordLoginSuccess(r.Context(), user); err != nil {
+		log.Println("Failed to record login:", err)
+	}
+
+	if _, err := c.RefreshTokenRepository.DeleteExpired(r.Context(), time.Now().UTC()); err != nil {
+		log.Println("Failed to delete expired refresh tokens:", err)
+	}
 
-	user.RefreshToken = refreshToken
-	c.UserRepository.Update(user)
+	family, err := randomID(16)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"token": token, "refresh_token": refreshToken})
+	tokens, err := c.issueTokens(r.Context(), user, family, time.Now().Add(c.SessionTTL))
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	render.JSON(w, r, tokens)
 }
 
+// RefreshToken exchanges a refresh token for a new access token and a new refresh token.
+// A refresh token can be used only once: presenting it again means it was stolen, so the
+// whole family is revoked and both the thief and the user have to log in again.
 func (c *AuthConfig) RefreshToken(w http.ResponseWriter, r *http.Request) {
-	payload := &models.UserRequest{}
+	payload := &models.RefreshTokenRequest{}
 	if err := render.Bind(r, payload); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid request payload",
-		})
+		problem.BindError(w, r, err)
+		return
+	}
+
+	claims, err := ParseRefreshToken(c.RefreshSecret, payload.RefreshToken)
+	if err != nil {
+		problem.Write(w, r, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	stored, err := c.RefreshTokenRepository.FindByHash(r.Context(), HashToken(payload.RefreshToken))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to refresh token")
+		return
+	}
+	if err != nil || stored.FamilyID != claims.Family || strconv.FormatUint(uint64(stored.UserID), 10) != claims.Subject {
+		problem.Write(w, r, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+	if stored.RevokedAt != nil {
+		problem.Write(w, r, http.StatusUnauthorized, "Refresh token revoked")
+		return
+	}
+
+	if err := c.RefreshTokenRepository.Consume(r.Context(), stored.ID); err != nil {
+		if errors.Is(err, dbmodel.ErrRefreshTokenConsumed) {
+			log.Printf("Refresh token reuse detected for user %d, revoking family %s", stored.UserID, stored.FamilyID)
+			if err := c.RefreshTokenRepository.RevokeFamily(r.Context(), stored.FamilyID); err != nil {
+				log.Println("Failed to revoke refresh token family:", err)
+			}
+			problem.Write(w, r, http.StatusUnauthorized, "Refresh token reuse detected, please log in again")
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to refresh token")
 		return
 	}
 
-	user, err := c.UserRepository.GetUserByEmail(payload.Email)
+	user, err := c.UserRepository.FindById(r.Context(), stored.UserID)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusUnauthorized)
+		problem.LookupError(w, r, err, http.StatusUnauthorized, "User not found")
+		return
+	}
+	if user.IsDisabled() {
+		problem.Write(w, r, http.StatusUnauthorized, "Account disabled")
+		return
+	}
+
+	tokens, err := c.issueTokens(r.Context(), user, stored.FamilyID, stored.FamilyExpiresAt)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	render.JSON(w, r, tokens)
+}
+
+// LogoutHandler revokes the refresh token and every token rotated from the same login.
+// The access token stays valid until it expires.
+func (c *AuthConfig) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	payload := &models.RefreshTokenRequest{}
+	if err := render.Bind(r, payload); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	if _, err := ParseRefreshToken(c.RefreshSecret, payload.RefreshToken); err != nil {
+		problem.Write(w, r, http.StatusUnauthorized, "Invalid refresh token")
 		return
 	}
 
+	stored, err := c.RefreshTokenRepository.FindByHash(r.Context(), HashToken(payload.RefreshToken))
+	switch {
+	case err == nil:
+		if err := c.RefreshTokenRepository.RevokeFamily(r.Context(), stored.FamilyID); err != nil {
+			problem.Write(w, r, http.StatusInternalServerError, "Failed to revoke refresh token")
+			return
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to revoke refresh token")
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+// issueTokens signs an access token and a refresh token of the given family; the refresh
+// token never outlives the family.
+func (c *AuthConfig) issueTokens(ctx context.Context, user *dbmodel.User, family string, familyExpiresAt time.Time) (*models.TokenResponse, error) {
 	userRole := user.Role
 	if userRole == "" {
 		userRole = "user"
 	}
 
-	newToken, err := GenerateToken("your_secret_key", payload.Email, userRole)
+	token, err := GenerateToken(c.AccessSecret, user.ID, user.Email, userRole, family, c.AccessTokenTTL)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
-		return
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(c.RefreshTokenTTL)
+	if expiresAt.After(familyExpiresAt) {
+		expiresAt = familyExpiresAt
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"token": newToken})
+	refreshToken, err := GenerateRefreshToken(c.RefreshSecret, user.ID, family, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.RefreshTokenRepository.Create(ctx, &dbmodel.RefreshToken{
+		UserID:          user.ID,
+		FamilyID:        family,
+		TokenHash:       HashToken(refreshToken),
+		ExpiresAt:       expiresAt.UTC(),
+		FamilyExpiresAt: familyExpiresAt.UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{Token: token, RefreshToken: refreshToken}, nil
+}
+
+// retryAfter tells the client how many seconds to wait, rounded up.
+func retryAfter(w http.ResponseWriter, wait time.Duration) {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
 }
@@ -0,0 +1,226 @@
+package authentification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/database/migrations"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/password"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testPassword = "Str0ng-Passw0rd"
+
+// newTestConfig returns the configuration the authentication handlers need, on a fresh
+// in-memory SQLite database, with a lockout after 3 failed logins.
+func newTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to ":memory:" opens its own database.
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.SeedRoles(db); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := password.NewPolicy(10, 3, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &config.Config{
+		Settings: config.Settings{
+			AccessSecret:       "test-access-secret",
+			RefreshSecret:      "test-refresh-secret",
+			AccessTokenTTL:     time.Hour,
+			RefreshTokenTTL:    24 * time.Hour,
+			SessionTTL:         7 * 24 * time.Hour,
+			PasswordResetTTL:   time.Hour,
+			LoginMaxAttempts:   3,
+			LoginIPMaxAttempts: 100,
+			LoginLockout:       time.Hour,
+		},
+		PasswordPolicy:          policy,
+		UserRepository:          dbmodel.NewUserRepository(db),
+		RoleRepository:          dbmodel.NewRoleRepository(db),
+		RefreshTokenRepository:  dbmodel.NewRefreshTokenRepository(db),
+		PasswordResetRepository: dbmodel.NewPasswordResetRepository(db),
+		UnitOfWork:              dbmodel.NewUnitOfWork(db),
+	}
+}
+
+func createUser(t *testing.T, cfg *config.Config, email, role string) *dbmodel.User {
+	t.Helper()
+	user, err := cfg.UserRepository.Create(context.Background(), &dbmodel.User{Email: email, Password: testPassword, Role: role})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// post sends a JSON body to the authentication routes.
+func post(router http.Handler, path string, body interface{}) *httptest.ResponseRecorder {
+	encoded, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(string(encoded)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// tokens decodes the tokens of a successful login or refresh.
+func tokens(t *testing.T, rec *httptest.ResponseRecorder) *models.TokenResponse {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	response := &models.TokenResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func login(t *testing.T, router http.Handler, email, pass string) *httptest.ResponseRecorder {
+	t.Helper()
+	return post(router, "/", models.UserRequest{Email: email, Password: pass})
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	cfg := newTestConfig(t)
+	createUser(t, cfg, "alice@example.com", "user")
+	router := Routes(cfg)
+
+	first := tokens(t, login(t, router, "alice@example.com", testPassword))
+	other := tokens(t, login(t, router, "alice@example.com", testPassword))
+
+	second := tokens(t, post(router, "/refresh", models.RefreshTokenRequest{RefreshToken: first.RefreshToken}))
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("the refresh returned the same refresh token")
+	}
+
+	// Using the first token again means it was stolen: the whole session is revoked.
+	if rec := post(router, "/refresh", models.RefreshTokenRequest{RefreshToken: first.RefreshToken}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("reuse of a refresh token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := post(router, "/refresh", models.RefreshTokenRequest{RefreshToken: second.RefreshToken}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh token rotated from a reused one: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	// The other session of the user is left alone.
+	other = tokens(t, post(router, "/refresh", models.RefreshTokenRequest{RefreshToken: other.RefreshToken}))
+
+	if rec := post(router, "/logout", models.RefreshTokenRequest{RefreshToken: other.RefreshToken}); rec.Code != http.StatusNoContent {
+		t.Fatalf("logout: status = %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body.String())
+	}
+	if rec := post(router, "/refresh", models.RefreshTokenRequest{RefreshToken: other.RefreshToken}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh after logout: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRefreshTokenRejectsForgedTokens(t *testing.T) {
+	cfg := newTestConfig(t)
+	user := createUser(t, cfg, "alice@example.com", "user")
+	router := Routes(cfg)
+
+	forged, err := GenerateRefreshToken("another-secret", user.ID, "family", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, err := GenerateRefreshToken(cfg.RefreshSecret, user.ID, "family", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	access := tokens(t, login(t, router, "alice@example.com", testPassword)).Token
+	for name, token := range map[string]string{"forged": forged, "never issued": unknown, "access": access} {
+		if rec := post(router, "/refresh", models.RefreshTokenRequest{RefreshToken: token}); rec.Code != http.StatusUnauthorized {
+			t.Errorf("refresh with a %s token: status = %d, want %d", name, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	cfg := newTestConfig(t)
+	user := createUser(t, cfg, "alice@example.com", "user")
+	router := Routes(cfg)
+
+	for i := 0; i < 2; i++ {
+		if rec := login(t, router, "alice@example.com", "Wrong-Passw0rd"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("failed login %d: status = %d, want %d", i+1, rec.Code, http.StatusUnauthorized)
+		}
+	}
+	// After the second failure the account waits a second; the right password is refused
+	// meanwhile, without counting as a failure.
+	if rec := login(t, router, "alice@example.com", testPassword); rec.Code != http.StatusUnauthorized {
+		t.Errorf("login during the delay: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	time.Sleep(loginDelay(2))
+
+	if rec := login(t, router, "alice@example.com", "Wrong-Passw0rd"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("third failed login: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	stored, err := cfg.UserRepository.FindById(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.IsLocked(time.Now()) {
+		t.Fatalf("user after %d failures = %+v, want them locked", cfg.LoginMaxAttempts, stored)
+	}
+	if rec := login(t, router, "alice@example.com", testPassword); rec.Code != http.StatusUnauthorized {
+		t.Errorf("login to a locked account: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	if err := cfg.UserRepository.Unlock(context.Background(), stored); err != nil {
+		t.Fatal(err)
+	}
+	tokens(t, login(t, router, "alice@example.com", testPassword))
+}
+
+func TestLoginIPThrottle(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.LoginIPMaxAttempts = 2
+	createUser(t, cfg, "alice@example.com", "user")
+	router := Routes(cfg)
+
+	for _, email := range []string{"bob@example.com", "carol@example.com"} {
+		if rec := login(t, router, email, testPassword); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("login to an unknown address: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	}
+	rec := login(t, router, "alice@example.com", testPassword)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("login from a throttled IP: status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("the throttled login has no Retry-After")
+	}
+}
+
+func TestLoginDelay(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		0: 0, 1: 0, 2: time.Second, 3: 2 * time.Second, 6: 16 * time.Second, 7: maxLoginDelay, 100: maxLoginDelay,
+	} {
+		if got := loginDelay(failures); got != want {
+			t.Errorf("loginDelay(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
@@ -1,42 +1,114 @@
 package authentification
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var RefreshSecret = []byte("my_refresh_secret")
+// AccessClaims identify the user, the access token and the login session (refresh token
+// family) it was issued for.
+type AccessClaims struct {
+	Email   string `json:"email"`
+	Role    string `json:"role"`
+	Session string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// RefreshClaims identify the user and the token family (login session) of a refresh token.
+type RefreshClaims struct {
+	Family string `json:"fam"`
+	jwt.RegisteredClaims
+}
 
-func GenerateToken(secret, email, role string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": email,
-		"role":  role,
-		"exp":   time.Now().Add(time.Hour * 1).Unix(),
+// GenerateToken signs an access token for the user, valid for ttl.
+func GenerateToken(secret string, userID uint, email, role, session string, ttl time.Duration) (string, error) {
+	id, err := randomID(16)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, AccessClaims{
+		Email:   email,
+		Role:    role,
+		Session: session,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
 	})
 	return token.SignedString([]byte(secret))
 }
 
-func GenerateRefreshToken(email, role string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": email,
-		"role":  role,
-		"exp":   time.Now().Add(time.Hour * 1).Unix(),
+// GenerateRefreshToken signs a refresh token for the user that expires at expiresAt.
+// Each token gets a random ID, so that two tokens of the same family never collide.
+func GenerateRefreshToken(secret string, userID uint, family string, expiresAt time.Time) (string, error) {
+	id, err := randomID(16)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, RefreshClaims{
+		Family: family,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
 	})
-	return token.SignedString(RefreshSecret)
+	return token.SignedString([]byte(secret))
 }
 
-func ParseToken(secret, tokenString string) (string, string, error) {
+// ParseToken checks the signature and expiry of an access token, with or without its
+// "Bearer " prefix, and returns the ID of the user it was issued to.
+func ParseToken(secret, tokenString string) (*AccessClaims, uint, error) {
 	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+	claims := &AccessClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secret), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return nil, 0, errors.New("access token without a valid subject")
+	}
+	return claims, uint(userID), nil
+}
+
+// ParseRefreshToken checks the signature and expiry of a refresh token.
+func ParseRefreshToken(secret, tokenString string) (*RefreshClaims, error) {
+	claims := &RefreshClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// HashToken is the form under which refresh and password reset tokens are stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		email := claims["email"].(string)
-		role, _ := claims["role"].(string)
-		return email, role, nil
+// randomID returns size random bytes, hex encoded.
+func randomID(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
 	}
-	return "", "", err
+	return hex.EncodeToString(buf), nil
 }
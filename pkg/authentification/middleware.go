@@ -2,54 +2,141 @@ package authentification
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"gorm.io/gorm"
 )
 
-func AuthMiddleware(secret string) func(http.Handler) http.Handler {
+// Principal is the user a request is made for, as loaded by AuthMiddleware.
+type Principal struct {
+	UserID      uint
+	Email       string
+	Role        string
+	Permissions []string
+	TokenID     string
+	SessionID   string
+}
+
+// Can tells whether the principal's role grants the permission.
+func (p *Principal) Can(permission string) bool {
+	return slices.Contains(p.Permissions, permission)
+}
+
+type contextKey int
+
+const principalKey contextKey = iota
+
+// AuthMiddleware checks the access token and loads the user it was issued to. The user,
+// their role and its permissions are read on every request, so a deleted or disabled
+// user or a role change takes effect immediately, before the token expires.
+func AuthMiddleware(configuration *config.Config) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Missing token", http.StatusUnauthorized)
+				problem.Write(w, r, http.StatusUnauthorized, "Missing token")
 				return
 			}
 
-			email, role, err := ParseToken(secret, authHeader)
+			claims, userID, err := ParseToken(configuration.AccessSecret, authHeader)
 			if err != nil {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				problem.Write(w, r, http.StatusUnauthorized, "Invalid token")
+				return
+			}
+
+			user, err := configuration.UserRepository.FindById(r.Context(), userID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				problem.Write(w, r, http.StatusUnauthorized, "User no longer exists")
+				return
+			}
+			if err != nil {
+				problem.Write(w, r, http.StatusInternalServerError, "Failed to load user")
+				return
+			}
+			if user.IsDisabled() {
+				problem.Write(w, r, http.StatusUnauthorized, "Account disabled")
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), "email", email)
-			ctx = context.WithValue(ctx, "role", role)
+			role := user.Role
+			if role == "" {
+				role = "user"
+			}
+			principal := &Principal{
+				UserID:    user.ID,
+				Email:     user.Email,
+				Role:      role,
+				TokenID:   claims.ID,
+				SessionID: claims.Session,
+			}
+			stored, err := configuration.RoleRepository.FindByName(r.Context(), role)
+			switch {
+			case err == nil:
+				principal.Permissions = stored.PermissionNames()
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				problem.Write(w, r, http.StatusInternalServerError, "Failed to load role")
+				return
+			}
+
+			ctx := WithPrincipal(r.Context(), principal)
+			ctx = dbmodel.WithAuditActor(ctx, user.ID, user.Email)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-func GetUserFromContext(ctx context.Context) string {
-	email, _ := ctx.Value("email").(string)
-	return email
+// WithPrincipal returns a copy of ctx carrying the principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
 }
 
-func GetRoleFromContext(ctx context.Context) string {
-	role, _ := ctx.Value("role").(string)
-	return role
+// PrincipalFromContext returns the principal stored by AuthMiddleware, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(*Principal)
+	return principal, ok
 }
 
-func RequireRole(allowedRoles ...string) func(http.Handler) http.Handler {
+// RequirePermission lets through the users whose role has the permission.
+func RequirePermission(permission string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			userRole := GetRoleFromContext(r.Context())
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				log.Println("RequirePermission used on a route without AuthMiddleware")
+				problem.Write(w, r, http.StatusUnauthorized, "Missing token")
+				return
+			}
+			if !principal.Can(permission) {
+				problem.Write(w, r, http.StatusForbidden, "Forbidden: missing permission "+permission)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-			for _, role := range allowedRoles {
-				if userRole == role {
-					next.ServeHTTP(w, r)
+// RequirePermissionToIncludeDeleted refuses ?include_deleted=true to the users whose role
+// lacks the permission, so that deleted records are only listed to those allowed to see
+// them. Malformed values are left for the handler to reject.
+func RequirePermissionToIncludeDeleted(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			includeDeleted, _ := strconv.ParseBool(r.URL.Query().Get("include_deleted"))
+			if includeDeleted {
+				principal, ok := PrincipalFromContext(r.Context())
+				if !ok || !principal.Can(permission) {
+					problem.Write(w, r, http.StatusForbidden, "Forbidden: missing permission "+permission+" to include deleted records")
 					return
 				}
 			}
-
-			http.Error(w, "Forbidden: insufficient permissions", http.StatusForbidden)
+			next.ServeHTTP(w, r)
 		})
 	}
 }
@@ -0,0 +1,109 @@
+package authentification
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/go-chi/chi/v5"
+)
+
+// protectedRouter serves the cats the way the API does: reading them needs cats:read,
+// writing them cats:write, and listing deleted ones deleted:manage.
+func protectedRouter(cfg *config.Config) http.Handler {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router := chi.NewRouter()
+	router.Use(AuthMiddleware(cfg))
+	router.With(
+		RequirePermission(dbmodel.PermissionCatsRead),
+		RequirePermissionToIncludeDeleted(dbmodel.PermissionDeletedManage),
+	).Get("/cats", ok)
+	router.With(RequirePermission(dbmodel.PermissionCatsWrite)).Post("/cats", ok)
+	return router
+}
+
+func accessToken(t *testing.T, cfg *config.Config, user *dbmodel.User) string {
+	t.Helper()
+	token, err := GenerateToken(cfg.AccessSecret, user.ID, user.Email, user.Role, "session", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+func TestPermissions(t *testing.T) {
+	cfg := newTestConfig(t)
+	admin := createUser(t, cfg, "admin@example.com", "admin")
+	user := createUser(t, cfg, "user@example.com", "user")
+	forged, err := GenerateToken("another-secret", admin.ID, admin.Email, "admin", "session", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := protectedRouter(cfg)
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		status        int
+	}{
+		{"without a token", http.MethodGet, "/cats", "", http.StatusUnauthorized},
+		{"with a forged token", http.MethodGet, "/cats", "Bearer " + forged, http.StatusUnauthorized},
+		{"read with cats:read", http.MethodGet, "/cats", accessToken(t, cfg, user), http.StatusOK},
+		{"write without cats:write", http.MethodPost, "/cats", accessToken(t, cfg, user), http.StatusForbidden},
+		{"write with cats:write", http.MethodPost, "/cats", accessToken(t, cfg, admin), http.StatusOK},
+		{"deleted records without deleted:manage", http.MethodGet, "/cats?include_deleted=true", accessToken(t, cfg, user), http.StatusForbidden},
+		{"deleted records with deleted:manage", http.MethodGet, "/cats?include_deleted=true", accessToken(t, cfg, admin), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestPermissionsFollowTheStoredUser(t *testing.T) {
+	cfg := newTestConfig(t)
+	user := createUser(t, cfg, "user@example.com", "user")
+	token := accessToken(t, cfg, user)
+	router := protectedRouter(cfg)
+	write := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/cats", nil)
+		req.Header.Set("Authorization", token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if status := write(); status != http.StatusForbidden {
+		t.Fatalf("write as user: status = %d, want %d", status, http.StatusForbidden)
+	}
+	// The token still says "user"; the role read on each request is the new one.
+	user.Role = "admin"
+	if _, err := cfg.UserRepository.Update(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	if status := write(); status != http.StatusOK {
+		t.Errorf("write after promotion to admin: status = %d, want %d", status, http.StatusOK)
+	}
+	if err := cfg.UserRepository.SetDisabled(context.Background(), user, true); err != nil {
+		t.Fatal(err)
+	}
+	if status := write(); status != http.StatusUnauthorized {
+		t.Errorf("write as a disabled user: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,171 @@
+package authentification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/mailer"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"github.com/go-chi/render"
+	"gorm.io/gorm"
+)
+
+// passwordResetTimeout bounds the lookup and the delivery of a reset link, which run after
+// the response has been sent.
+const passwordResetTimeout = time.Minute
+
+// ForgotPasswordHandler emails a single-use password reset link. It answers 202 before
+// looking the address up, whether or not it matches an account, so that neither the
+// response nor its timing can be used to list accounts. Requests are limited per client
+// IP like failed logins.
+func (c *AuthConfig) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	payload := &models.ForgotPasswordRequest{}
+	if err := render.Bind(r, payload); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	ip := clientIP(r)
+	if wait := c.resetRequests.blockedFor(ip, c.LoginIPMaxAttempts, c.LoginLockout, now); wait > 0 {
+		retryAfter(w, wait)
+		problem.Write(w, r, http.StatusTooManyRequests, "Too many password reset requests, try again later")
+		return
+	}
+	// Every request counts, whether or not the address matches an account.
+	c.resetRequests.recordFailure(ip, c.LoginLockout, now)
+
+	// The request context is cancelled once the response is sent; its values (request ID)
+	// are kept for the audit log.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), passwordResetTimeout)
+	go func() {
+		defer cancel()
+		user, err := c.UserRepository.GetUserByEmail(ctx, payload.Email)
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("Failed to look up the account of a password reset request: %v", err)
+			}
+			return
+		}
+		if err := c.sendPasswordReset(ctx, user); err != nil {
+			log.Printf("Failed to send password reset to user %d: %v", user.ID, err)
+		}
+	}()
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, map[string]string{
+		"message": "if an account matches this address, a reset link has been sent to it",
+	})
+}
+
+// ResetPasswordHandler sets a new password with a token received by email. Every session
+// of the user is ended.
+func (c *AuthConfig) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	payload := &models.ResetPasswordRequest{}
+	if err := render.Bind(r, payload); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	invalidToken := problem.Field("token", "the reset link is invalid or has expired")
+
+	token, err := c.PasswordResetRepository.FindByHash(r.Context(), HashToken(payload.Token))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+	if err != nil || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		problem.BindError(w, r, invalidToken)
+		return
+	}
+
+	user, err := c.UserRepository.FindById(r.Context(), token.UserID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, http.StatusInternalServerError, "Failed to reset password")
+			return
+		}
+		problem.BindError(w, r, invalidToken)
+		return
+	}
+
+	if err := c.PasswordPolicy.Validate("new_password", payload.NewPassword, user.Email); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	if err := c.PasswordResetRepository.Consume(r.Context(), token.ID); err != nil {
+		problem.BindError(w, r, invalidToken)
+		return
+	}
+
+	if err := c.UserRepository.UpdatePassword(dbmodel.WithAuditActor(r.Context(), user.ID, user.Email), user, payload.NewPassword); err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+	if err := c.RefreshTokenRepository.RevokeUser(r.Context(), user.ID); err != nil {
+		log.Println("Failed to revoke refresh tokens after password reset:", err)
+	}
+	if err := c.PasswordResetRepository.DeleteForUser(r.Context(), user.ID); err != nil {
+		log.Println("Failed to delete password reset tokens:", err)
+	}
+
+	render.NoContent(w, r)
+}
+
+// sendPasswordReset replaces any pending reset token of the user and emails the new one.
+func (c *AuthConfig) sendPasswordReset(ctx context.Context, user *dbmodel.User) error {
+	if _, err := c.PasswordResetRepository.DeleteExpired(ctx, time.Now().UTC()); err != nil {
+		log.Println("Failed to delete expired password reset tokens:", err)
+	}
+	if err := c.PasswordResetRepository.DeleteForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	secret, err := randomID(32)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(c.PasswordResetTTL).UTC()
+	if _, err := c.PasswordResetRepository.Create(ctx, &dbmodel.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: HashToken(secret),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	return c.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Réinitialisation de votre mot de passe",
+		Body: fmt.Sprintf("Bonjour,\n\n"+
+			"Une réinitialisation du mot de passe de votre compte a été demandée.\n"+
+			"Pour choisir un nouveau mot de passe, utilisez ce lien avant le %s (UTC) :\n\n%s\n\n"+
+			"Ce lien ne peut servir qu'une seule fois. Si vous n'êtes pas à l'origine de cette demande, ignorez ce message.\n",
+			expiresAt.Format("02/01/2006 15:04"), c.passwordResetLink(secret)),
+	})
+}
+
+// passwordResetLink adds the token to the configured frontend page, or returns the bare
+// token when no page is configured.
+func (c *AuthConfig) passwordResetLink(token string) string {
+	if c.PasswordResetURL == "" {
+		return token
+	}
+	link, err := url.Parse(c.PasswordResetURL)
+	if err != nil {
+		return token
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
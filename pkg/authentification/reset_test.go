@@ -0,0 +1,96 @@
+package authentification
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/mailer"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+)
+
+// channelMailer hands the messages it sends to the test.
+type channelMailer chan mailer.Message
+
+func (m channelMailer) Send(ctx context.Context, message mailer.Message) error {
+	m <- message
+	return nil
+}
+
+// requestReset asks for a reset link for the address and returns the token it carries.
+func requestReset(t *testing.T, router http.Handler, mails channelMailer, email string) string {
+	t.Helper()
+	if rec := post(router, "/forgot", models.ForgotPasswordRequest{Email: email}); rec.Code != http.StatusAccepted {
+		t.Fatalf("forgot password: status = %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+	select {
+	case message := <-mails:
+		if message.To != email {
+			t.Fatalf("reset link sent to %s, want %s", message.To, email)
+		}
+		for _, line := range strings.Split(message.Body, "\n") {
+			if link, err := url.Parse(line); err == nil && strings.HasPrefix(line, "https://") {
+				return link.Query().Get("token")
+			}
+		}
+		t.Fatalf("no reset link in %q", message.Body)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reset link was sent")
+	}
+	return ""
+}
+
+func TestPasswordResetTokenIsSingleUse(t *testing.T) {
+	cfg := newTestConfig(t)
+	mails := make(channelMailer, 1)
+	cfg.Mailer = mails
+	cfg.PasswordResetURL = "https://vet.example.com/reset"
+	createUser(t, cfg, "alice@example.com", "user")
+	router := Routes(cfg)
+
+	session := tokens(t, login(t, router, "alice@example.com", testPassword))
+	token := requestReset(t, router, mails, "alice@example.com")
+
+	reset := models.ResetPasswordRequest{Token: token, NewPassword: "N3w-Passw0rd!"}
+	if rec := post(router, "/reset", reset); rec.Code != http.StatusNoContent {
+		t.Fatalf("reset: status = %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body.String())
+	}
+	reset.NewPassword = "Th1rd-Passw0rd!"
+	if rec := post(router, "/reset", reset); rec.Code != http.StatusBadRequest {
+		t.Errorf("second use of the reset token: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	if rec := login(t, router, "alice@example.com", testPassword); rec.Code != http.StatusUnauthorized {
+		t.Errorf("login with the old password: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	tokens(t, login(t, router, "alice@example.com", "N3w-Passw0rd!"))
+	if rec := post(router, "/refresh", models.RefreshTokenRequest{RefreshToken: session.RefreshToken}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh of a session opened before the reset: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPasswordResetReplacesPendingToken(t *testing.T) {
+	cfg := newTestConfig(t)
+	mails := make(channelMailer, 1)
+	cfg.Mailer = mails
+	cfg.PasswordResetURL = "https://vet.example.com/reset"
+	createUser(t, cfg, "alice@example.com", "user")
+	router := Routes(cfg)
+
+	first := requestReset(t, router, mails, "alice@example.com")
+	second := requestReset(t, router, mails, "alice@example.com")
+
+	if rec := post(router, "/reset", models.ResetPasswordRequest{Token: first, NewPassword: "N3w-Passw0rd!"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("reset with a replaced token: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := post(router, "/reset", models.ResetPasswordRequest{Token: second, NewPassword: "short"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("reset to a weak password: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	// A rejected password does not use the token up.
+	if rec := post(router, "/reset", models.ResetPasswordRequest{Token: second, NewPassword: "N3w-Passw0rd!"}); rec.Code != http.StatusNoContent {
+		t.Errorf("reset with the latest token: status = %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body.String())
+	}
+}
@@ -11,6 +11,9 @@ func Routes(configuration *config.Config) *chi.Mux {
 
 	router.Post("/", authConfig.LoginHandler)
 	router.Post("/refresh", authConfig.RefreshToken)
+	router.Post("/logout", authConfig.LogoutHandler)
+	router.Post("/forgot", authConfig.ForgotPasswordHandler)
+	router.Post("/reset", authConfig.ResetPasswordHandler)
 
 	return router
 }
@@ -0,0 +1,89 @@
+package authentification
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxLoginDelay caps the progressive delay imposed between two failed logins of an account.
+const maxLoginDelay = 30 * time.Second
+
+// loginDelay is the time an account must wait after its failures-th failed login in a row:
+// nothing after the first one, then 1s, 2s, 4s… up to maxLoginDelay.
+func loginDelay(failures int) time.Duration {
+	if failures < 2 {
+		return 0
+	}
+	delay := time.Second << (failures - 2)
+	if delay <= 0 || delay > maxLoginDelay {
+		return maxLoginDelay
+	}
+	return delay
+}
+
+// ipThrottle counts failed logins, or password reset requests, per client IP, in memory.
+// An IP reaching the limit is blocked until the lockout duration has passed since its
+// last failure.
+type ipThrottle struct {
+	mu       sync.Mutex
+	failures map[string]*ipFailures
+}
+
+type ipFailures struct {
+	count int
+	last  time.Time
+}
+
+func newIPThrottle() *ipThrottle {
+	return &ipThrottle{failures: map[string]*ipFailures{}}
+}
+
+// blockedFor returns how long the IP must still wait, or 0 when it may try to log in.
+func (t *ipThrottle) blockedFor(ip string, maxAttempts int, lockout time.Duration, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.failures[ip]
+	if !ok {
+		return 0
+	}
+	if now.Sub(entry.last) >= lockout {
+		delete(t.failures, ip)
+		return 0
+	}
+	if entry.count < maxAttempts {
+		return 0
+	}
+	return entry.last.Add(lockout).Sub(now)
+}
+
+func (t *ipThrottle) recordFailure(ip string, lockout time.Duration, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.failures[ip]
+	if !ok || now.Sub(entry.last) >= lockout {
+		entry = &ipFailures{}
+		t.failures[ip] = entry
+	}
+	entry.count++
+	entry.last = now
+
+	for key, other := range t.failures {
+		if now.Sub(other.last) >= lockout {
+			delete(t.failures, key)
+		}
+	}
+}
+
+// clientIP is the address the request came from; behind a trusted proxy, middleware.RealIP
+// has already replaced RemoteAddr with the forwarded address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
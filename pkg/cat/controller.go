@@ -1,14 +1,19 @@
 package cat
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/emmanuelYohore/vet-clinic-api/config"
 	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/etag"
 	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/patch"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"gorm.io/gorm"
 )
 
 type CatConfig struct {
@@ -25,91 +30,124 @@ func New(configuration *config.Config) *CatConfig {
 // @Accept json
 // @Produce json
 // @Param cat body models.CatRequest true "Cat payload"
-// @Success 201 {object} dbmodel.Cat
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 201 {object} models.CatResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /cats [post]
 func (config *CatConfig) CreateCatHandler(w http.ResponseWriter, r *http.Request) {
 	req := &models.CatRequest{}
 
 	if err := render.Bind(r, req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid request payload",
-		})
+		problem.BindError(w, r, err)
 		return
 	}
 
+	if req.OwnerID != nil {
+		if _, err := config.OwnerRepository.FindById(r.Context(), *req.OwnerID); err != nil {
+			problem.LookupError(w, r, err, http.StatusUnprocessableEntity, "owner not found")
+			return
+		}
+	}
+
 	cat := &dbmodel.Cat{
-		Name:   req.Name,
-		Age:    req.Age,
-		Breed:  req.Breed,
-		Weigth: req.Weigth,
+		Name:    req.Name,
+		Age:     req.Age,
+		Breed:   req.Breed,
+		OwnerID: req.OwnerID,
 	}
 
-	savedCat, err := config.CatRepository.Create(cat)
+	savedCat, err := config.CatRepository.Create(r.Context(), cat)
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "unable to save cat",
-		})
+		problem.Write(w, r, http.StatusInternalServerError, "unable to save cat")
 		return
 	}
 
+	etag.Set(w, savedCat.Version)
 	render.Status(r, http.StatusCreated)
-	render.JSON(w, r, savedCat)
+	render.JSON(w, r, models.NewCatResponse(savedCat))
 }
 
 // GetAllCatsHandler godoc
 // @Summary List cats
 // @Tags cats
 // @Produce json
-// @Success 200 {array} dbmodel.Cat
-// @Failure 500 {object} map[string]string
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. name,-age (id, name, age, breed, weight, created_at)"
+// @Param include_deleted query bool false "Also list deleted records (requires deleted:manage)"
+// @Param name query string false "Name contains"
+// @Param breed query string false "Breed"
+// @Param age_min query int false "Minimum age"
+// @Param age_max query int false "Maximum age"
+// @Param weight_min query number false "Minimum current weight (kg)"
+// @Param weight_max query number false "Maximum current weight (kg)"
+// @Param owner_id query int false "Owner ID"
+// @Success 200 {array} models.CatResponse
+// @Header 200 {integer} X-Total-Count "Total number of matching cats"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /cats [get]
 func (config *CatConfig) GetAllCatsHandler(w http.ResponseWriter, r *http.Request) {
-	cats, err := config.CatRepository.FindAll()
+	opts, err := models.ParseQueryOptions(r)
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to fetch cats",
-		})
+		problem.InvalidQuery(w, r, err)
 		return
 	}
+
+	cats, total, err := config.CatRepository.FindAll(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch cats")
+		return
+	}
+	models.SetPaginationHeaders(w, r, opts, total)
 	render.Status(r, http.StatusOK)
-	render.JSON(w, r, cats)
+	render.JSON(w, r, models.NewCatResponses(cats))
 }
 
 // GetCatByIDHandler godoc
 // @Summary Get a cat by ID
 // @Tags cats
 // @Produce json
+// @Description Includes the current weight (latest measurement) and its change over 30, 90 and 365 days.
 // @Param id path int true "Cat ID"
-// @Success 200 {object} dbmodel.Cat
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {object} models.CatDetailResponse
+// @Header 200 {string} ETag "Version of the cat"
+// @Success 304 "The cached copy is current"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /cats/{id} [get]
 func (config *CatConfig) GetCatByIDHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	id64, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid cat ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid cat ID")
+		return
+	}
+
+	cat, err := config.CatRepository.FindById(r.Context(), uint(id64))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "cat not found")
+		return
+	}
+	if etag.NotModified(w, r, cat.Version) {
 		return
 	}
 
-	cat, err := config.CatRepository.FindById(uint(id64))
+	detail, err := config.catDetail(r.Context(), cat)
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{
-			"error": "cat not found",
-		})
+		problem.Write(w, r, http.StatusInternalServerError, "failed to load cat weight")
 		return
 	}
 
-	render.JSON(w, r, cat)
+	render.JSON(w, r, detail)
 }
 
 // UpdateCatHandler godoc
@@ -118,92 +156,151 @@ func (config *CatConfig) GetCatByIDHandler(w http.ResponseWriter, r *http.Reques
 // @Accept json
 // @Produce json
 // @Param id path int true "Cat ID"
+// @Param If-Match header string true "ETag of the version being updated"
 // @Param cat body models.CatRequest true "Cat payload"
-// @Success 200 {object} dbmodel.Cat
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} models.CatResponse
+// @Header 200 {string} ETag "New version of the cat"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /cats/{id} [put]
 func (config *CatConfig) UpdateCatHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	id64, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid cat ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid cat ID")
 		return
 	}
 
 	req := &models.CatRequest{}
 	if err := render.Bind(r, req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid request payload",
-		})
+		problem.BindError(w, r, err)
+		return
+	}
+
+	existing, err := config.CatRepository.FindById(r.Context(), uint(id64))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "cat not found")
+		return
+	}
+	if !etag.Match(w, r, existing.Version) {
+		return
+	}
+
+	config.updateCat(w, r, existing, req)
+}
+
+// PatchCatHandler godoc
+// @Summary Partially update a cat
+// @Description Send a merge patch (application/merge-patch+json) with only the fields to change, null removing the owner, or a JSON Patch (application/json-patch+json). The patched cat is validated as a whole.
+// @Tags cats
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path int true "Cat ID"
+// @Param If-Match header string true "ETag of the version being updated"
+// @Param cat body models.CatRequest true "Fields to change"
+// @Success 200 {object} models.CatResponse
+// @Header 200 {string} ETag "New version of the cat"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 415 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /cats/{id} [patch]
+func (config *CatConfig) PatchCatHandler(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid cat ID")
 		return
 	}
 
-	existing, err := config.CatRepository.FindById(uint(id64))
+	existing, err := config.CatRepository.FindById(r.Context(), uint(id64))
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{
-			"error": "cat not found",
-		})
+		problem.LookupError(w, r, err, http.StatusNotFound, "cat not found")
 		return
 	}
+	if !etag.Match(w, r, existing.Version) {
+		return
+	}
+
+	req := models.NewCatRequest(existing)
+	if err := patch.Bind(r, req); err != nil {
+		patch.WriteError(w, r, err)
+		return
+	}
+
+	config.updateCat(w, r, existing, req)
+}
+
+// updateCat checks the owner, then saves the cat with the fields of req.
+func (config *CatConfig) updateCat(w http.ResponseWriter, r *http.Request, existing *dbmodel.Cat, req *models.CatRequest) {
+	if req.OwnerID != nil {
+		if _, err := config.OwnerRepository.FindById(r.Context(), *req.OwnerID); err != nil {
+			problem.LookupError(w, r, err, http.StatusUnprocessableEntity, "owner not found")
+			return
+		}
+	}
 
 	existing.Name = req.Name
 	existing.Age = req.Age
 	existing.Breed = req.Breed
-	existing.Weigth = req.Weigth
+	existing.OwnerID = req.OwnerID
 
-	updatedCat, err := config.CatRepository.Update(existing)
+	updatedCat, err := config.CatRepository.Update(r.Context(), existing)
+	if errors.Is(err, dbmodel.ErrVersionConflict) {
+		etag.Conflict(w, r)
+		return
+	}
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to update cat",
-		})
+		problem.Write(w, r, http.StatusInternalServerError, "failed to update cat")
 		return
 	}
 
-	render.JSON(w, r, updatedCat)
+	etag.Set(w, updatedCat.Version)
+	render.JSON(w, r, models.NewCatResponse(updatedCat))
 }
 
 // DeleteCatHandler godoc
 // @Summary Delete a cat
 // @Tags cats
 // @Param id path int true "Cat ID"
+// @Param If-Match header string true "ETag of the version being deleted"
 // @Success 204 {object} nil
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /cats/{id} [delete]
 func (config *CatConfig) DeleteCatHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	id64, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid cat ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid cat ID")
 		return
 	}
 
-	cat, err := config.CatRepository.FindById(uint(id64))
+	cat, err := config.CatRepository.FindById(r.Context(), uint(id64))
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{
-			"error": "cat not found",
-		})
+		problem.LookupError(w, r, err, http.StatusNotFound, "cat not found")
+		return
+	}
+	if !etag.Match(w, r, cat.Version) {
 		return
 	}
 
-	if err := config.CatRepository.Delete(uint(id64), cat); err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to delete cat",
-		})
+	if err := config.CatRepository.Delete(r.Context(), uint(id64), cat); err != nil {
+		if errors.Is(err, dbmodel.ErrVersionConflict) {
+			etag.Conflict(w, r)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to delete cat")
 		return
 	}
 
@@ -211,42 +308,79 @@ func (config *CatConfig) DeleteCatHandler(w http.ResponseWriter, r *http.Request
 	render.JSON(w, r, nil)
 }
 
+// RestoreCatHandler godoc
+// @Summary Restore a deleted cat
+// @Description Also restores the visits, treatments, vaccinations, weight measurements and appointments deleted along with the cat.
+// @Tags cats
+// @Produce json
+// @Param id path int true "Cat ID"
+// @Success 200 {object} models.CatResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /cats/{id}/restore [post]
+func (config *CatConfig) RestoreCatHandler(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid cat ID")
+		return
+	}
+
+	restored, err := config.CatRepository.Restore(r.Context(), uint(id64))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			problem.Write(w, r, http.StatusNotFound, "cat not found")
+		case errors.Is(err, dbmodel.ErrNotDeleted), errors.Is(err, dbmodel.ErrParentDeleted):
+			problem.Write(w, r, http.StatusConflict, err.Error())
+		default:
+			problem.Write(w, r, http.StatusInternalServerError, "failed to restore cat")
+		}
+		return
+	}
+
+	etag.Set(w, restored.Version)
+	render.JSON(w, r, models.NewCatResponse(restored))
+}
+
 // GetCatHistoryHandler godoc
-// @Summary Get a cat history (visits)
+// @Summary Get a cat history (visits with their treatments)
 // @Tags cats
 // @Produce json
 // @Param id path int true "Cat ID"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} models.CatHistoryResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /cats/{id}/history [get]
 func (c *CatConfig) GetCatHistoryHandler(w http.ResponseWriter, r *http.Request) {
     idParam := chi.URLParam(r, "id")
     id, err := strconv.Atoi(idParam)
     if err != nil {
-        render.Status(r, http.StatusBadRequest)
-        render.JSON(w, r, map[string]string{"error": "invalid cat ID"})
+        problem.Write(w, r, http.StatusBadRequest, "invalid cat ID")
         return
     }
 
-    cat, err := c.Config.CatRepository.FindById(uint(id))
+    cat, err := c.Config.CatRepository.FindById(r.Context(), uint(id))
     if err != nil {
-        render.Status(r, http.StatusNotFound)
-        render.JSON(w, r, map[string]string{"error": "cat not found"})
+        problem.LookupError(w, r, err, http.StatusNotFound, "cat not found")
         return
     }
 
-    visits, err := c.Config.VisitRepository.FindByCatID(uint(id))
+    visits, err := c.Config.CatRepository.CatHistory(r.Context(), uint(id))
     if err != nil {
-        render.Status(r, http.StatusInternalServerError)
-        render.JSON(w, r, map[string]string{"error": "could not load visits"})
+        problem.Write(w, r, http.StatusInternalServerError, "could not load visits")
         return
     }
 
-    history := map[string]interface{}{
-        "cat":    cat,
-        "visits": visits,
+    history := &models.CatHistoryResponse{
+        Cat:    models.NewCatResponse(cat),
+        Visits: make([]*models.VisitHistoryResponse, 0, len(visits)),
+    }
+    for _, visit := range visits {
+        history.Visits = append(history.Visits, models.NewVisitHistoryResponse(visit))
     }
 
     render.Status(r, http.StatusOK)
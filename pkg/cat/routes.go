@@ -2,6 +2,8 @@ package cat
 
 import (
 	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/authentification"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -9,12 +11,29 @@ func Routes(configuration *config.Config) *chi.Mux {
 	catConfig := New(configuration)
 	router := chi.NewRouter()
 
-	router.Post("/", catConfig.CreateCatHandler)
-	router.Get("/", catConfig.GetAllCatsHandler)
-	router.Get("/{id}", catConfig.GetCatByIDHandler)
-	router.Put("/{id}", catConfig.UpdateCatHandler)
-	router.Delete("/{id}", catConfig.DeleteCatHandler)
-	router.Get("/{id}/history", catConfig.GetCatHistoryHandler)
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionCatsRead))
+		r.Get("/", catConfig.GetAllCatsHandler)
+		r.Get("/{id}", catConfig.GetCatByIDHandler)
+		r.Get("/{id}/history", catConfig.GetCatHistoryHandler)
+		r.Get("/{id}/weights", catConfig.GetCatWeightsHandler)
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionCatsWrite))
+		r.Post("/", catConfig.CreateCatHandler)
+		r.Put("/{id}", catConfig.UpdateCatHandler)
+		r.Patch("/{id}", catConfig.PatchCatHandler)
+		r.Delete("/{id}", catConfig.DeleteCatHandler)
+		r.Post("/{id}/weights", catConfig.CreateCatWeightHandler)
+		r.Delete("/{id}/weights/{weightId}", catConfig.DeleteCatWeightHandler)
+
+		r.Group(func(r chi.Router) {
+			r.Use(authentification.RequirePermission(dbmodel.PermissionDeletedManage))
+			r.Post("/{id}/restore", catConfig.RestoreCatHandler)
+			r.Post("/{id}/weights/{weightId}/restore", catConfig.RestoreCatWeightHandler)
+		})
+	})
 
 	return router
 }
@@ -0,0 +1,281 @@
+package cat
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/etag"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"gorm.io/gorm"
+)
+
+// weightTrendPeriods are the periods, in days, summarized in the cat detail.
+var weightTrendPeriods = []int{30, 90, 365}
+
+// CreateCatWeightHandler godoc
+// @Summary Record a weight measurement for a cat
+// @Description unit is kg (default), g or lb; measured_at defaults to the visit date, or now.
+// @Tags cats
+// @Accept json
+// @Produce json
+// @Param id path int true "Cat ID"
+// @Param measurement body models.WeightMeasurementRequest true "Weight measurement"
+// @Success 201 {object} models.WeightMeasurementResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /cats/{id}/weights [post]
+func (config *CatConfig) CreateCatWeightHandler(w http.ResponseWriter, r *http.Request) {
+	cat, ok := config.findCat(w, r)
+	if !ok {
+		return
+	}
+
+	req := &models.WeightMeasurementRequest{}
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	measurement := &dbmodel.WeightMeasurement{
+		CatID:   cat.ID,
+		Value:   req.Value,
+		Unit:    req.Unit,
+		VisitID: req.VisitID,
+		Notes:   req.Notes,
+	}
+
+	if req.VisitID != nil {
+		visit, err := config.VisitRepository.FindById(r.Context(), *req.VisitID)
+		if err != nil {
+			problem.LookupError(w, r, err, http.StatusUnprocessableEntity, "visit not found")
+			return
+		}
+		if visit.CatID != cat.ID {
+			problem.Write(w, r, http.StatusUnprocessableEntity, "the visit belongs to another cat")
+			return
+		}
+		measurement.MeasuredAt = visit.Date.UTC()
+	}
+	if req.MeasuredAt != nil {
+		measurement.MeasuredAt = *req.MeasuredAt
+	}
+	if measurement.MeasuredAt.IsZero() {
+		measurement.MeasuredAt = time.Now().UTC()
+	}
+
+	saved, err := config.WeightMeasurementRepository.Create(r.Context(), measurement)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "unable to save weight measurement")
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, models.NewWeightMeasurementResponse(saved))
+}
+
+// GetCatWeightsHandler godoc
+// @Summary List the weight measurements of a cat
+// @Tags cats
+// @Produce json
+// @Param id path int true "Cat ID"
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. -measured_at (id, measured_at, value, created_at)"
+// @Param include_deleted query bool false "Also list deleted records (requires deleted:manage)"
+// @Param measured_from query string false "Measured on or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param measured_to query string false "Measured on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Param visit_id query int false "Visit ID"
+// @Success 200 {array} models.WeightMeasurementResponse
+// @Header 200 {integer} X-Total-Count "Total number of matching measurements"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /cats/{id}/weights [get]
+func (config *CatConfig) GetCatWeightsHandler(w http.ResponseWriter, r *http.Request) {
+	cat, ok := config.findCat(w, r)
+	if !ok {
+		return
+	}
+
+	opts, err := models.ParseQueryOptions(r)
+	if err != nil {
+		problem.InvalidQuery(w, r, err)
+		return
+	}
+	if len(opts.Sort) == 0 {
+		opts.Sort = []dbmodel.SortField{{Field: "measured_at"}}
+	}
+	opts.Filters["cat_id"] = strconv.FormatUint(uint64(cat.ID), 10)
+
+	measurements, total, err := config.WeightMeasurementRepository.FindAll(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch weight measurements")
+		return
+	}
+	models.SetPaginationHeaders(w, r, opts, total)
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, models.NewWeightMeasurementResponses(measurements))
+}
+
+// DeleteCatWeightHandler godoc
+// @Summary Delete a weight measurement recorded by mistake
+// @Tags cats
+// @Param id path int true "Cat ID"
+// @Param weightId path int true "Measurement ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /cats/{id}/weights/{weightId} [delete]
+func (config *CatConfig) DeleteCatWeightHandler(w http.ResponseWriter, r *http.Request) {
+	cat, ok := config.findCat(w, r)
+	if !ok {
+		return
+	}
+
+	id64, err := strconv.ParseUint(chi.URLParam(r, "weightId"), 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid measurement ID")
+		return
+	}
+
+	measurement, err := config.WeightMeasurementRepository.FindById(r.Context(), uint(id64))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "weight measurement not found")
+		return
+	}
+	if measurement.CatID != cat.ID {
+		problem.Write(w, r, http.StatusNotFound, "weight measurement not found")
+		return
+	}
+
+	if err := config.WeightMeasurementRepository.Delete(r.Context(), measurement.ID, measurement); err != nil {
+		if errors.Is(err, dbmodel.ErrVersionConflict) {
+			etag.Conflict(w, r)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to delete weight measurement")
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+// RestoreCatWeightHandler godoc
+// @Summary Restore a deleted weight measurement
+// @Tags cats
+// @Produce json
+// @Param id path int true "Cat ID"
+// @Param weightId path int true "Measurement ID"
+// @Success 200 {object} models.WeightMeasurementResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /cats/{id}/weights/{weightId}/restore [post]
+func (config *CatConfig) RestoreCatWeightHandler(w http.ResponseWriter, r *http.Request) {
+	cat, ok := config.findCat(w, r)
+	if !ok {
+		return
+	}
+
+	id64, err := strconv.ParseUint(chi.URLParam(r, "weightId"), 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid measurement ID")
+		return
+	}
+
+	measurement, err := config.WeightMeasurementRepository.Restore(r.Context(), cat.ID, uint(id64))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			problem.Write(w, r, http.StatusNotFound, "weight measurement not found")
+		case errors.Is(err, dbmodel.ErrNotDeleted):
+			problem.Write(w, r, http.StatusConflict, err.Error())
+		default:
+			problem.Write(w, r, http.StatusInternalServerError, "failed to restore weight measurement")
+		}
+		return
+	}
+
+	render.JSON(w, r, models.NewWeightMeasurementResponse(measurement))
+}
+
+func (config *CatConfig) findCat(w http.ResponseWriter, r *http.Request) (*dbmodel.Cat, bool) {
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid cat ID")
+		return nil, false
+	}
+
+	cat, err := config.CatRepository.FindById(r.Context(), uint(id64))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "cat not found")
+		return nil, false
+	}
+	return cat, true
+}
+
+// catDetail adds the current weight and the weight trend to a cat.
+func (config *CatConfig) catDetail(ctx context.Context, cat *dbmodel.Cat) (*models.CatDetailResponse, error) {
+	detail := &models.CatDetailResponse{CatResponse: models.NewCatResponse(cat), WeightTrend: []models.WeightTrend{}}
+
+	current, err := config.WeightMeasurementRepository.Latest(ctx, cat.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return detail, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	detail.CurrentWeight = models.NewWeightMeasurementResponse(current)
+	currentKg := roundTo(current.Kilograms, 3)
+	detail.CurrentWeightKg = &currentKg
+
+	now := time.Now()
+	for _, days := range weightTrendPeriods {
+		trend := models.WeightTrend{PeriodDays: days}
+
+		reference, err := config.WeightMeasurementRepository.LatestBefore(ctx, cat.ID, now.AddDate(0, 0, -days))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if reference != nil {
+			referenceKg := roundTo(reference.Kilograms, 3)
+			changeKg := roundTo(current.Kilograms-reference.Kilograms, 3)
+			trend.ReferenceMeasuredAt = &reference.MeasuredAt
+			trend.ReferenceKg = &referenceKg
+			trend.ChangeKg = &changeKg
+			if reference.Kilograms > 0 {
+				changePercent := roundTo((current.Kilograms-reference.Kilograms)/reference.Kilograms*100, 1)
+				trend.ChangePercent = &changePercent
+			}
+		}
+
+		detail.WeightTrend = append(detail.WeightTrend, trend)
+	}
+
+	return detail, nil
+}
+
+func roundTo(value float64, decimals int) float64 {
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
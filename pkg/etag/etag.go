@@ -0,0 +1,64 @@
+// Package etag implements conditional requests on versioned records. Responses carry an
+// ETag made from the record version; a GET sending the current ETag in If-None-Match is
+// answered 304 Not Modified, and a PUT or DELETE must send in If-Match the ETag of the
+// version it is based on, so that it cannot overwrite a change it has not seen.
+package etag
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+)
+
+// For returns the entity tag of the given version of a record.
+func For(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// Set sends the entity tag of the given version in the ETag header.
+func Set(w http.ResponseWriter, version uint) {
+	w.Header().Set("ETag", For(version))
+}
+
+// NotModified sets the ETag header and, if If-None-Match lists the tag of the given
+// version, answers 304 Not Modified and returns true.
+func NotModified(w http.ResponseWriter, r *http.Request, version uint) bool {
+	Set(w, version)
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == For(version) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// Match checks that If-Match names the given version. If not, it answers 428 Precondition
+// Required when the header is missing or 412 Precondition Failed, and returns false.
+func Match(w http.ResponseWriter, r *http.Request, version uint) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		problem.Write(w, r, http.StatusPreconditionRequired, "send the ETag of the record in If-Match")
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		// Weak tags never match: If-Match uses the strong comparison.
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == For(version) {
+			return true
+		}
+	}
+	Conflict(w, r)
+	return false
+}
+
+// Conflict answers 412 Precondition Failed to a write based on an outdated version.
+func Conflict(w http.ResponseWriter, r *http.Request) {
+	problem.Write(w, r, http.StatusPreconditionFailed, "the record was modified since it was read; fetch it again")
+}
@@ -0,0 +1,78 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// recordHandler serves a record at version 3 the way the resource handlers do.
+func recordHandler(w http.ResponseWriter, r *http.Request) {
+	const version = 3
+	switch r.Method {
+	case http.MethodGet:
+		if NotModified(w, r, version) {
+			return
+		}
+		w.Write([]byte(`{"version":3}`))
+	default:
+		if !Match(w, r, version) {
+			return
+		}
+		Set(w, version+1)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func TestFor(t *testing.T) {
+	if tag := For(3); tag != `"3"` {
+		t.Errorf("For(3) = %s, want \"3\"", tag)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		header string
+		value  string
+		status int
+		etag   string
+	}{
+		{"get", http.MethodGet, "", "", http.StatusOK, `"3"`},
+		{"get the current version", http.MethodGet, "If-None-Match", `"3"`, http.StatusNotModified, `"3"`},
+		{"get a weak current version", http.MethodGet, "If-None-Match", `W/"3"`, http.StatusNotModified, `"3"`},
+		{"get any version", http.MethodGet, "If-None-Match", `*`, http.StatusNotModified, `"3"`},
+		{"get an older version", http.MethodGet, "If-None-Match", `"1", "2"`, http.StatusOK, `"3"`},
+		{"put the current version", http.MethodPut, "If-Match", `"3"`, http.StatusOK, `"4"`},
+		{"put one of several versions", http.MethodPut, "If-Match", `"2", "3"`, http.StatusOK, `"4"`},
+		{"put any version", http.MethodPut, "If-Match", `*`, http.StatusOK, `"4"`},
+		{"put an older version", http.MethodPut, "If-Match", `"2"`, http.StatusPreconditionFailed, ""},
+		{"put a weak tag", http.MethodPut, "If-Match", `W/"3"`, http.StatusPreconditionFailed, ""},
+		{"put without If-Match", http.MethodPut, "", "", http.StatusPreconditionRequired, ""},
+		{"delete an older version", http.MethodDelete, "If-Match", `"4"`, http.StatusPreconditionFailed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/cats/1", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			http.HandlerFunc(recordHandler).ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if got := rec.Header().Get("ETag"); got != tt.etag {
+				t.Errorf("ETag = %q, want %q", got, tt.etag)
+			}
+			if rec.Code == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 response has a body: %s", rec.Body.String())
+			}
+			if rec.Code >= http.StatusBadRequest && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("error Content-Type = %q, want a problem", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+)
+
+const maxAppointmentDuration = 8 * time.Hour
+
+type AppointmentRequest struct {
+	CatID          uint      `json:"cat_id"`
+	VeterinarianID uint      `json:"veterinarian_id"`
+	StartsAt       time.Time `json:"starts_at"`
+	EndsAt         time.Time `json:"ends_at"`
+	Reason         string    `json:"reason"`
+	Notes          string    `json:"notes"`
+}
+
+func (a *AppointmentRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
+	if a.CatID == 0 {
+		errs.Add("cat_id", "cat_id must not be empty")
+	}
+	if a.VeterinarianID == 0 {
+		errs.Add("veterinarian_id", "veterinarian_id must not be empty")
+	}
+	if a.StartsAt.IsZero() {
+		errs.Add("starts_at", "starts_at must not be empty")
+	}
+	if a.EndsAt.IsZero() {
+		errs.Add("ends_at", "ends_at must not be empty")
+	}
+	if !a.StartsAt.IsZero() && !a.EndsAt.IsZero() {
+		if !a.EndsAt.After(a.StartsAt) {
+			errs.Add("ends_at", "ends_at must be after starts_at")
+		} else if a.EndsAt.Sub(a.StartsAt) > maxAppointmentDuration {
+			errs.Add("ends_at", fmt.Sprintf("an appointment cannot last longer than %s", maxAppointmentDuration))
+		}
+	}
+
+	a.StartsAt = a.StartsAt.UTC()
+	a.EndsAt = a.EndsAt.UTC()
+	return errs.Err()
+}
+
+type AppointmentStatusRequest struct {
+	Status string `json:"status"`
+}
+
+func (a *AppointmentStatusRequest) Bind(r *http.Request) error {
+	switch a.Status {
+	case dbmodel.AppointmentBooked, dbmodel.AppointmentCheckedIn, dbmodel.AppointmentCancelled,
+		dbmodel.AppointmentNoShow, dbmodel.AppointmentCompleted:
+		return nil
+	}
+	return problem.Field("status", "status must be booked, checked_in, cancelled, no_show or completed")
+}
+
+type WorkingHoursSlot struct {
+	Weekday time.Weekday `json:"weekday" swaggertype:"integer"`
+	Start   string       `json:"start"`
+	End     string       `json:"end"`
+}
+
+type WorkingHoursRequest struct {
+	Hours []WorkingHoursSlot `json:"hours"`
+}
+
+func (w *WorkingHoursRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
+	for i, slot := range w.Hours {
+		field := fmt.Sprintf("hours[%d]", i)
+		if slot.Weekday < time.Sunday || slot.Weekday > time.Saturday {
+			errs.Add(field+".weekday", field+".weekday must be between 0 (Sunday) and 6 (Saturday)")
+		}
+		start, startErr := time.Parse("15:04", slot.Start)
+		if startErr != nil {
+			errs.Add(field+".start", field+".start must be in HH:MM format")
+		}
+		end, endErr := time.Parse("15:04", slot.End)
+		if endErr != nil {
+			errs.Add(field+".end", field+".end must be in HH:MM format")
+		}
+		if startErr == nil && endErr == nil && !end.After(start) {
+			errs.Add(field+".end", field+".end must be after start")
+		}
+	}
+	return errs.Err()
+}
+
+type AppointmentResponse struct {
+	ID             uint       `json:"id"`
+	CatID          uint       `json:"cat_id"`
+	VeterinarianID uint       `json:"veterinarian_id"`
+	StartsAt       time.Time  `json:"starts_at"`
+	EndsAt         time.Time  `json:"ends_at"`
+	Status         string     `json:"status"`
+	Reason         string     `json:"reason"`
+	Notes          string     `json:"notes"`
+	VisitID        *uint      `json:"visit_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
+
+func NewAppointmentResponse(appointment *dbmodel.Appointment) *AppointmentResponse {
+	return &AppointmentResponse{
+		ID:             appointment.ID,
+		CatID:          appointment.CatID,
+		VeterinarianID: appointment.VeterinarianID,
+		StartsAt:       appointment.StartsAt,
+		EndsAt:         appointment.EndsAt,
+		Status:         appointment.Status,
+		Reason:         appointment.Reason,
+		Notes:          appointment.Notes,
+		VisitID:        appointment.VisitID,
+		CreatedAt:      appointment.CreatedAt,
+		UpdatedAt:      appointment.UpdatedAt,
+		DeletedAt:      deletedAt(appointment.DeletedAt),
+	}
+}
+
+func NewAppointmentResponses(appointments []*dbmodel.Appointment) []*AppointmentResponse {
+	return mapList(appointments, NewAppointmentResponse)
+}
+
+type SlotResponse struct {
+	VeterinarianID uint      `json:"veterinarian_id"`
+	StartsAt       time.Time `json:"starts_at"`
+	EndsAt         time.Time `json:"ends_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+)
+
+type AuditChangeResponse struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type AuditLogResponse struct {
+	ID         uint                           `json:"id"`
+	CreatedAt  time.Time                      `json:"created_at"`
+	ActorID    *uint                          `json:"actor_id"`
+	ActorEmail string                         `json:"actor_email"`
+	Action     string                         `json:"action"`
+	EntityType string                         `json:"entity_type"`
+	EntityID   uint                           `json:"entity_id"`
+	Changes    map[string]AuditChangeResponse `json:"changes"`
+	RequestID  string                         `json:"request_id"`
+}
+
+func NewAuditLogResponse(entry *dbmodel.AuditLog) *AuditLogResponse {
+	changes := make(map[string]AuditChangeResponse, len(entry.Changes))
+	for column, change := range entry.Changes {
+		changes[column] = AuditChangeResponse{Before: change.Before, After: change.After}
+	}
+	return &AuditLogResponse{
+		ID:         entry.ID,
+		CreatedAt:  entry.CreatedAt,
+		ActorID:    entry.ActorID,
+		ActorEmail: entry.ActorEmail,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		Changes:    changes,
+		RequestID:  entry.RequestID,
+	}
+}
+
+func NewAuditLogResponses(entries []*dbmodel.AuditLog) []*AuditLogResponse {
+	return mapList(entries, NewAuditLogResponse)
+}
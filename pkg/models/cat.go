@@ -1,37 +1,76 @@
 package models
 
 import (
-	"errors"
 	"net/http"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
 )
 
 type CatRequest struct {
-	Name   string `json:"name"`
-	Age    int    `json:"age"`
-	Breed  string `json:"breed"`
-	Weigth int    `json:"weigth"`
+	Name    string `json:"name"`
+	Age     int    `json:"age"`
+	Breed   string `json:"breed"`
+	OwnerID *uint  `json:"owner_id,omitempty"`
 }
 
-func (c *CatRequest) Bind(r *http.Request) error{
+func (c *CatRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
 	if c.Name == "" {
-		return errors.New("le champ name ne doit pas être vide")
+		errs.Add("name", "name must not be empty")
 	}
 	if c.Age < 0 {
-		return errors.New("age doit être supérieur ou égale à 0 ")
+		errs.Add("age", "age must be greater than or equal to 0")
 	}
 	if c.Breed == "" {
-		return errors.New("le champ breed ne doit pas être vide")
-	}
-	if c.Age < 0 {
-		return errors.New("weigth doit être supérieur ou égale à 0 ")
+		errs.Add("breed", "breed must not be empty")
 	}
-	return nil
+	return errs.Err()
+}
 
+// NewCatRequest returns the payload that would leave the cat unchanged, to which a PATCH
+// applies its changes.
+func NewCatRequest(cat *dbmodel.Cat) *CatRequest {
+	return &CatRequest{
+		Name:    cat.Name,
+		Age:     cat.Age,
+		Breed:   cat.Breed,
+		OwnerID: cat.OwnerID,
+	}
 }
 
 type CatResponse struct {
-	Name   string `json:"name"`
-	Age    int    `json:"age"`
-	Breed  string `json:"breed"`
-	Weigth int    `json:"weigth"`
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Age       int        `json:"age"`
+	Breed     string     `json:"breed"`
+	OwnerID   *uint      `json:"owner_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func NewCatResponse(cat *dbmodel.Cat) *CatResponse {
+	return &CatResponse{
+		ID:        cat.ID,
+		Name:      cat.Name,
+		Age:       cat.Age,
+		Breed:     cat.Breed,
+		OwnerID:   cat.OwnerID,
+		CreatedAt: cat.CreatedAt,
+		UpdatedAt: cat.UpdatedAt,
+		DeletedAt: deletedAt(cat.DeletedAt),
+	}
+}
+
+func NewCatResponses(cats []*dbmodel.Cat) []*CatResponse {
+	return mapList(cats, NewCatResponse)
+}
+
+// CatHistoryResponse is a cat with all its visits and their treatments.
+type CatHistoryResponse struct {
+	Cat    *CatResponse            `json:"cat"`
+	Visits []*VisitHistoryResponse `json:"visits"`
 }
@@ -0,0 +1,270 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"gorm.io/gorm"
+)
+
+var (
+	created  = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	updated  = time.Date(2024, 3, 2, 10, 30, 0, 0, time.UTC)
+	deleted  = time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC)
+	ownerID  = uint(4)
+	vetID    = uint(7)
+	visitID  = uint(11)
+	userID   = uint(13)
+	license  = "LIC-42"
+	lockedAt = time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+)
+
+func assertMapped(t *testing.T, got, want interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestNewCatResponse(t *testing.T) {
+	cat := &dbmodel.Cat{
+		ID: 1, CreatedAt: created, UpdatedAt: updated, Version: 3,
+		DeletedAt: gorm.DeletedAt{Time: deleted, Valid: true},
+		Name:      "Felix", Age: 4, Breed: "Siamese", OwnerID: &ownerID,
+	}
+	assertMapped(t, NewCatResponse(cat), &CatResponse{
+		ID: 1, Name: "Felix", Age: 4, Breed: "Siamese", OwnerID: &ownerID,
+		CreatedAt: created, UpdatedAt: updated, DeletedAt: &deleted,
+	})
+	assertMapped(t, NewCatRequest(cat), &CatRequest{Name: "Felix", Age: 4, Breed: "Siamese", OwnerID: &ownerID})
+
+	cat.DeletedAt = gorm.DeletedAt{}
+	if got := NewCatResponse(cat).DeletedAt; got != nil {
+		t.Errorf("DeletedAt = %v for a live cat, want nil", got)
+	}
+	if got := NewCatResponses(nil); got == nil || len(got) != 0 {
+		t.Errorf("NewCatResponses(nil) = %#v, want an empty list", got)
+	}
+}
+
+func TestNewVisitResponse(t *testing.T) {
+	start := created.AddDate(0, 0, 1)
+	visit := &dbmodel.Visit{
+		ID: 2, CreatedAt: created, UpdatedAt: updated, Version: 2,
+		Date: created, Motif: "checkup", CatID: 1,
+		VeterinarianID: &vetID,
+		Veterinarian:   &dbmodel.Veterinarian{ID: vetID, Name: "Dr Martin"},
+		Treatments: []dbmodel.Treatment{
+			{ID: 5, Name: "Amoxicillin", Dosage: 50, Unit: "mg", StartDate: &start, VisitID: 2},
+		},
+	}
+	want := &VisitResponse{
+		ID: 2, Date: created, Motif: "checkup", CatID: 1,
+		VeterinarianID: &vetID,
+		Veterinarian:   &VeterinarianSummary{ID: vetID, Name: "Dr Martin"},
+		CreatedAt:      created, UpdatedAt: updated,
+	}
+	assertMapped(t, NewVisitResponse(visit), want)
+	assertMapped(t, NewVisitHistoryResponse(visit), &VisitHistoryResponse{
+		VisitResponse: want,
+		Treatments: []*TreatmentResponse{
+			{ID: 5, Name: "Amoxicillin", Dosage: 50, Unit: "mg", StartDate: &start, VisitID: 2},
+		},
+	})
+	assertMapped(t, NewVisitUpdateRequest(visit), &VisitUpdateRequest{
+		Date: created, Motif: "checkup", VeterinarianID: &vetID, CatID: 1,
+	})
+
+	visit.Veterinarian = nil
+	if got := NewVisitResponse(visit).Veterinarian; got != nil {
+		t.Errorf("Veterinarian = %+v when it was not loaded, want nil", got)
+	}
+}
+
+func TestNewTreatmentResponse(t *testing.T) {
+	end := created.AddDate(0, 0, 7)
+	treatment := &dbmodel.Treatment{
+		ID: 5, CreatedAt: created, UpdatedAt: updated, Version: 1,
+		DeletedAt: gorm.DeletedAt{Time: deleted, Valid: true},
+		Name:      "Amoxicillin", Dosage: 50, Unit: "mg", Route: "oral", Frequency: "twice a day",
+		StartDate: &created, EndDate: &end, Notes: "with food", VisitID: 2,
+	}
+	assertMapped(t, NewTreatmentResponse(treatment), &TreatmentResponse{
+		ID: 5, Name: "Amoxicillin", Dosage: 50, Unit: "mg", Route: "oral", Frequency: "twice a day",
+		StartDate: &created, EndDate: &end, Notes: "with food", VisitID: 2,
+		CreatedAt: created, UpdatedAt: updated, DeletedAt: &deleted,
+	})
+	assertMapped(t, NewTreatmentRequest(treatment), &TreatmentRequest{
+		Name: "Amoxicillin", Dosage: 50, Unit: "mg", Route: "oral", Frequency: "twice a day",
+		StartDate: &created, EndDate: &end, Notes: "with food", VisitID: 2,
+	})
+}
+
+func TestNewUserResponse(t *testing.T) {
+	user := &dbmodel.User{
+		Model:    gorm.Model{ID: userID, CreatedAt: created, UpdatedAt: updated},
+		Email:    "vet@example.com",
+		Password: "$2a$10$abcdefghijklmnopqrstuuJ4qkqR5Y8k3bC9e0Vb6y4H2Qm0xW1e",
+		Role:     "admin",
+
+		FailedLoginAttempts: 2,
+		LockedUntil:         &lockedAt,
+		LastLoginAt:         &updated,
+		LastFailedLoginAt:   &lockedAt,
+		DisabledAt:          &deleted,
+	}
+	response := NewUserResponse(user)
+	assertMapped(t, response, &UserResponse{
+		ID: userID, Email: "vet@example.com", Role: "admin",
+		CreatedAt: created, UpdatedAt: updated,
+
+		FailedLoginAttempts: 2,
+		LockedUntil:         &lockedAt,
+		LastLoginAt:         &updated,
+		LastFailedLoginAt:   &lockedAt,
+		DisabledAt:          &deleted,
+	})
+	assertMapped(t, NewUserPatchRequest(user), &UserPatchRequest{Email: "vet@example.com", Role: "admin"})
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"password", "refresh_token", "$2"} {
+		if strings.Contains(string(body), secret) {
+			t.Errorf("user response contains %q: %s", secret, body)
+		}
+	}
+}
+
+func TestNewOwnerResponse(t *testing.T) {
+	owner := &dbmodel.Owner{
+		ID: ownerID, CreatedAt: created, UpdatedAt: updated,
+		DeletedAt: gorm.DeletedAt{Time: deleted, Valid: true},
+		Name:      "Alice Durand", Phone: "+33612345678", Email: "alice@example.com",
+		Address: "1 rue de la Paix", PreferredContact: "email", Notes: "prefers mornings",
+	}
+	assertMapped(t, NewOwnerResponse(owner), &OwnerResponse{
+		ID: ownerID, Name: "Alice Durand", Phone: "+33612345678", Email: "alice@example.com",
+		Address: "1 rue de la Paix", PreferredContact: "email", Notes: "prefers mornings",
+		CreatedAt: created, UpdatedAt: updated, DeletedAt: &deleted,
+	})
+}
+
+func TestNewAppointmentResponse(t *testing.T) {
+	ends := created.Add(30 * time.Minute)
+	appointment := &dbmodel.Appointment{
+		ID: 9, CreatedAt: created, UpdatedAt: updated,
+		DeletedAt: gorm.DeletedAt{Time: deleted, Valid: true},
+		CatID:     1, VeterinarianID: vetID, StartsAt: created, EndsAt: ends,
+		Status: "completed", Reason: "vaccine", Notes: "calm", VisitID: &visitID,
+	}
+	assertMapped(t, NewAppointmentResponse(appointment), &AppointmentResponse{
+		ID: 9, CatID: 1, VeterinarianID: vetID, StartsAt: created, EndsAt: ends,
+		Status: "completed", Reason: "vaccine", Notes: "calm", VisitID: &visitID,
+		CreatedAt: created, UpdatedAt: updated, DeletedAt: &deleted,
+	})
+}
+
+func TestNewVaccinationResponse(t *testing.T) {
+	due := created.AddDate(1, 0, 0)
+	vaccination := &dbmodel.Vaccination{
+		ID: 6, CreatedAt: created, UpdatedAt: updated, Version: 1,
+		DeletedAt: gorm.DeletedAt{Time: deleted, Valid: true},
+		CatID:     1, VaccineName: "Rabies", LotNumber: "L-1", Manufacturer: "Acme",
+		AdministeredAt: created, VeterinarianID: &vetID,
+		Veterinarian: &dbmodel.Veterinarian{ID: vetID, Name: "Dr Martin"},
+		NextDueAt:    &due, VisitID: &visitID, Notes: "left leg",
+	}
+	assertMapped(t, NewVaccinationResponse(vaccination), &VaccinationResponse{
+		ID: 6, CatID: 1, VaccineName: "Rabies", LotNumber: "L-1", Manufacturer: "Acme",
+		AdministeredAt: created, VeterinarianID: &vetID,
+		Veterinarian: &VeterinarianSummary{ID: vetID, Name: "Dr Martin"},
+		NextDueAt:    &due, VisitID: &visitID, Notes: "left leg",
+		CreatedAt: created, UpdatedAt: updated, DeletedAt: &deleted,
+	})
+}
+
+func TestNewVeterinarianResponse(t *testing.T) {
+	veterinarian := &dbmodel.Veterinarian{
+		ID: vetID, CreatedAt: created, UpdatedAt: updated,
+		DeletedAt: gorm.DeletedAt{Time: deleted, Valid: true},
+		Name:      "Dr Martin", LicenseNumber: &license, Specialties: []string{"surgery"},
+		Active: true, UserID: &userID,
+	}
+	assertMapped(t, NewVeterinarianResponse(veterinarian), &VeterinarianResponse{
+		ID: vetID, Name: "Dr Martin", LicenseNumber: &license, Specialties: []string{"surgery"},
+		Active: true, UserID: &userID, CreatedAt: created, UpdatedAt: updated, DeletedAt: &deleted,
+	})
+	assertMapped(t, NewVeterinarianSummary(veterinarian), &VeterinarianSummary{ID: vetID, Name: "Dr Martin"})
+	if got := NewVeterinarianSummary(nil); got != nil {
+		t.Errorf("NewVeterinarianSummary(nil) = %+v, want nil", got)
+	}
+
+	veterinarian.Specialties = nil
+	if got := NewVeterinarianResponse(veterinarian).Specialties; got == nil {
+		t.Error("Specialties is nil, want an empty list")
+	}
+
+	hours := []dbmodel.WorkingHours{
+		{ID: 3, CreatedAt: created, VeterinarianID: vetID, Weekday: time.Tuesday, Start: "09:00", End: "12:30"},
+	}
+	assertMapped(t, NewWorkingHoursResponses(hours), []*WorkingHoursResponse{
+		{ID: 3, VeterinarianID: vetID, Weekday: time.Tuesday, Start: "09:00", End: "12:30"},
+	})
+}
+
+func TestNewWeightMeasurementResponse(t *testing.T) {
+	measurement := &dbmodel.WeightMeasurement{
+		ID: 8, CreatedAt: created, UpdatedAt: updated, Version: 1,
+		DeletedAt: gorm.DeletedAt{Time: deleted, Valid: true},
+		CatID:     1, Value: 9.5, Unit: "lb", Kilograms: 4.309, MeasuredAt: created,
+		VisitID: &visitID, Notes: "after meal",
+	}
+	assertMapped(t, NewWeightMeasurementResponse(measurement), &WeightMeasurementResponse{
+		ID: 8, CatID: 1, Value: 9.5, Unit: "lb", MeasuredAt: created,
+		VisitID: &visitID, Notes: "after meal",
+		CreatedAt: created, UpdatedAt: updated, DeletedAt: &deleted,
+	})
+	if got := NewWeightMeasurementResponse(nil); got != nil {
+		t.Errorf("NewWeightMeasurementResponse(nil) = %+v, want nil", got)
+	}
+}
+
+func TestNewAuditLogResponse(t *testing.T) {
+	entry := &dbmodel.AuditLog{
+		ID: 10, CreatedAt: created, ActorID: &userID, ActorEmail: "vet@example.com",
+		Action: "update", EntityType: "cat", EntityID: 1, RequestID: "req-1",
+		Changes: map[string]dbmodel.AuditChange{"name": {Before: "Felix", After: "Tom"}},
+	}
+	assertMapped(t, NewAuditLogResponse(entry), &AuditLogResponse{
+		ID: 10, CreatedAt: created, ActorID: &userID, ActorEmail: "vet@example.com",
+		Action: "update", EntityType: "cat", EntityID: 1, RequestID: "req-1",
+		Changes: map[string]AuditChangeResponse{"name": {Before: "Felix", After: "Tom"}},
+	})
+}
+
+func TestNewRoleResponse(t *testing.T) {
+	permission := dbmodel.Permission{ID: 1, Name: "cats:read", Description: "Read cats"}
+	role := &dbmodel.Role{
+		ID: 2, CreatedAt: created, UpdatedAt: updated,
+		Name: "reception", Description: "Front desk", Permissions: []dbmodel.Permission{permission},
+	}
+	assertMapped(t, NewRoleResponse(role), &RoleResponse{
+		ID: 2, Name: "reception", Description: "Front desk", Permissions: []string{"cats:read"},
+		CreatedAt: created, UpdatedAt: updated,
+	})
+	assertMapped(t, NewPermissionResponse(&permission), &PermissionResponse{Name: "cats:read", Description: "Read cats"})
+}
+
+func TestNewPurgeResponse(t *testing.T) {
+	result := dbmodel.PurgeResult{"cat": 2, "visit": 5}
+	assertMapped(t, NewPurgeResponse(deleted, result), &PurgeResponse{
+		DeletedBefore: deleted,
+		Purged:        map[string]int64{"cat": 2, "visit": 5},
+	})
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+)
+
+var preferredContactMethods = map[string]bool{
+	"phone": true,
+	"sms":   true,
+	"email": true,
+	"post":  true,
+}
+
+type OwnerRequest struct {
+	Name             string `json:"name"`
+	Phone            string `json:"phone"`
+	Email            string `json:"email"`
+	Address          string `json:"address"`
+	PreferredContact string `json:"preferred_contact"`
+	Notes            string `json:"notes"`
+}
+
+func (o *OwnerRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
+	if o.Name == "" {
+		errs.Add("name", "name must not be empty")
+	}
+	if o.Phone == "" && o.Email == "" {
+		errs.Add("phone", "phone or email is required")
+		errs.Add("email", "phone or email is required")
+	}
+	if o.Email != "" {
+		if _, err := mail.ParseAddress(o.Email); err != nil {
+			errs.Add("email", "email is not a valid address")
+		}
+	}
+	if o.PreferredContact != "" && !preferredContactMethods[o.PreferredContact] {
+		errs.Add("preferred_contact", "preferred_contact must be phone, sms, email or post")
+	}
+	if (o.PreferredContact == "phone" || o.PreferredContact == "sms") && o.Phone == "" {
+		errs.Add("phone", "phone is required for this contact method")
+	}
+	if o.PreferredContact == "email" && o.Email == "" {
+		errs.Add("email", "email is required for this contact method")
+	}
+	if o.PreferredContact == "post" && o.Address == "" {
+		errs.Add("address", "address is required for this contact method")
+	}
+
+	o.Phone = dbmodel.NormalizePhone(o.Phone)
+	return errs.Err()
+}
+
+type OwnerResponse struct {
+	ID               uint       `json:"id"`
+	Name             string     `json:"name"`
+	Phone            string     `json:"phone"`
+	Email            string     `json:"email"`
+	Address          string     `json:"address"`
+	PreferredContact string     `json:"preferred_contact"`
+	Notes            string     `json:"notes"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+}
+
+func NewOwnerResponse(owner *dbmodel.Owner) *OwnerResponse {
+	return &OwnerResponse{
+		ID:               owner.ID,
+		Name:             owner.Name,
+		Phone:            owner.Phone,
+		Email:            owner.Email,
+		Address:          owner.Address,
+		PreferredContact: owner.PreferredContact,
+		Notes:            owner.Notes,
+		CreatedAt:        owner.CreatedAt,
+		UpdatedAt:        owner.UpdatedAt,
+		DeletedAt:        deletedAt(owner.DeletedAt),
+	}
+}
+
+func NewOwnerResponses(owners []*dbmodel.Owner) []*OwnerResponse {
+	return mapList(owners, NewOwnerResponse)
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+)
+
+// ParseQueryOptions reads page, limit, sort and include_deleted from the query string;
+// every other parameter is passed to the repository as a filter.
+func ParseQueryOptions(r *http.Request) (dbmodel.QueryOptions, error) {
+	query := r.URL.Query()
+	opts := dbmodel.QueryOptions{
+		Page:    1,
+		Limit:   dbmodel.DefaultPageSize,
+		Filters: map[string]string{},
+	}
+
+	if page := query.Get("page"); page != "" {
+		value, err := strconv.Atoi(page)
+		if err != nil || value < 1 {
+			return opts, problem.Field("page", "page must be an integer greater than or equal to 1")
+		}
+		opts.Page = value
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > dbmodel.MaxPageSize {
+			return opts, problem.Field("limit", fmt.Sprintf("limit must be between 1 and %d", dbmodel.MaxPageSize))
+		}
+		opts.Limit = value
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		for _, field := range strings.Split(sort, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			desc := strings.HasPrefix(field, "-")
+			opts.Sort = append(opts.Sort, dbmodel.SortField{
+				Field: strings.TrimPrefix(field, "-"),
+				Desc:  desc,
+			})
+		}
+	}
+
+	if includeDeleted := query.Get("include_deleted"); includeDeleted != "" {
+		value, err := strconv.ParseBool(includeDeleted)
+		if err != nil {
+			return opts, problem.Field("include_deleted", "include_deleted must be true or false")
+		}
+		opts.IncludeDeleted = value
+	}
+
+	for name, values := range query {
+		if name == "page" || name == "limit" || name == "sort" || name == "include_deleted" || len(values) == 0 {
+			continue
+		}
+		opts.Filters[name] = values[0]
+	}
+
+	return opts, nil
+}
+
+// SetPaginationHeaders writes X-Total-Count and an RFC 8288 Link header for a list response.
+func SetPaginationHeaders(w http.ResponseWriter, r *http.Request, opts dbmodel.QueryOptions, total int64) {
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+
+	lastPage := int((total + int64(opts.Limit) - 1) / int64(opts.Limit))
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	links := []string{
+		pageLink(r.URL, 1, "first"),
+		pageLink(r.URL, lastPage, "last"),
+	}
+	if opts.Page > 1 {
+		links = append(links, pageLink(r.URL, min(opts.Page-1, lastPage), "prev"))
+	}
+	if opts.Page < lastPage {
+		links = append(links, pageLink(r.URL, opts.Page+1, "next"))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+func pageLink(current *url.URL, page int, rel string) string {
+	link := *current
+	query := link.Query()
+	query.Set("page", strconv.Itoa(page))
+	link.RawQuery = query.Encode()
+	return fmt.Sprintf("<%s>; rel=\"%s\"", link.RequestURI(), rel)
+}
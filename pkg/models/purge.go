@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+)
+
+type PurgeResponse struct {
+	DeletedBefore time.Time        `json:"deleted_before"`
+	Purged        map[string]int64 `json:"purged"`
+}
+
+func NewPurgeResponse(deletedBefore time.Time, result dbmodel.PurgeResult) *PurgeResponse {
+	return &PurgeResponse{
+		DeletedBefore: deletedBefore,
+		Purged:        result,
+	}
+}
@@ -0,0 +1,11 @@
+package models
+
+// mapList converts the persistence structs returned by a repository into their
+// response DTOs; a nil list is rendered as an empty JSON array.
+func mapList[T, R any](items []T, convert func(T) R) []R {
+	responses := make([]R, 0, len(items))
+	for _, item := range items {
+		responses = append(responses, convert(item))
+	}
+	return responses
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+func (ro *RoleRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
+	if !roleNamePattern.MatchString(ro.Name) {
+		errs.Add("name", "name must be 1 to 50 lowercase letters, digits, - or _, starting with a letter")
+	}
+
+	known := map[string]bool{}
+	for _, permission := range dbmodel.PermissionCatalog {
+		known[permission.Name] = true
+	}
+	seen := map[string]bool{}
+	for i, permission := range ro.Permissions {
+		field := fmt.Sprintf("permissions[%d]", i)
+		if !known[permission] {
+			errs.Add(field, "permission inconnue : "+permission)
+		} else if seen[permission] {
+			errs.Add(field, "permission en double : "+permission)
+		}
+		seen[permission] = true
+	}
+	return errs.Err()
+}
+
+type RoleResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func NewRoleResponse(role *dbmodel.Role) *RoleResponse {
+	return &RoleResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: role.PermissionNames(),
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+}
+
+func NewRoleResponses(roles []*dbmodel.Role) []*RoleResponse {
+	return mapList(roles, NewRoleResponse)
+}
+
+type PermissionResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func NewPermissionResponse(permission *dbmodel.Permission) *PermissionResponse {
+	return &PermissionResponse{
+		Name:        permission.Name,
+		Description: permission.Description,
+	}
+}
+
+func NewPermissionResponses(permissions []*dbmodel.Permission) []*PermissionResponse {
+	return mapList(permissions, NewPermissionResponse)
+}
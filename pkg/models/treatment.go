@@ -1,21 +1,100 @@
 package models
 
 import (
-	"errors"
 	"net/http"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
 )
 
 type TreatmentRequest struct {
-	Name string `json:"name"`
+	Name      string     `json:"name"`
+	Dosage    float64    `json:"dosage"`
+	Unit      string     `json:"unit"`
+	Route     string     `json:"route"`
+	Frequency string     `json:"frequency"`
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+	Notes     string     `json:"notes"`
+	VisitID   uint       `json:"visit_id"`
 }
 
 func (t *TreatmentRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
+	if t.VisitID == 0 {
+		errs.Add("visit_id", "visit_id must not be empty")
+	}
+	t.validate(&errs, "")
+	return errs.Err()
+}
+
+// NewTreatmentRequest returns the payload that would leave the treatment unchanged, to
+// which a PATCH applies its changes.
+func NewTreatmentRequest(treatment *dbmodel.Treatment) *TreatmentRequest {
+	return &TreatmentRequest{
+		Name:      treatment.Name,
+		Dosage:    treatment.Dosage,
+		Unit:      treatment.Unit,
+		Route:     treatment.Route,
+		Frequency: treatment.Frequency,
+		StartDate: treatment.StartDate,
+		EndDate:   treatment.EndDate,
+		Notes:     treatment.Notes,
+		VisitID:   treatment.VisitID,
+	}
+}
+
+// validate checks every field but visit_id, naming the fields with the given prefix.
+func (t *TreatmentRequest) validate(errs *problem.ValidationErrors, prefix string) {
 	if t.Name == "" {
-		return errors.New("le champ name ne doit pas être vide")
+		errs.Add(prefix+"name", "name must not be empty")
+	}
+	if t.Dosage < 0 {
+		errs.Add(prefix+"dosage", "dosage must be greater than or equal to 0")
+	}
+	if t.Dosage > 0 && t.Unit == "" {
+		errs.Add(prefix+"unit", "unit is required when a dosage is given")
+	}
+	if t.StartDate != nil && t.EndDate != nil && t.EndDate.Before(*t.StartDate) {
+		errs.Add(prefix+"end_date", "end_date must be after start_date")
 	}
-	return nil
 }
 
 type TreatmentResponse struct {
-	Name string `json:"name"`
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Dosage    float64    `json:"dosage"`
+	Unit      string     `json:"unit"`
+	Route     string     `json:"route"`
+	Frequency string     `json:"frequency"`
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+	Notes     string     `json:"notes"`
+	VisitID   uint       `json:"visit_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func NewTreatmentResponse(treatment *dbmodel.Treatment) *TreatmentResponse {
+	return &TreatmentResponse{
+		ID:        treatment.ID,
+		Name:      treatment.Name,
+		Dosage:    treatment.Dosage,
+		Unit:      treatment.Unit,
+		Route:     treatment.Route,
+		Frequency: treatment.Frequency,
+		StartDate: treatment.StartDate,
+		EndDate:   treatment.EndDate,
+		Notes:     treatment.Notes,
+		VisitID:   treatment.VisitID,
+		CreatedAt: treatment.CreatedAt,
+		UpdatedAt: treatment.UpdatedAt,
+		DeletedAt: deletedAt(treatment.DeletedAt),
+	}
+}
+
+func NewTreatmentResponses(treatments []*dbmodel.Treatment) []*TreatmentResponse {
+	return mapList(treatments, NewTreatmentResponse)
 }
@@ -1,8 +1,11 @@
 package models
 
 import (
-	"errors"
 	"net/http"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
 )
 
 type UserRequest struct {
@@ -12,21 +15,137 @@ type UserRequest struct {
 }
 
 func (u *UserRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
 	if u.Email == "" {
-		return errors.New("le champ email ne doit pas être vide")
+		errs.Add("email", "email must not be empty")
 	}
 	if u.Password == "" {
-
-		return errors.New("le champ password ne doit pas être vide")
+		errs.Add("password", "password must not be empty")
 	}
+	return errs.Err()
+}
 
-	return nil
+// UserPatchRequest is an account as a PATCH sees it. The password hash is not part of
+// it: a patch sets password only to change it.
+type UserPatchRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password,omitempty"`
+	Role     string `json:"role"`
+}
 
+func NewUserPatchRequest(user *dbmodel.User) *UserPatchRequest {
+	return &UserPatchRequest{Email: user.Email, Role: user.Role}
 }
 
+func (u *UserPatchRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
+	if u.Email == "" {
+		errs.Add("email", "email must not be empty")
+	}
+	if u.Role == "" {
+		errs.Add("role", "role must not be empty")
+	}
+	return errs.Err()
+}
+
+// UserResponse is the public view of an account: the password hash is never exposed.
 type UserResponse struct {
-	Email        string `json:"email"`
-	Password     string `json:"password"`
-	Role         string `json:"role"`
+	ID        uint      `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	FailedLoginAttempts int        `json:"failed_login_attempts"`
+	LockedUntil         *time.Time `json:"locked_until"`
+	LastLoginAt         *time.Time `json:"last_login_at"`
+	LastFailedLoginAt   *time.Time `json:"last_failed_login_at"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+}
+
+func NewUserResponse(user *dbmodel.User) *UserResponse {
+	return &UserResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+
+		FailedLoginAttempts: user.FailedLoginAttempts,
+		LockedUntil:         user.LockedUntil,
+		LastLoginAt:         user.LastLoginAt,
+		LastFailedLoginAt:   user.LastFailedLoginAt,
+		DisabledAt:          user.DisabledAt,
+	}
+}
+
+func NewUserResponses(users []*dbmodel.User) []*UserResponse {
+	return mapList(users, NewUserResponse)
+}
+
+// MeResponse describes the logged-in user, with what their role allows and the login
+// session the access token belongs to.
+type MeResponse struct {
+	*UserResponse
+	Permissions []string `json:"permissions"`
+	SessionID   string   `json:"session_id"`
+}
+
+type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
+
+func (t *RefreshTokenRequest) Bind(r *http.Request) error {
+	if t.RefreshToken == "" {
+		return problem.Field("refresh_token", "refresh_token must not be empty")
+	}
+	return nil
+}
+
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (p *PasswordChangeRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
+	if p.CurrentPassword == "" {
+		errs.Add("current_password", "current_password must not be empty")
+	}
+	if p.NewPassword == "" {
+		errs.Add("new_password", "new_password must not be empty")
+	}
+	return errs.Err()
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+func (f *ForgotPasswordRequest) Bind(r *http.Request) error {
+	if f.Email == "" {
+		return problem.Field("email", "email must not be empty")
+	}
+	return nil
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func (p *ResetPasswordRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
+	if p.Token == "" {
+		errs.Add("token", "token must not be empty")
+	}
+	if p.NewPassword == "" {
+		errs.Add("new_password", "new_password must not be empty")
+	}
+	return errs.Err()
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+)
+
+type VaccinationRequest struct {
+	CatID          uint       `json:"cat_id"`
+	VaccineName    string     `json:"vaccine_name"`
+	LotNumber      string     `json:"lot_number"`
+	Manufacturer   string     `json:"manufacturer"`
+	AdministeredAt time.Time  `json:"administered_at"`
+	VeterinarianID *uint      `json:"veterinarian_id"`
+	NextDueAt      *time.Time `json:"next_due_at"`
+	VisitID        *uint      `json:"visit_id"`
+	Notes          string     `json:"notes"`
+}
+
+func (v *VaccinationRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
+
+	if v.CatID == 0 {
+		errs.Add("cat_id", "cat_id must not be empty")
+	}
+
+	v.VaccineName = strings.TrimSpace(v.VaccineName)
+	if v.VaccineName == "" {
+		errs.Add("vaccine_name", "vaccine_name must not be empty")
+	}
+
+	v.LotNumber = strings.TrimSpace(v.LotNumber)
+	if len(v.LotNumber) > 50 {
+		errs.Add("lot_number", "lot_number must not exceed 50 characters")
+	}
+
+	if v.AdministeredAt.IsZero() {
+		errs.Add("administered_at", "administered_at must not be empty")
+	} else if v.AdministeredAt.After(time.Now()) {
+		errs.Add("administered_at", "administered_at cannot be in the future")
+	} else if v.NextDueAt != nil && !v.NextDueAt.After(v.AdministeredAt) {
+		errs.Add("next_due_at", "next_due_at must be after administered_at")
+	}
+
+	if v.VeterinarianID != nil && *v.VeterinarianID == 0 {
+		v.VeterinarianID = nil
+	}
+	if v.VisitID != nil && *v.VisitID == 0 {
+		v.VisitID = nil
+	}
+
+	v.AdministeredAt = v.AdministeredAt.UTC()
+	if v.NextDueAt != nil {
+		nextDueAt := v.NextDueAt.UTC()
+		v.NextDueAt = &nextDueAt
+	}
+	return errs.Err()
+}
+
+type VaccinationResponse struct {
+	ID             uint                 `json:"id"`
+	CatID          uint                 `json:"cat_id"`
+	VaccineName    string               `json:"vaccine_name"`
+	LotNumber      string               `json:"lot_number"`
+	Manufacturer   string               `json:"manufacturer"`
+	AdministeredAt time.Time            `json:"administered_at"`
+	VeterinarianID *uint                `json:"veterinarian_id"`
+	Veterinarian   *VeterinarianSummary `json:"veterinarian,omitempty"`
+	NextDueAt      *time.Time           `json:"next_due_at"`
+	VisitID        *uint                `json:"visit_id"`
+	Notes          string               `json:"notes"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	DeletedAt      *time.Time           `json:"deleted_at,omitempty"`
+}
+
+func NewVaccinationResponse(vaccination *dbmodel.Vaccination) *VaccinationResponse {
+	return &VaccinationResponse{
+		ID:             vaccination.ID,
+		CatID:          vaccination.CatID,
+		VaccineName:    vaccination.VaccineName,
+		LotNumber:      vaccination.LotNumber,
+		Manufacturer:   vaccination.Manufacturer,
+		AdministeredAt: vaccination.AdministeredAt,
+		VeterinarianID: vaccination.VeterinarianID,
+		Veterinarian:   NewVeterinarianSummary(vaccination.Veterinarian),
+		NextDueAt:      vaccination.NextDueAt,
+		VisitID:        vaccination.VisitID,
+		Notes:          vaccination.Notes,
+		CreatedAt:      vaccination.CreatedAt,
+		UpdatedAt:      vaccination.UpdatedAt,
+		DeletedAt:      deletedAt(vaccination.DeletedAt),
+	}
+}
+
+func NewVaccinationResponses(vaccinations []*dbmodel.Vaccination) []*VaccinationResponse {
+	return mapList(vaccinations, NewVaccinationResponse)
+}
+
+type DueVaccinationResponse struct {
+	VaccinationID      uint      `json:"vaccination_id"`
+	CatID              uint      `json:"cat_id"`
+	CatName            string    `json:"cat_name"`
+	OwnerID            *uint     `json:"owner_id"`
+	VaccineName        string    `json:"vaccine_name"`
+	LastAdministeredAt time.Time `json:"last_administered_at"`
+	NextDueAt          time.Time `json:"next_due_at"`
+	Overdue            bool      `json:"overdue"`
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+)
+
+type VeterinarianRequest struct {
+	Name          string   `json:"name"`
+	LicenseNumber string   `json:"license_number"`
+	Specialties   []string `json:"specialties"`
+	Active        *bool    `json:"active"`
+	UserID        *uint    `json:"user_id"`
+}
+
+func (v *VeterinarianRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
+
+	v.Name = strings.TrimSpace(v.Name)
+	if v.Name == "" {
+		errs.Add("name", "name must not be empty")
+	}
+
+	v.LicenseNumber = strings.TrimSpace(v.LicenseNumber)
+	if len(v.LicenseNumber) > 50 {
+		errs.Add("license_number", "license_number must not exceed 50 characters")
+	}
+
+	specialties := make([]string, 0, len(v.Specialties))
+	for _, specialty := range v.Specialties {
+		if specialty = strings.TrimSpace(specialty); specialty != "" {
+			specialties = append(specialties, specialty)
+		}
+	}
+	v.Specialties = specialties
+
+	if v.UserID != nil && *v.UserID == 0 {
+		errs.Add("user_id", "user_id must be a valid ID")
+	}
+
+	return errs.Err()
+}
+
+type VeterinarianResponse struct {
+	ID            uint       `json:"id"`
+	Name          string     `json:"name"`
+	LicenseNumber *string    `json:"license_number"`
+	Specialties   []string   `json:"specialties"`
+	Active        bool       `json:"active"`
+	UserID        *uint      `json:"user_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+}
+
+func NewVeterinarianResponse(veterinarian *dbmodel.Veterinarian) *VeterinarianResponse {
+	specialties := veterinarian.Specialties
+	if specialties == nil {
+		specialties = []string{}
+	}
+	return &VeterinarianResponse{
+		ID:            veterinarian.ID,
+		Name:          veterinarian.Name,
+		LicenseNumber: veterinarian.LicenseNumber,
+		Specialties:   specialties,
+		Active:        veterinarian.Active,
+		UserID:        veterinarian.UserID,
+		CreatedAt:     veterinarian.CreatedAt,
+		UpdatedAt:     veterinarian.UpdatedAt,
+		DeletedAt:     deletedAt(veterinarian.DeletedAt),
+	}
+}
+
+func NewVeterinarianResponses(veterinarians []*dbmodel.Veterinarian) []*VeterinarianResponse {
+	return mapList(veterinarians, NewVeterinarianResponse)
+}
+
+// VeterinarianSummary names the veterinarian on the records that reference one.
+type VeterinarianSummary struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// NewVeterinarianSummary returns nil when the veterinarian was not loaded.
+func NewVeterinarianSummary(veterinarian *dbmodel.Veterinarian) *VeterinarianSummary {
+	if veterinarian == nil {
+		return nil
+	}
+	return &VeterinarianSummary{ID: veterinarian.ID, Name: veterinarian.Name}
+}
+
+type WorkingHoursResponse struct {
+	ID             uint         `json:"id"`
+	VeterinarianID uint         `json:"veterinarian_id"`
+	Weekday        time.Weekday `json:"weekday" swaggertype:"integer"`
+	Start          string       `json:"start"`
+	End            string       `json:"end"`
+}
+
+func NewWorkingHoursResponses(hours []dbmodel.WorkingHours) []*WorkingHoursResponse {
+	responses := make([]*WorkingHoursResponse, 0, len(hours))
+	for _, slot := range hours {
+		responses = append(responses, &WorkingHoursResponse{
+			ID:             slot.ID,
+			VeterinarianID: slot.VeterinarianID,
+			Weekday:        slot.Weekday,
+			Start:          slot.Start,
+			End:            slot.End,
+		})
+	}
+	return responses
+}
@@ -2,34 +2,134 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
 )
 
 type VisitRequest struct {
-	Date        time.Time `json:"date"`
-	Motif       string    `json:"motif"`
-	Veterinaire string    `json:"veterinaire"`
+	Date           time.Time `json:"date"`
+	Motif          string    `json:"motif"`
+	VeterinarianID uint      `json:"veterinarian_id"`
+	CatID          uint      `json:"cat_id"`
 }
 
 func (v *VisitRequest) Bind(r *http.Request) error {
-	if v.Motif == "" {
-		return errors.New("le champ motif ne doit pas être vide")
+	var errs problem.ValidationErrors
+	validateVisit(&errs, v.Date, v.Motif, v.CatID)
+	if v.VeterinarianID == 0 {
+		errs.Add("veterinarian_id", "veterinarian_id must not be empty")
 	}
+	return errs.Err()
+}
+
+// VisitUpdateRequest is the payload of PUT and PATCH on a visit. Unlike VisitRequest, it
+// accepts a null veterinarian_id, so that the visits whose free-text veterinarian could
+// not be matched to a veterinarian record can still be updated.
+type VisitUpdateRequest struct {
+	Date           time.Time `json:"date"`
+	Motif          string    `json:"motif"`
+	VeterinarianID *uint     `json:"veterinarian_id"`
+	CatID          uint      `json:"cat_id"`
+}
 
-	if v.Veterinaire == "" {
-		return errors.New("le champ veterinaire ne doit pas être vide")
+func (v *VisitUpdateRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
+	validateVisit(&errs, v.Date, v.Motif, v.CatID)
+	if v.VeterinarianID != nil && *v.VeterinarianID == 0 {
+		errs.Add("veterinarian_id", "veterinarian_id must be a veterinarian ID or null")
 	}
+	return errs.Err()
+}
 
-	if v.Date.IsZero() {
-		return errors.New("le champ date ne doit pas être vide")
+// NewVisitUpdateRequest returns the payload that would leave the visit unchanged, to
+// which a PATCH applies its changes.
+func NewVisitUpdateRequest(visit *dbmodel.Visit) *VisitUpdateRequest {
+	return &VisitUpdateRequest{
+		Date:           visit.Date,
+		Motif:          visit.Motif,
+		VeterinarianID: visit.VeterinarianID,
+		CatID:          visit.CatID,
 	}
+}
 
-	return nil
+func validateVisit(errs *problem.ValidationErrors, date time.Time, motif string, catID uint) {
+	if motif == "" {
+		errs.Add("motif", "motif must not be empty")
+	}
+	if date.IsZero() {
+		errs.Add("date", "date must not be empty")
+	}
+	if catID == 0 {
+		errs.Add("cat_id", "cat_id must not be empty")
+	}
+}
+
+// CompleteVisitRequest records a visit with the treatments prescribed during it. The
+// treatments are attached to the new visit, so they carry no visit_id.
+type CompleteVisitRequest struct {
+	VisitRequest
+	Treatments []TreatmentRequest `json:"treatments"`
+}
+
+func (v *CompleteVisitRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
+	if err := v.VisitRequest.Bind(r); err != nil && !errors.As(err, &errs) {
+		return err
+	}
+	for i := range v.Treatments {
+		prefix := fmt.Sprintf("treatments[%d].", i)
+		if v.Treatments[i].VisitID != 0 {
+			errs.Add(prefix+"visit_id", "visit_id must not be set")
+		}
+		v.Treatments[i].validate(&errs, prefix)
+	}
+	return errs.Err()
 }
 
 type VisitResponse struct {
-	Date        time.Time `json:"date"`
-	Motif       string    `json:"motif"`
-	Veterinaire string    `json:"veterinaire"`
+	ID             uint                 `json:"id"`
+	Date           time.Time            `json:"date"`
+	Motif          string               `json:"motif"`
+	VeterinarianID *uint                `json:"veterinarian_id"`
+	Veterinarian   *VeterinarianSummary `json:"veterinarian,omitempty"`
+	CatID          uint                 `json:"cat_id"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	DeletedAt      *time.Time           `json:"deleted_at,omitempty"`
+}
+
+func NewVisitResponse(visit *dbmodel.Visit) *VisitResponse {
+	return &VisitResponse{
+		ID:             visit.ID,
+		Date:           visit.Date,
+		Motif:          visit.Motif,
+		VeterinarianID: visit.VeterinarianID,
+		Veterinarian:   NewVeterinarianSummary(visit.Veterinarian),
+		CatID:          visit.CatID,
+		CreatedAt:      visit.CreatedAt,
+		UpdatedAt:      visit.UpdatedAt,
+		DeletedAt:      deletedAt(visit.DeletedAt),
+	}
+}
+
+func NewVisitResponses(visits []*dbmodel.Visit) []*VisitResponse {
+	return mapList(visits, NewVisitResponse)
+}
+
+// VisitHistoryResponse is a visit with its treatments, as listed in a cat history.
+type VisitHistoryResponse struct {
+	*VisitResponse
+	Treatments []*TreatmentResponse `json:"treatments"`
+}
+
+func NewVisitHistoryResponse(visit *dbmodel.Visit) *VisitHistoryResponse {
+	treatments := make([]*TreatmentResponse, 0, len(visit.Treatments))
+	for i := range visit.Treatments {
+		treatments = append(treatments, NewTreatmentResponse(&visit.Treatments[i]))
+	}
+	return &VisitHistoryResponse{VisitResponse: NewVisitResponse(visit), Treatments: treatments}
 }
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+)
+
+// maxCatWeightKg rejects obvious typos such as a weight in grams sent with the kg unit.
+const maxCatWeightKg = 50
+
+type WeightMeasurementRequest struct {
+	Value      float64    `json:"value"`
+	Unit       string     `json:"unit"`
+	MeasuredAt *time.Time `json:"measured_at"`
+	VisitID    *uint      `json:"visit_id"`
+	Notes      string     `json:"notes"`
+}
+
+func (m *WeightMeasurementRequest) Bind(r *http.Request) error {
+	var errs problem.ValidationErrors
+
+	m.Unit = strings.ToLower(strings.TrimSpace(m.Unit))
+	if m.Unit == "" {
+		m.Unit = "kg"
+	}
+	factor, ok := dbmodel.WeightUnits[m.Unit]
+	if !ok {
+		errs.Add("unit", "unit must be kg, g or lb")
+	}
+	if m.Value <= 0 {
+		errs.Add("value", "value must be greater than 0")
+	} else if ok && m.Value*factor > maxCatWeightKg {
+		errs.Add("value", "value exceeds the maximum plausible weight for a cat (50 kg)")
+	}
+	if m.MeasuredAt != nil {
+		if m.MeasuredAt.After(time.Now()) {
+			errs.Add("measured_at", "measured_at cannot be in the future")
+		}
+		measuredAt := m.MeasuredAt.UTC()
+		m.MeasuredAt = &measuredAt
+	}
+	if m.VisitID != nil && *m.VisitID == 0 {
+		m.VisitID = nil
+	}
+	return errs.Err()
+}
+
+// WeightTrend compares the current weight with the last measurement taken before the period.
+// The change fields are null when no measurement is that old.
+type WeightTrend struct {
+	PeriodDays          int        `json:"period_days"`
+	ReferenceMeasuredAt *time.Time `json:"reference_measured_at"`
+	ReferenceKg         *float64   `json:"reference_kg"`
+	ChangeKg            *float64   `json:"change_kg"`
+	ChangePercent       *float64   `json:"change_percent"`
+}
+
+type WeightMeasurementResponse struct {
+	ID         uint       `json:"id"`
+	CatID      uint       `json:"cat_id"`
+	Value      float64    `json:"value"`
+	Unit       string     `json:"unit"`
+	MeasuredAt time.Time  `json:"measured_at"`
+	VisitID    *uint      `json:"visit_id"`
+	Notes      string     `json:"notes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+}
+
+// NewWeightMeasurementResponse returns nil for a cat that was never weighed.
+func NewWeightMeasurementResponse(measurement *dbmodel.WeightMeasurement) *WeightMeasurementResponse {
+	if measurement == nil {
+		return nil
+	}
+	return &WeightMeasurementResponse{
+		ID:         measurement.ID,
+		CatID:      measurement.CatID,
+		Value:      measurement.Value,
+		Unit:       measurement.Unit,
+		MeasuredAt: measurement.MeasuredAt,
+		VisitID:    measurement.VisitID,
+		Notes:      measurement.Notes,
+		CreatedAt:  measurement.CreatedAt,
+		UpdatedAt:  measurement.UpdatedAt,
+		DeletedAt:  deletedAt(measurement.DeletedAt),
+	}
+}
+
+func NewWeightMeasurementResponses(measurements []*dbmodel.WeightMeasurement) []*WeightMeasurementResponse {
+	return mapList(measurements, NewWeightMeasurementResponse)
+}
+
+type CatDetailResponse struct {
+	*CatResponse
+	CurrentWeight   *WeightMeasurementResponse `json:"current_weight"`
+	CurrentWeightKg *float64                   `json:"current_weight_kg"`
+	WeightTrend     []WeightTrend              `json:"weight_trend"`
+}
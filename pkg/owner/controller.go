@@ -0,0 +1,288 @@
+package owner
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"gorm.io/gorm"
+)
+
+type OwnerConfig struct {
+	*config.Config
+}
+
+func New(configuration *config.Config) *OwnerConfig {
+	return &OwnerConfig{configuration}
+}
+
+// CreateOwnerHandler godoc
+// @Summary Create an owner
+// @Tags owners
+// @Accept json
+// @Produce json
+// @Param owner body models.OwnerRequest true "Owner payload"
+// @Success 201 {object} models.OwnerResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /owners [post]
+func (config *OwnerConfig) CreateOwnerHandler(w http.ResponseWriter, r *http.Request) {
+	req := &models.OwnerRequest{}
+
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	owner := &dbmodel.Owner{}
+	applyOwnerRequest(owner, req)
+
+	savedOwner, err := config.OwnerRepository.Create(r.Context(), owner)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "unable to save owner")
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, models.NewOwnerResponse(savedOwner))
+}
+
+// GetAllOwnersHandler godoc
+// @Summary List owners
+// @Tags owners
+// @Produce json
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. name (id, name, email, created_at)"
+// @Param name query string false "Name contains"
+// @Param phone query string false "Phone number (separators are ignored)"
+// @Param email query string false "Email"
+// @Success 200 {array} models.OwnerResponse
+// @Header 200 {integer} X-Total-Count "Total number of matching owners"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /owners [get]
+func (config *OwnerConfig) GetAllOwnersHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := models.ParseQueryOptions(r)
+	if err != nil {
+		problem.InvalidQuery(w, r, err)
+		return
+	}
+
+	owners, total, err := config.OwnerRepository.FindAll(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch owners")
+		return
+	}
+	models.SetPaginationHeaders(w, r, opts, total)
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, models.NewOwnerResponses(owners))
+}
+
+// GetOwnerByIDHandler godoc
+// @Summary Get an owner by ID
+// @Tags owners
+// @Produce json
+// @Param id path int true "Owner ID"
+// @Success 200 {object} models.OwnerResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /owners/{id} [get]
+func (config *OwnerConfig) GetOwnerByIDHandler(w http.ResponseWriter, r *http.Request) {
+	idParam := chi.URLParam(r, "id")
+	id64, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid owner ID")
+		return
+	}
+
+	owner, err := config.OwnerRepository.FindById(r.Context(), uint(id64))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "owner not found")
+		return
+	}
+
+	render.JSON(w, r, models.NewOwnerResponse(owner))
+}
+
+// UpdateOwnerHandler godoc
+// @Summary Update an owner
+// @Tags owners
+// @Accept json
+// @Produce json
+// @Param id path int true "Owner ID"
+// @Param owner body models.OwnerRequest true "Owner payload"
+// @Success 200 {object} models.OwnerResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /owners/{id} [put]
+func (config *OwnerConfig) UpdateOwnerHandler(w http.ResponseWriter, r *http.Request) {
+	idParam := chi.URLParam(r, "id")
+	id64, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid owner ID")
+		return
+	}
+
+	req := &models.OwnerRequest{}
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	existing, err := config.OwnerRepository.FindById(r.Context(), uint(id64))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "owner not found")
+		return
+	}
+
+	applyOwnerRequest(existing, req)
+
+	updatedOwner, err := config.OwnerRepository.Update(r.Context(), existing)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to update owner")
+		return
+	}
+
+	render.JSON(w, r, models.NewOwnerResponse(updatedOwner))
+}
+
+// DeleteOwnerHandler godoc
+// @Summary Delete an owner (their cats are kept without owner)
+// @Tags owners
+// @Param id path int true "Owner ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /owners/{id} [delete]
+func (config *OwnerConfig) DeleteOwnerHandler(w http.ResponseWriter, r *http.Request) {
+	idParam := chi.URLParam(r, "id")
+	id64, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid owner ID")
+		return
+	}
+
+	owner, err := config.OwnerRepository.FindById(r.Context(), uint(id64))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "owner not found")
+		return
+	}
+
+	if err := config.OwnerRepository.Delete(r.Context(), uint(id64), owner); err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to delete owner")
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+// RestoreOwnerHandler godoc
+// @Summary Restore a deleted owner
+// @Description The cats the owner had are not given back to them.
+// @Tags owners
+// @Produce json
+// @Param id path int true "Owner ID"
+// @Success 200 {object} models.OwnerResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /owners/{id}/restore [post]
+func (config *OwnerConfig) RestoreOwnerHandler(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid owner ID")
+		return
+	}
+
+	restored, err := config.OwnerRepository.Restore(r.Context(), uint(id64))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			problem.Write(w, r, http.StatusNotFound, "owner not found")
+		case errors.Is(err, dbmodel.ErrNotDeleted), errors.Is(err, dbmodel.ErrParentDeleted):
+			problem.Write(w, r, http.StatusConflict, err.Error())
+		default:
+			problem.Write(w, r, http.StatusInternalServerError, "failed to restore owner")
+		}
+		return
+	}
+
+	render.JSON(w, r, models.NewOwnerResponse(restored))
+}
+
+// GetOwnerCatsHandler godoc
+// @Summary List the cats of an owner
+// @Tags owners
+// @Produce json
+// @Param id path int true "Owner ID"
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. name"
+// @Success 200 {array} models.CatResponse
+// @Header 200 {integer} X-Total-Count "Total number of matching cats"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /owners/{id}/cats [get]
+func (config *OwnerConfig) GetOwnerCatsHandler(w http.ResponseWriter, r *http.Request) {
+	idParam := chi.URLParam(r, "id")
+	id64, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid owner ID")
+		return
+	}
+
+	if _, err := config.OwnerRepository.FindById(r.Context(), uint(id64)); err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "owner not found")
+		return
+	}
+
+	opts, err := models.ParseQueryOptions(r)
+	if err != nil {
+		problem.InvalidQuery(w, r, err)
+		return
+	}
+	opts.Filters["owner_id"] = idParam
+
+	cats, total, err := config.CatRepository.FindAll(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch cats for owner")
+		return
+	}
+	models.SetPaginationHeaders(w, r, opts, total)
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, models.NewCatResponses(cats))
+}
+
+func applyOwnerRequest(owner *dbmodel.Owner, req *models.OwnerRequest) {
+	owner.Name = req.Name
+	owner.Phone = req.Phone
+	owner.Email = req.Email
+	owner.Address = req.Address
+	owner.PreferredContact = req.PreferredContact
+	owner.Notes = req.Notes
+}
@@ -0,0 +1,30 @@
+package owner
+
+import (
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/authentification"
+	"github.com/go-chi/chi/v5"
+)
+
+func Routes(configuration *config.Config) *chi.Mux {
+	ownerConfig := New(configuration)
+	router := chi.NewRouter()
+
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionOwnersRead))
+		r.Get("/", ownerConfig.GetAllOwnersHandler)
+		r.Get("/{id}", ownerConfig.GetOwnerByIDHandler)
+		r.Get("/{id}/cats", ownerConfig.GetOwnerCatsHandler)
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionOwnersWrite))
+		r.Post("/", ownerConfig.CreateOwnerHandler)
+		r.Put("/{id}", ownerConfig.UpdateOwnerHandler)
+		r.Delete("/{id}", ownerConfig.DeleteOwnerHandler)
+		r.With(authentification.RequirePermission(dbmodel.PermissionDeletedManage)).Post("/{id}/restore", ownerConfig.RestoreOwnerHandler)
+	})
+
+	return router
+}
@@ -0,0 +1,125 @@
+// Package password enforces the password policy applied to every password a user chooses.
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+)
+
+// maxBytes is the longest password bcrypt can hash.
+const maxBytes = 72
+
+//go:embed wordlist.txt
+var bundledWordlist string
+
+type Policy struct {
+	MinLength  int
+	MinClasses int
+
+	breached map[string]bool
+}
+
+// NewPolicy builds a policy checking passwords against the wordlist at wordlistPath, or
+// against the bundled wordlist when the path is empty. checkBreached=false disables the check.
+func NewPolicy(minLength, minClasses int, checkBreached bool, wordlistPath string) (*Policy, error) {
+	policy := &Policy{MinLength: minLength, MinClasses: minClasses}
+	if !checkBreached {
+		return policy, nil
+	}
+
+	var source io.Reader = strings.NewReader(bundledWordlist)
+	if wordlistPath != "" {
+		file, err := os.Open(wordlistPath)
+		if err != nil {
+			return nil, fmt.Errorf("opening password wordlist: %w", err)
+		}
+		defer file.Close()
+		source = file
+	}
+
+	breached, err := readWordlist(source)
+	if err != nil {
+		return nil, fmt.Errorf("reading password wordlist: %w", err)
+	}
+	policy.breached = breached
+	return policy, nil
+}
+
+// Check returns the rules the password breaks, as messages for the user; email is the
+// account the password is for and must not appear in it.
+func (p *Policy) Check(password, email string) []string {
+	var violations []string
+
+	if len([]rune(password)) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("the password must be at least %d characters long", p.MinLength))
+	}
+	if len(password) > maxBytes {
+		violations = append(violations, fmt.Sprintf("the password must not exceed %d bytes", maxBytes))
+	}
+	if characterClasses(password) < p.MinClasses {
+		violations = append(violations, fmt.Sprintf("the password must combine at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses))
+	}
+
+	lowered := strings.ToLower(password)
+	if p.breached[lowered] {
+		violations = append(violations, "this password appears in a list of breached passwords")
+	}
+	if local, _, _ := strings.Cut(strings.ToLower(email), "@"); len(local) >= 3 && strings.Contains(lowered, local) {
+		violations = append(violations, "the password must not contain the email address")
+	}
+
+	return violations
+}
+
+// Validate reports every broken rule as an error on the given request field.
+func (p *Policy) Validate(field, password, email string) error {
+	var errs problem.ValidationErrors
+	for _, violation := range p.Check(password, email) {
+		errs.Add(field, violation)
+	}
+	return errs.Err()
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsDigit(c):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	return classes
+}
+
+func readWordlist(source io.Reader) (map[string]bool, error) {
+	words := map[string]bool{}
+	scanner := bufio.NewScanner(source)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		words[strings.ToLower(word)] = true
+	}
+	return words, scanner.Err()
+}
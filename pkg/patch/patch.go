@@ -0,0 +1,346 @@
+// Package patch applies the body of a PATCH request to the current state of a record.
+// The body is either a JSON Merge Patch (RFC 7396), sent as application/merge-patch+json
+// or application/json, or a JSON Patch (RFC 6902), sent as application/json-patch+json.
+// Only the patched record is validated, so a client sends just the fields it changes.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"github.com/go-chi/render"
+)
+
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+	// ErrUnsupportedMediaType is returned for a body that is neither kind of patch.
+	ErrUnsupportedMediaType = errors.New("send a merge patch (" + MergePatchType + ") or a JSON Patch (" + JSONPatchType + ")")
+	// ErrConflict is returned when a JSON Patch operation cannot be applied to the record:
+	// a path does not exist or a test operation fails.
+	ErrConflict = errors.New("the patch cannot be applied to the record")
+)
+
+// Bind applies the patch in the body of r to v, which holds the current state of the
+// record as its update payload, and then validates the result with v.Bind.
+func Bind(r *http.Request, v render.Binder) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != MergePatchType && mediaType != JSONPatchType && mediaType != "application/json" {
+		return ErrUnsupportedMediaType
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	current, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var document interface{}
+	if err := json.Unmarshal(current, &document); err != nil {
+		return err
+	}
+
+	if mediaType == JSONPatchType {
+		var operations []operation
+		if err := json.Unmarshal(body, &operations); err != nil {
+			return err
+		}
+		if document, err = applyJSONPatch(document, operations); err != nil {
+			return err
+		}
+	} else {
+		var mergePatch interface{}
+		if err := json.Unmarshal(body, &mergePatch); err != nil {
+			return err
+		}
+		document = merge(document, mergePatch)
+	}
+
+	patched, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	// Fields the patch removed must end up empty, not keep their current value.
+	target := reflect.ValueOf(v).Elem()
+	target.Set(reflect.Zero(target.Type()))
+	if err := json.Unmarshal(patched, v); err != nil {
+		return err
+	}
+	return v.Bind(r)
+}
+
+// WriteError answers a patch that could not be bound: 415 for an unknown media type,
+// 409 for a JSON Patch that does not apply, and 400 for an invalid body or record.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrUnsupportedMediaType):
+		problem.Write(w, r, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, ErrConflict):
+		problem.Write(w, r, http.StatusConflict, err.Error())
+	default:
+		problem.BindError(w, r, err)
+	}
+}
+
+// merge applies a merge patch: objects are merged member by member, a null member
+// removes the member, and any other value replaces the target.
+func merge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = merge(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+// operation is one step of a JSON Patch. Value is nil when the member is missing and
+// holds "null" when it is null.
+type operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch applies the operations in order; if one fails, the whole patch fails.
+func applyJSONPatch(document interface{}, operations []operation) (interface{}, error) {
+	for i, op := range operations {
+		var err error
+		if document, err = op.apply(document); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return document, nil
+}
+
+func (op operation) apply(document interface{}) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, problem.Field("value", "value is required for the "+op.Op+" operation")
+		}
+		var value interface{}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return add(document, path, value)
+		case "replace":
+			if len(path) == 0 {
+				return value, nil
+			}
+			if document, _, err = remove(document, path); err != nil {
+				return nil, err
+			}
+			return add(document, path, value)
+		default:
+			current, err := get(document, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, fmt.Errorf("%w: the value at %s differs", ErrConflict, op.Path)
+			}
+			return document, nil
+		}
+	case "remove":
+		document, _, err = remove(document, path)
+		return document, err
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+				return nil, problem.Field("from", "a value cannot be moved into one of its children")
+			}
+			document, value, err = remove(document, from)
+		} else {
+			value, err = get(document, from)
+			if err == nil {
+				value, err = deepCopy(value)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return add(document, path, value)
+	default:
+		return nil, problem.Field("op", "unknown operation: "+op.Op)
+	}
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, problem.Field("path", "path "+pointer+" must start with /")
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			child, ok := container[token]
+			if !ok {
+				return nil, missing(token)
+			}
+			node = child
+		case []interface{}:
+			i, err := index(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = container[i]
+		default:
+			return nil, missing(token)
+		}
+	}
+	return node, nil
+}
+
+// add returns node with value added at path. Adding to an object member replaces it;
+// adding to an array inserts the value, "-" meaning after the last element.
+func add(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token := path[0]
+	switch container := node.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			container[token] = value
+			return container, nil
+		}
+		child, ok := container[token]
+		if !ok {
+			return nil, missing(token)
+		}
+		child, err := add(child, path[1:], value)
+		container[token] = child
+		return container, err
+	case []interface{}:
+		if len(path) == 1 {
+			i := len(container)
+			if token != "-" {
+				var err error
+				if i, err = index(token, len(container)); err != nil {
+					return nil, err
+				}
+			}
+			container = append(container, nil)
+			copy(container[i+1:], container[i:])
+			container[i] = value
+			return container, nil
+		}
+		i, err := index(token, len(container)-1)
+		if err != nil {
+			return nil, err
+		}
+		container[i], err = add(container[i], path[1:], value)
+		return container, err
+	default:
+		return nil, missing(token)
+	}
+}
+
+// remove returns node without the value at path, and that value.
+func remove(node interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, problem.Field("path", "the whole document cannot be removed")
+	}
+	token := path[0]
+	switch container := node.(type) {
+	case map[string]interface{}:
+		child, ok := container[token]
+		if !ok {
+			return nil, nil, missing(token)
+		}
+		if len(path) == 1 {
+			delete(container, token)
+			return container, child, nil
+		}
+		child, removed, err := remove(child, path[1:])
+		container[token] = child
+		return container, removed, err
+	case []interface{}:
+		i, err := index(token, len(container)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(path) == 1 {
+			removed := container[i]
+			return append(container[:i], container[i+1:]...), removed, nil
+		}
+		child, removed, err := remove(container[i], path[1:])
+		container[i] = child
+		return container, removed, err
+	default:
+		return nil, nil, missing(token)
+	}
+}
+
+// index parses an array index, which must be at most maximum.
+func index(token string, maximum int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, problem.Field("path", "invalid array index: "+token)
+	}
+	if i > maximum {
+		return 0, missing(token)
+	}
+	return i, nil
+}
+
+func missing(token string) error {
+	return fmt.Errorf("%w: %q does not exist", ErrConflict, token)
+}
+
+func deepCopy(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var copied interface{}
+	err = json.Unmarshal(encoded, &copied)
+	return copied, err
+}
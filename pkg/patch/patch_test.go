@@ -0,0 +1,162 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	const document = `{"name":"Felix","tags":["calm","indoor"],"owner":{"name":"Alice"},"a/b":1,"m~n":2}`
+	tests := []struct {
+		name     string
+		patch    string
+		want     string
+		conflict bool
+		invalid  bool
+	}{
+		{"add a member", `[{"op":"add","path":"/age","value":4}]`,
+			`{"name":"Felix","age":4,"tags":["calm","indoor"],"owner":{"name":"Alice"},"a/b":1,"m~n":2}`, false, false},
+		{"add replaces an existing member", `[{"op":"add","path":"/name","value":"Tom"}]`,
+			`{"name":"Tom","tags":["calm","indoor"],"owner":{"name":"Alice"},"a/b":1,"m~n":2}`, false, false},
+		{"add inserts at an array index", `[{"op":"add","path":"/tags/1","value":"old"}]`,
+			`{"name":"Felix","tags":["calm","old","indoor"],"owner":{"name":"Alice"},"a/b":1,"m~n":2}`, false, false},
+		{"add appends with -", `[{"op":"add","path":"/tags/-","value":"old"}]`,
+			`{"name":"Felix","tags":["calm","indoor","old"],"owner":{"name":"Alice"},"a/b":1,"m~n":2}`, false, false},
+		{"add to a missing parent", `[{"op":"add","path":"/breed/name","value":"Siamese"}]`, "", true, false},
+		{"add past the end of an array", `[{"op":"add","path":"/tags/3","value":"old"}]`, "", true, false},
+		{"add without a value", `[{"op":"add","path":"/age"}]`, "", false, true},
+		{"remove a member", `[{"op":"remove","path":"/owner/name"}]`,
+			`{"name":"Felix","tags":["calm","indoor"],"owner":{},"a/b":1,"m~n":2}`, false, false},
+		{"remove an array element", `[{"op":"remove","path":"/tags/0"}]`,
+			`{"name":"Felix","tags":["indoor"],"owner":{"name":"Alice"},"a/b":1,"m~n":2}`, false, false},
+		{"remove a missing member", `[{"op":"remove","path":"/breed"}]`, "", true, false},
+		{"remove the root", `[{"op":"remove","path":""}]`, "", false, true},
+		{"replace a member", `[{"op":"replace","path":"/owner/name","value":"Bob"}]`,
+			`{"name":"Felix","tags":["calm","indoor"],"owner":{"name":"Bob"},"a/b":1,"m~n":2}`, false, false},
+		{"replace an array element", `[{"op":"replace","path":"/tags/1","value":"outdoor"}]`,
+			`{"name":"Felix","tags":["calm","outdoor"],"owner":{"name":"Alice"},"a/b":1,"m~n":2}`, false, false},
+		{"replace a missing member", `[{"op":"replace","path":"/breed","value":"Siamese"}]`, "", true, false},
+		{"replace with -", `[{"op":"replace","path":"/tags/-","value":"old"}]`, "", false, true},
+		{"replace the root", `[{"op":"replace","path":"","value":{"name":"Tom"}}]`, `{"name":"Tom"}`, false, false},
+		{"move a member", `[{"op":"move","from":"/owner/name","path":"/owner_name"}]`,
+			`{"name":"Felix","tags":["calm","indoor"],"owner":{},"owner_name":"Alice","a/b":1,"m~n":2}`, false, false},
+		{"move an array element", `[{"op":"move","from":"/tags/0","path":"/tags/-"}]`,
+			`{"name":"Felix","tags":["indoor","calm"],"owner":{"name":"Alice"},"a/b":1,"m~n":2}`, false, false},
+		{"move into a child", `[{"op":"move","from":"/owner","path":"/owner/previous"}]`, "", false, true},
+		{"copy a member", `[{"op":"copy","from":"/owner","path":"/previous_owner"},{"op":"replace","path":"/owner/name","value":"Bob"}]`,
+			`{"name":"Felix","tags":["calm","indoor"],"owner":{"name":"Bob"},"previous_owner":{"name":"Alice"},"a/b":1,"m~n":2}`, false, false},
+		{"copy a missing member", `[{"op":"copy","from":"/breed","path":"/race"}]`, "", true, false},
+		{"test a matching value", `[{"op":"test","path":"/tags","value":["calm","indoor"]}]`, document, false, false},
+		{"test a different value", `[{"op":"test","path":"/name","value":"Tom"}]`, "", true, false},
+		{"test null", `[{"op":"test","path":"/name","value":null}]`, "", true, false},
+		{"escaped tokens", `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`,
+			`{"name":"Felix","tags":["calm","indoor"],"owner":{"name":"Alice"},"a/b":3}`, false, false},
+		{"invalid index", `[{"op":"remove","path":"/tags/01"}]`, "", false, true},
+		{"path without a slash", `[{"op":"remove","path":"name"}]`, "", false, true},
+		{"unknown operation", `[{"op":"rename","path":"/name"}]`, "", false, true},
+		{"a failing operation fails the patch", `[{"op":"replace","path":"/name","value":"Tom"},{"op":"test","path":"/name","value":"Felix"}]`, "", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc interface{}
+			if err := json.Unmarshal([]byte(document), &doc); err != nil {
+				t.Fatal(err)
+			}
+			var operations []operation
+			if err := json.Unmarshal([]byte(tt.patch), &operations); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := applyJSONPatch(doc, operations)
+			var validation problem.ValidationErrors
+			switch {
+			case tt.conflict:
+				if !errors.Is(err, ErrConflict) {
+					t.Fatalf("error = %v, want %v", err, ErrConflict)
+				}
+			case tt.invalid:
+				if !errors.As(err, &validation) {
+					t.Fatalf("error = %v, want a validation error", err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			default:
+				var want interface{}
+				if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("patched document = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+type catPatch struct {
+	Name  string   `json:"name"`
+	Breed string   `json:"breed,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+func (c *catPatch) Bind(r *http.Request) error {
+	if c.Name == "" {
+		return problem.Field("name", "name is required")
+	}
+	return nil
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        catPatch
+		err         error
+	}{
+		{"merge patch", MergePatchType, `{"breed":"Siamese","tags":null}`, catPatch{Name: "Felix", Breed: "Siamese"}, nil},
+		{"plain JSON is a merge patch", "application/json", `{"name":"Tom"}`, catPatch{Name: "Tom", Tags: []string{"calm"}}, nil},
+		{"JSON Patch", JSONPatchType, `[{"op":"add","path":"/tags/-","value":"indoor"}]`,
+			catPatch{Name: "Felix", Tags: []string{"calm", "indoor"}}, nil},
+		{"JSON Patch of the root", JSONPatchType, `[{"op":"replace","path":"","value":{"name":"Tom"}}]`, catPatch{Name: "Tom"}, nil},
+		{"failing JSON Patch", JSONPatchType, `[{"op":"test","path":"/name","value":"Tom"}]`, catPatch{}, ErrConflict},
+		{"unsupported media type", "text/plain", `name=Tom`, catPatch{}, ErrUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/cats/1", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			cat := &catPatch{Name: "Felix", Tags: []string{"calm"}}
+
+			err := Bind(req, cat)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*cat, tt.want) {
+				t.Errorf("bound cat = %+v, want %+v", *cat, tt.want)
+			}
+		})
+	}
+
+	t.Run("the patched record is validated", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPatch, "/cats/1", strings.NewReader(`{"name":null}`))
+		req.Header.Set("Content-Type", MergePatchType)
+		var validation problem.ValidationErrors
+		if err := Bind(req, &catPatch{Name: "Felix"}); !errors.As(err, &validation) {
+			t.Errorf("error = %v, want a validation error", err)
+		}
+	})
+}
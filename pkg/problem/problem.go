@@ -0,0 +1,195 @@
+// Package problem writes error responses as RFC 7807 problem documents.
+package problem
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"gorm.io/gorm"
+)
+
+const (
+	ContentType = "application/problem+json"
+
+	// TypeValidation identifies payloads or query strings rejected field by field.
+	TypeValidation = "/problems/validation-error"
+)
+
+// FieldError points at the request field that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Problem is the body of every error response.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// ValidationErrors collects the field errors found by a Bind method.
+type ValidationErrors []FieldError
+
+func (v *ValidationErrors) Add(field, message string) {
+	*v = append(*v, FieldError{Field: field, Message: message})
+}
+
+// Err returns nil when no field failed, so Bind methods can end with "return errs.Err()".
+func (v ValidationErrors) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, fieldError := range v {
+		messages = append(messages, fieldError.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Field is a single field error, for checks that stop validation early.
+func Field(field, message string) error {
+	return ValidationErrors{{Field: field, Message: message}}
+}
+
+// Write sends a problem with the given status; detail explains this occurrence.
+func Write(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	WriteProblem(w, r, &Problem{Status: status, Detail: detail})
+}
+
+// LookupError answers a request whose record could not be read: with status and detail
+// when the record does not exist, and as a server error when the lookup itself failed.
+func LookupError(w http.ResponseWriter, r *http.Request, err error, status int, detail string) {
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		Write(w, r, http.StatusInternalServerError, "failed to read from the database")
+		return
+	}
+	Write(w, r, status, detail)
+}
+
+// WriteProblem fills in the defaults (type, title, instance, request ID) and sends the problem.
+// A server error caused by the request running past its query timeout is reported as 503.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p *Problem) {
+	if p.Status == http.StatusInternalServerError && errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		p.Status = http.StatusServiceUnavailable
+		p.Title = ""
+		p.Detail = "the request took longer than its time limit"
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = middleware.GetReqID(r.Context())
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// BindError answers a request whose payload could not be decoded or validated.
+func BindError(w http.ResponseWriter, r *http.Request, err error) {
+	p := &Problem{
+		Type:   TypeValidation,
+		Title:  "Invalid request payload",
+		Status: http.StatusBadRequest,
+	}
+
+	var validation ValidationErrors
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validation):
+		p.Detail = "the request payload is invalid"
+		p.Errors = validation
+	case errors.As(err, &typeError) && typeError.Field == "":
+		p.Detail = "the request body must be a JSON " + jsonType(typeError.Type)
+	case errors.As(err, &typeError):
+		p.Detail = "the request payload is invalid"
+		p.Errors = []FieldError{{Field: typeError.Field, Message: typeError.Field + " must be a JSON " + jsonType(typeError.Type)}}
+	case errors.As(err, &syntaxError), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		p.Detail = "the request body is not valid JSON"
+	default:
+		p.Detail = err.Error()
+	}
+
+	WriteProblem(w, r, p)
+}
+
+// jsonType names the kind of JSON value that decodes into t, without exposing Go types.
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	default:
+		return "value"
+	}
+}
+
+var textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// InvalidQuery answers a request whose query string (pagination, sort or filters) was rejected.
+func InvalidQuery(w http.ResponseWriter, r *http.Request, err error) {
+	p := &Problem{
+		Type:   TypeValidation,
+		Title:  "Invalid query parameters",
+		Status: http.StatusBadRequest,
+		Detail: err.Error(),
+	}
+
+	var validation ValidationErrors
+	if errors.As(err, &validation) {
+		p.Errors = validation
+	}
+
+	WriteProblem(w, r, p)
+}
+
+// RequestIDHeader echoes the request ID set by middleware.RequestID in the X-Request-Id
+// response header, so that clients can quote it when reporting an error.
+func RequestIDHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			w.Header().Set(middleware.RequestIDHeader, id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,41 @@
+package purge
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"github.com/go-chi/render"
+)
+
+type PurgeConfig struct {
+	*config.Config
+}
+
+func New(configuration *config.Config) *PurgeConfig {
+	return &PurgeConfig{configuration}
+}
+
+// PurgeHandler godoc
+// @Summary Permanently remove the records deleted before the retention period
+// @Description Removes the cats, visits, treatments, vaccinations, weight measurements, appointments, owners and veterinarians deleted more than the configured retention ago. They can no longer be restored. Each record is removed on its own: a 500 means that some could not be, the others are purged.
+// @Tags purge
+// @Produce json
+// @Success 200 {object} models.PurgeResponse
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /purge [post]
+func (config *PurgeConfig) PurgeHandler(w http.ResponseWriter, r *http.Request) {
+	deletedBefore := time.Now().Add(-config.DeletedRetention)
+	result, err := config.PurgeRepository.Purge(r.Context(), deletedBefore)
+	if err != nil {
+		log.Println("Failed to purge deleted records:", err)
+		problem.Write(w, r, http.StatusInternalServerError, "some deleted records could not be purged")
+		return
+	}
+
+	render.JSON(w, r, models.NewPurgeResponse(deletedBefore, result))
+}
@@ -0,0 +1,40 @@
+package purge
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+)
+
+// Start runs the purge every PurgeInterval until ctx is done. It does nothing when the
+// interval is 0.
+func Start(ctx context.Context, configuration *config.Config) {
+	if configuration.PurgeInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(configuration.PurgeInterval)
+		defer ticker.Stop()
+		for {
+			run(ctx, configuration)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func run(ctx context.Context, configuration *config.Config) {
+	result, err := configuration.PurgeRepository.Purge(ctx, time.Now().Add(-configuration.DeletedRetention))
+	if err != nil {
+		log.Println("Failed to purge deleted records:", err)
+	}
+	for entityType, count := range result {
+		log.Printf("Purged %d deleted %s records", count, entityType)
+	}
+}
@@ -0,0 +1,15 @@
+package purge
+
+import (
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/go-chi/chi/v5"
+)
+
+func Routes(configuration *config.Config) *chi.Mux {
+	purgeConfig := New(configuration)
+	router := chi.NewRouter()
+
+	router.Post("/", purgeConfig.PurgeHandler)
+
+	return router
+}
@@ -0,0 +1,220 @@
+package role
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/authentification"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"gorm.io/gorm"
+)
+
+type RoleConfig struct {
+	*config.Config
+}
+
+func New(configuration *config.Config) *RoleConfig {
+	return &RoleConfig{configuration}
+}
+
+// GetAllPermissionsHandler godoc
+// @Summary List the permissions that can be granted to roles
+// @Tags roles
+// @Produce json
+// @Success 200 {array} models.PermissionResponse
+// @Failure 500 {object} problem.Problem
+// @Router /permissions [get]
+func (config *RoleConfig) GetAllPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	permissions, err := config.RoleRepository.AllPermissions(r.Context())
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch permissions")
+		return
+	}
+
+	render.JSON(w, r, models.NewPermissionResponses(permissions))
+}
+
+// GetAllRolesHandler godoc
+// @Summary List roles with their permissions
+// @Tags roles
+// @Produce json
+// @Success 200 {array} models.RoleResponse
+// @Failure 500 {object} problem.Problem
+// @Router /roles [get]
+func (config *RoleConfig) GetAllRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := config.RoleRepository.FindAll(r.Context())
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch roles")
+		return
+	}
+
+	render.JSON(w, r, models.NewRoleResponses(roles))
+}
+
+// GetRoleByIDHandler godoc
+// @Summary Get a role by ID
+// @Tags roles
+// @Produce json
+// @Param id path int true "Role ID"
+// @Success 200 {object} models.RoleResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /roles/{id} [get]
+func (config *RoleConfig) GetRoleByIDHandler(w http.ResponseWriter, r *http.Request) {
+	role, ok := config.findRole(w, r)
+	if !ok {
+		return
+	}
+
+	render.JSON(w, r, models.NewRoleResponse(role))
+}
+
+// CreateRoleHandler godoc
+// @Summary Create a role
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param role body models.RoleRequest true "Role payload"
+// @Success 201 {object} models.RoleResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /roles [post]
+func (config *RoleConfig) CreateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	req := &models.RoleRequest{}
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	if _, err := config.RoleRepository.FindByName(r.Context(), req.Name); err == nil {
+		problem.Write(w, r, http.StatusConflict, "a role with this name already exists")
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Write(w, r, http.StatusInternalServerError, "unable to save role")
+		return
+	}
+
+	permissions, err := config.RoleRepository.FindPermissions(r.Context(), req.Permissions)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "unable to save role")
+		return
+	}
+
+	role, err := config.RoleRepository.Create(r.Context(), &dbmodel.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: permissions,
+	})
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "unable to save role")
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, models.NewRoleResponse(role))
+}
+
+// UpdateRoleHandler godoc
+// @Summary Update the description and the permissions of a role
+// @Description The name of a role cannot change, since users refer to their role by name. An admin cannot remove roles:manage from their own role.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param role body models.RoleRequest true "Role payload"
+// @Success 200 {object} models.RoleResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /roles/{id} [put]
+func (config *RoleConfig) UpdateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	role, ok := config.findRole(w, r)
+	if !ok {
+		return
+	}
+
+	req := &models.RoleRequest{}
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+	if req.Name != role.Name {
+		problem.BindError(w, r, problem.Field("name", "the name of a role cannot be changed"))
+		return
+	}
+	if principal, ok := authentification.PrincipalFromContext(r.Context()); ok && role.Name == principal.Role && !slices.Contains(req.Permissions, dbmodel.PermissionRolesManage) {
+		problem.Write(w, r, http.StatusConflict, "you cannot remove "+dbmodel.PermissionRolesManage+" from your own role")
+		return
+	}
+
+	permissions, err := config.RoleRepository.FindPermissions(r.Context(), req.Permissions)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to update role")
+		return
+	}
+
+	role.Description = req.Description
+	role.Permissions = permissions
+	updated, err := config.RoleRepository.Update(r.Context(), role)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to update role")
+		return
+	}
+
+	render.JSON(w, r, models.NewRoleResponse(updated))
+}
+
+// DeleteRoleHandler godoc
+// @Summary Delete a role
+// @Description Only roles that no user has can be deleted.
+// @Tags roles
+// @Param id path int true "Role ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /roles/{id} [delete]
+func (config *RoleConfig) DeleteRoleHandler(w http.ResponseWriter, r *http.Request) {
+	role, ok := config.findRole(w, r)
+	if !ok {
+		return
+	}
+
+	if err := config.RoleRepository.Delete(r.Context(), role.ID, role); err != nil {
+		if errors.Is(err, dbmodel.ErrRoleInUse) {
+			problem.Write(w, r, http.StatusConflict, err.Error())
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to delete role")
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+func (config *RoleConfig) findRole(w http.ResponseWriter, r *http.Request) (*dbmodel.Role, bool) {
+	idParam := chi.URLParam(r, "id")
+	id64, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid role ID")
+		return nil, false
+	}
+
+	role, err := config.RoleRepository.FindById(r.Context(), uint(id64))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "role not found")
+		return nil, false
+	}
+	return role, true
+}
@@ -0,0 +1,29 @@
+package role
+
+import (
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/go-chi/chi/v5"
+)
+
+func Routes(configuration *config.Config) *chi.Mux {
+	roleConfig := New(configuration)
+	router := chi.NewRouter()
+
+	router.Get("/", roleConfig.GetAllRolesHandler)
+	router.Post("/", roleConfig.CreateRoleHandler)
+	router.Get("/{id}", roleConfig.GetRoleByIDHandler)
+	router.Put("/{id}", roleConfig.UpdateRoleHandler)
+	router.Delete("/{id}", roleConfig.DeleteRoleHandler)
+
+	return router
+}
+
+// PermissionRoutes serve the catalog of permissions and are mounted under /permissions.
+func PermissionRoutes(configuration *config.Config) *chi.Mux {
+	roleConfig := New(configuration)
+	router := chi.NewRouter()
+
+	router.Get("/", roleConfig.GetAllPermissionsHandler)
+
+	return router
+}
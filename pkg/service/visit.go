@@ -0,0 +1,144 @@
+// Package service holds the operations that span several repositories. Each of them runs
+// in one unit of work, so that it either fully succeeds or leaves the database unchanged.
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCatNotFound is returned when the cat of a visit does not exist.
+	ErrCatNotFound = errors.New("cat not found")
+	// ErrVeterinarianUnavailable is returned when the veterinarian of a visit does not
+	// exist or is inactive.
+	ErrVeterinarianUnavailable = errors.New("veterinarian not found or inactive")
+	// ErrAppointmentNotCompleted is returned when recording the visit of an appointment
+	// that is not completed.
+	ErrAppointmentNotCompleted = errors.New("only completed appointments can be converted into a visit")
+)
+
+// VisitService records visits and what was done during them.
+type VisitService interface {
+	// Create records a visit of an existing cat by an active veterinarian.
+	Create(ctx context.Context, visit *dbmodel.Visit) (*dbmodel.Visit, error)
+	// Complete records a visit together with the treatments prescribed during it, in one
+	// transaction. The treatments are attached to the new visit.
+	Complete(ctx context.Context, visit *dbmodel.Visit, treatments []*dbmodel.Treatment) (*dbmodel.Visit, error)
+	// FromAppointment records the visit of a completed appointment and links the
+	// appointment to it. It returns dbmodel.ErrAppointmentHasVisit if the appointment
+	// already has a visit.
+	FromAppointment(ctx context.Context, appointmentID uint) (*dbmodel.Visit, error)
+}
+
+type visitService struct {
+	uow dbmodel.UnitOfWork
+}
+
+func NewVisitService(uow dbmodel.UnitOfWork) VisitService {
+	return &visitService{uow: uow}
+}
+
+func (s *visitService) Create(ctx context.Context, visit *dbmodel.Visit) (*dbmodel.Visit, error) {
+	return s.Complete(ctx, visit, nil)
+}
+
+func (s *visitService) Complete(ctx context.Context, visit *dbmodel.Visit, treatments []*dbmodel.Treatment) (*dbmodel.Visit, error) {
+	var saved *dbmodel.Visit
+	err := s.uow.Do(ctx, func(repos dbmodel.Repositories) error {
+		var err error
+		saved, err = createVisit(ctx, repos, visit, treatments)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func (s *visitService) FromAppointment(ctx context.Context, appointmentID uint) (*dbmodel.Visit, error) {
+	var saved *dbmodel.Visit
+	err := s.uow.Do(ctx, func(repos dbmodel.Repositories) error {
+		appointment, err := repos.Appointments.FindById(ctx, appointmentID)
+		if err != nil {
+			return err
+		}
+		if appointment.Status != dbmodel.AppointmentCompleted {
+			return ErrAppointmentNotCompleted
+		}
+		if appointment.VisitID != nil {
+			return dbmodel.ErrAppointmentHasVisit
+		}
+
+		visit := &dbmodel.Visit{
+			Date:           appointment.StartsAt,
+			Motif:          appointment.Reason,
+			VeterinarianID: &appointment.VeterinarianID,
+			CatID:          appointment.CatID,
+		}
+		if saved, err = createVisit(ctx, repos, visit, nil); err != nil {
+			return err
+		}
+		return repos.Appointments.LinkVisit(ctx, appointment, saved.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// createVisit saves the visit and its treatments with the repositories of a unit of work,
+// and returns the stored visit with its veterinarian and treatments.
+func createVisit(ctx context.Context, repos dbmodel.Repositories, visit *dbmodel.Visit, treatments []*dbmodel.Treatment) (*dbmodel.Visit, error) {
+	if err := checkVisitParties(ctx, repos, visit); err != nil {
+		return nil, err
+	}
+
+	saved, err := repos.Visits.Create(ctx, visit)
+	if err != nil {
+		return nil, err
+	}
+	for _, treatment := range treatments {
+		treatment.VisitID = saved.ID
+		if _, err := repos.Treatments.Create(ctx, treatment); err != nil {
+			return nil, err
+		}
+	}
+
+	saved, err = repos.Visits.FindById(ctx, saved.ID)
+	if err != nil {
+		return nil, err
+	}
+	saved.Treatments = make([]dbmodel.Treatment, 0, len(treatments))
+	for _, treatment := range treatments {
+		saved.Treatments = append(saved.Treatments, *treatment)
+	}
+	return saved, nil
+}
+
+// checkVisitParties makes sure the cat of the visit exists and its veterinarian is active.
+func checkVisitParties(ctx context.Context, repos dbmodel.Repositories, visit *dbmodel.Visit) error {
+	if _, err := repos.Cats.FindById(ctx, visit.CatID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCatNotFound
+		}
+		return err
+	}
+	if visit.VeterinarianID == nil {
+		return ErrVeterinarianUnavailable
+	}
+	veterinarian, err := repos.Veterinarians.FindById(ctx, *visit.VeterinarianID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVeterinarianUnavailable
+		}
+		return err
+	}
+	if !veterinarian.Active {
+		return ErrVeterinarianUnavailable
+	}
+	return nil
+}
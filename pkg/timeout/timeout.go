@@ -0,0 +1,56 @@
+// Package timeout bounds the time a request may spend in the database. Repositories run
+// their queries with the request context, so a query still running when the deadline
+// passes, or when the client goes away, is cancelled.
+package timeout
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/go-chi/chi/v5"
+)
+
+// Middleware gives each request a deadline: the query timeout configured for its route,
+// looked up in router as "METHOD /pattern", or the default query timeout. It must be
+// registered on router itself so that the whole pattern, mounts included, is known.
+func Middleware(router *chi.Mux, configuration *config.Config) func(http.Handler) http.Handler {
+	timeouts := make(map[string]time.Duration, len(configuration.QueryTimeouts))
+	for route, duration := range configuration.QueryTimeouts {
+		timeouts[trimSlash(route)] = duration
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			duration := configuration.QueryTimeout
+			if len(timeouts) > 0 {
+				if routeTimeout, ok := timeouts[r.Method+" "+trimSlash(routePattern(router, r))]; ok {
+					duration = routeTimeout
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), duration)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// routePattern returns the pattern of the route router would pick for r, or "" if none.
+func routePattern(router *chi.Mux, r *http.Request) string {
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+	return router.Find(chi.NewRouteContext(), r.Method, path)
+}
+
+// trimSlash lets "/api/v1/cats" name the route a subrouter mounts at "/api/v1/cats/".
+func trimSlash(route string) string {
+	if len(route) > 1 {
+		return strings.TrimSuffix(route, "/")
+	}
+	return route
+}
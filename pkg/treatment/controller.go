@@ -1,14 +1,19 @@
 package treatment
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/emmanuelYohore/vet-clinic-api/config"
 	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/etag"
 	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/patch"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"gorm.io/gorm"
 )
 
 type TreatmentConfig struct {
@@ -25,59 +30,79 @@ func New(configuration *config.Config) *TreatmentConfig {
 // @Accept json
 // @Produce json
 // @Param treatment body models.TreatmentRequest true "Treatment payload"
-// @Success 201 {object} dbmodel.Treatment
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 201 {object} models.TreatmentResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /treatments [post]
 func (config *TreatmentConfig) CreateTreatmentHandler(w http.ResponseWriter, r *http.Request) {
 	req := &models.TreatmentRequest{}
 
 	if err := render.Bind(r, req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid request payload",
-		})
+		problem.BindError(w, r, err)
 		return
 	}
 
-	treatment := &dbmodel.Treatment{
-		Name: req.Name,
+	if _, err := config.VisitRepository.FindById(r.Context(), req.VisitID); err != nil {
+		problem.LookupError(w, r, err, http.StatusUnprocessableEntity, "visit not found")
+		return
 	}
 
-	savedTreatment, err := config.TreatmentRepository.Create(treatment)
+	treatment := &dbmodel.Treatment{}
+	applyTreatmentRequest(treatment, req)
+
+	savedTreatment, err := config.TreatmentRepository.Create(r.Context(), treatment)
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "unable to save treatment",
-		})
+		problem.Write(w, r, http.StatusInternalServerError, "unable to save treatment")
 		return
 	}
 
+	etag.Set(w, savedTreatment.Version)
 	render.Status(r, http.StatusCreated)
 	w.Header().Set("Content-Type", "application/json")
-	render.JSON(w, r, savedTreatment)
+	render.JSON(w, r, models.NewTreatmentResponse(savedTreatment))
 }
 
 // GetAllTreatmentsHandler doc
 // @Summary Get all treatments
 // @Tags treatments
 // @Produce json
-// @Success 200 {array} dbmodel.Treatment
-// @Failure 500 {object} map[string]string
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. -start_date (id, name, visit_id, start_date, end_date, created_at)"
+// @Param include_deleted query bool false "Also list deleted records (requires deleted:manage)"
+// @Param visit_id query int false "Visit ID"
+// @Param name query string false "Name contains"
+// @Param route query string false "Route of administration"
+// @Param start_from query string false "Started on or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param start_to query string false "Started on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Success 200 {array} models.TreatmentResponse
+// @Header 200 {integer} X-Total-Count "Total number of matching treatments"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /treatments [get]
 func (config *TreatmentConfig) GetAllTreatmentsHandler(w http.ResponseWriter, r *http.Request) {
-	treatments, err := config.TreatmentRepository.FindAll()
+	opts, err := models.ParseQueryOptions(r)
+	if err != nil {
+		problem.InvalidQuery(w, r, err)
+		return
+	}
+
+	treatments, total, err := config.TreatmentRepository.FindAll(r.Context(), opts)
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to fetch treatments",
-		})
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch treatments")
 		return
 	}
+	models.SetPaginationHeaders(w, r, opts, total)
 
 	render.Status(r, http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
-	render.JSON(w, r, treatments)
+	render.JSON(w, r, models.NewTreatmentResponses(treatments))
 }
 
 // GetTreatmentByIDHandler doc
@@ -85,32 +110,33 @@ func (config *TreatmentConfig) GetAllTreatmentsHandler(w http.ResponseWriter, r
 // @Tags treatments
 // @Produce json
 // @Param id path int true "Treatment ID"
-// @Success 200 {object} dbmodel.Treatment
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {object} models.TreatmentResponse
+// @Header 200 {string} ETag "Version of the treatment"
+// @Success 304 "The cached copy is current"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /treatments/{id} [get]
 func (config *TreatmentConfig) GetTreatmentByIDHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	id64, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid treatment ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid treatment ID")
 		return
 	}
 
-	treatment, err := config.TreatmentRepository.FindById(uint(id64))
+	treatment, err := config.TreatmentRepository.FindById(r.Context(), uint(id64))
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{
-			"error": "cat not found",
-		})
+		problem.LookupError(w, r, err, http.StatusNotFound, "treatment not found")
+		return
+	}
+	if etag.NotModified(w, r, treatment.Version) {
 		return
 	}
 	render.Status(r, http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
-	render.JSON(w, r, treatment)
+	render.JSON(w, r, models.NewTreatmentResponse(treatment))
 }
 
 // UpdateTreatmentHandler doc
@@ -119,124 +145,298 @@ func (config *TreatmentConfig) GetTreatmentByIDHandler(w http.ResponseWriter, r
 // @Accept json
 // @Produce json
 // @Param id path int true "Treatment ID"
+// @Param If-Match header string true "ETag of the version being updated"
 // @Param treatment body models.TreatmentRequest true "Treatment payload"
-// @Success 200 {object} dbmodel.Treatment
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} models.TreatmentResponse
+// @Header 200 {string} ETag "New version of the treatment"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /treatments/{id} [put]
 func (config *TreatmentConfig) UpdateTreatmentHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	id64, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid treatment ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid treatment ID")
 		return
 	}
 
 	req := &models.TreatmentRequest{}
 	if err := render.Bind(r, req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid request payload",
-		})
+		problem.BindError(w, r, err)
 		return
 	}
 
-	existing, err := config.TreatmentRepository.FindById(uint(id64))
+	existing, err := config.TreatmentRepository.FindById(r.Context(), uint(id64))
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{
-			"error": "treatment not found",
-		})
+		problem.LookupError(w, r, err, http.StatusNotFound, "treatment not found")
 		return
 	}
+	if !etag.Match(w, r, existing.Version) {
+		return
+	}
+
+	config.updateTreatment(w, r, existing, req)
+}
 
-	existing.Name = req.Name
+// PatchTreatmentHandler doc
+// @Summary Partially update a treatment
+// @Description Send a merge patch (application/merge-patch+json) with only the fields to change, null clearing a date, or a JSON Patch (application/json-patch+json). The patched treatment is validated as a whole.
+// @Tags treatments
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path int true "Treatment ID"
+// @Param If-Match header string true "ETag of the version being updated"
+// @Param treatment body models.TreatmentRequest true "Fields to change"
+// @Success 200 {object} models.TreatmentResponse
+// @Header 200 {string} ETag "New version of the treatment"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 415 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /treatments/{id} [patch]
+func (config *TreatmentConfig) PatchTreatmentHandler(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid treatment ID")
+		return
+	}
 
-	updatedTreatment, err := config.TreatmentRepository.Update(existing)
+	existing, err := config.TreatmentRepository.FindById(r.Context(), uint(id64))
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to update treatment",
-		})
+		problem.LookupError(w, r, err, http.StatusNotFound, "treatment not found")
+		return
+	}
+	if !etag.Match(w, r, existing.Version) {
 		return
 	}
+
+	req := models.NewTreatmentRequest(existing)
+	if err := patch.Bind(r, req); err != nil {
+		patch.WriteError(w, r, err)
+		return
+	}
+
+	config.updateTreatment(w, r, existing, req)
+}
+
+// updateTreatment checks the visit, then saves the treatment with the fields of req.
+func (config *TreatmentConfig) updateTreatment(w http.ResponseWriter, r *http.Request, existing *dbmodel.Treatment, req *models.TreatmentRequest) {
+	if _, err := config.VisitRepository.FindById(r.Context(), req.VisitID); err != nil {
+		problem.LookupError(w, r, err, http.StatusUnprocessableEntity, "visit not found")
+		return
+	}
+
+	applyTreatmentRequest(existing, req)
+
+	updatedTreatment, err := config.TreatmentRepository.Update(r.Context(), existing)
+	if errors.Is(err, dbmodel.ErrVersionConflict) {
+		etag.Conflict(w, r)
+		return
+	}
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to update treatment")
+		return
+	}
+	etag.Set(w, updatedTreatment.Version)
 	render.Status(r, http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
-	render.JSON(w, r, updatedTreatment)
+	render.JSON(w, r, models.NewTreatmentResponse(updatedTreatment))
 }
 
 // DeleteTreatmentHandler doc
 // @Summary Delete a treatment
 // @Tags treatments
 // @Param id path int true "Treatment ID"
+// @Param If-Match header string true "ETag of the version being deleted"
 // @Success 204 {object} nil
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /treatments/{id} [delete]
 func (config *TreatmentConfig) DeleteTreatmentHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	id64, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid treatment ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid treatment ID")
 		return
 	}
 
-	treatment, err := config.TreatmentRepository.FindById(uint(id64))
+	treatment, err := config.TreatmentRepository.FindById(r.Context(), uint(id64))
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{
-			"error": "treatment not found",
-		})
+		problem.LookupError(w, r, err, http.StatusNotFound, "treatment not found")
+		return
+	}
+	if !etag.Match(w, r, treatment.Version) {
 		return
 	}
 
-	if err := config.TreatmentRepository.Delete(uint(id64), treatment); err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to delete treatment",
-		})
+	if err := config.TreatmentRepository.Delete(r.Context(), uint(id64), treatment); err != nil {
+		if errors.Is(err, dbmodel.ErrVersionConflict) {
+			etag.Conflict(w, r)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to delete treatment")
 		return
 	}
 	
 	render.Status(r, http.StatusNoContent)
 }
 
+// RestoreTreatmentHandler godoc
+// @Summary Restore a deleted treatment
+// @Description The visit of the treatment must not be deleted.
+// @Tags treatments
+// @Produce json
+// @Param id path int true "Treatment ID"
+// @Success 200 {object} models.TreatmentResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /treatments/{id}/restore [post]
+func (config *TreatmentConfig) RestoreTreatmentHandler(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid treatment ID")
+		return
+	}
+
+	restored, err := config.TreatmentRepository.Restore(r.Context(), uint(id64))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			problem.Write(w, r, http.StatusNotFound, "treatment not found")
+		case errors.Is(err, dbmodel.ErrNotDeleted), errors.Is(err, dbmodel.ErrParentDeleted):
+			problem.Write(w, r, http.StatusConflict, err.Error())
+		default:
+			problem.Write(w, r, http.StatusInternalServerError, "failed to restore treatment")
+		}
+		return
+	}
+
+	etag.Set(w, restored.Version)
+	render.JSON(w, r, models.NewTreatmentResponse(restored))
+}
+
+// CreateVisitTreatmentHandler doc
+// @Summary Create a treatment for a visit
+// @Tags treatments
+// @Accept json
+// @Produce json
+// @Param id path int true "Visit ID"
+// @Param treatment body models.TreatmentRequest true "Treatment payload (visit_id may be omitted)"
+// @Success 201 {object} models.TreatmentResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /visits/{id}/treatments [post]
+func (config *TreatmentConfig) CreateVisitTreatmentHandler(w http.ResponseWriter, r *http.Request) {
+	visitIDParam := chi.URLParam(r, "id")
+	visitID64, err := strconv.ParseUint(visitIDParam, 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid visit ID")
+		return
+	}
+
+	if _, err := config.VisitRepository.FindById(r.Context(), uint(visitID64)); err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "visit not found")
+		return
+	}
+
+	req := &models.TreatmentRequest{VisitID: uint(visitID64)}
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	if req.VisitID != uint(visitID64) {
+		problem.Write(w, r, http.StatusBadRequest, "visit_id does not match the visit in the URL")
+		return
+	}
+
+	treatment := &dbmodel.Treatment{}
+	applyTreatmentRequest(treatment, req)
+
+	savedTreatment, err := config.TreatmentRepository.Create(r.Context(), treatment)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "unable to save treatment")
+		return
+	}
+
+	etag.Set(w, savedTreatment.Version)
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, models.NewTreatmentResponse(savedTreatment))
+}
+
 // GetTreatmentByVisitHandler doc
 // @Summary Get treatments by Visit ID
 // @Tags treatments
 // @Produce json
-// @Param visit_id path int true "Visit ID"
-// @Success 200 {array} dbmodel.Treatment
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Param id path int true "Visit ID"
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. -start_date"
+// @Param include_deleted query bool false "Also list deleted records (requires deleted:manage)"
+// @Header 200 {integer} X-Total-Count "Total number of matching treatments"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Success 200 {array} models.TreatmentResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /visits/{id}/treatments [get]
 func (config *TreatmentConfig) GetTreatmentByVisitHandler(w http.ResponseWriter, r *http.Request) {
-	visitIDParam := chi.URLParam(r, "visit_id")
+	visitIDParam := chi.URLParam(r, "id")
 	visitID64, err := strconv.ParseUint(visitIDParam, 10, 32)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid visit ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid visit ID")
+		return
+	}
+
+	if _, err := config.VisitRepository.FindById(r.Context(), uint(visitID64)); err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "visit not found")
+		return
+	}
+
+	opts, err := models.ParseQueryOptions(r)
+	if err != nil {
+		problem.InvalidQuery(w, r, err)
 		return
 	}
-	treatments, err := config.TreatmentRepository.FindByVisitID(uint(visitID64))
+	opts.Filters["visit_id"] = visitIDParam
+	treatments, total, err := config.TreatmentRepository.FindAll(r.Context(), opts)
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to fetch treatments for the visit",
-		})
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch treatments for the visit")
 		return
 	}
+	models.SetPaginationHeaders(w, r, opts, total)
 	w.Header().Set("Content-Type", "application/json")
 
-	render.JSON(w, r, treatments)
+	render.JSON(w, r, models.NewTreatmentResponses(treatments))
+}
+
+func applyTreatmentRequest(treatment *dbmodel.Treatment, req *models.TreatmentRequest) {
+	treatment.Name = req.Name
+	treatment.Dosage = req.Dosage
+	treatment.Unit = req.Unit
+	treatment.Route = req.Route
+	treatment.Frequency = req.Frequency
+	treatment.StartDate = req.StartDate
+	treatment.EndDate = req.EndDate
+	treatment.Notes = req.Notes
+	treatment.VisitID = req.VisitID
 }
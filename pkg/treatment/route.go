@@ -2,6 +2,8 @@ package treatment
 
 import (
 	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/authentification"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -9,15 +11,31 @@ func Routes(configuration *config.Config) *chi.Mux {
 	treatmentConfig := New(configuration)
 	router := chi.NewRouter()
 
-	router.Post("/", treatmentConfig.CreateTreatmentHandler)
-	router.Get("/", treatmentConfig.GetAllTreatmentsHandler)
-	router.Get("/{id}", treatmentConfig.GetTreatmentByIDHandler)
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionTreatmentsRead))
+		r.Get("/", treatmentConfig.GetAllTreatmentsHandler)
+		r.Get("/{id}", treatmentConfig.GetTreatmentByIDHandler)
+	})
 
-	router.Get("/visits/{id}/treatments", treatmentConfig.GetTreatmentByVisitHandler)
-	router.Put("/{id}", treatmentConfig.UpdateTreatmentHandler)
-	router.Delete("/{id}", treatmentConfig.DeleteTreatmentHandler)
-	
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionTreatmentsWrite))
+		r.Post("/", treatmentConfig.CreateTreatmentHandler)
+		r.Put("/{id}", treatmentConfig.UpdateTreatmentHandler)
+		r.Patch("/{id}", treatmentConfig.PatchTreatmentHandler)
+		r.Delete("/{id}", treatmentConfig.DeleteTreatmentHandler)
+		r.With(authentification.RequirePermission(dbmodel.PermissionDeletedManage)).Post("/{id}/restore", treatmentConfig.RestoreTreatmentHandler)
+	})
 
+	return router
+}
+
+// VisitRoutes serves the treatments of a single visit and is mounted under /visits/{id}/treatments.
+func VisitRoutes(configuration *config.Config) *chi.Mux {
+	treatmentConfig := New(configuration)
+	router := chi.NewRouter()
+
+	router.With(authentification.RequirePermission(dbmodel.PermissionTreatmentsRead)).Get("/", treatmentConfig.GetTreatmentByVisitHandler)
+	router.With(authentification.RequirePermission(dbmodel.PermissionTreatmentsWrite)).Post("/", treatmentConfig.CreateVisitTreatmentHandler)
 
 	return router
 }
@@ -1,14 +1,21 @@
 package user
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/emmanuelYohore/vet-clinic-api/config"
 	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/authentification"
 	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/patch"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type UserConfig struct {
@@ -23,10 +30,11 @@ func (config *UserConfig) CreateUserHandler(w http.ResponseWriter, r *http.Reque
 	req := &models.UserRequest{}
 
 	if err := render.Bind(r, req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid request payload",
-		})
+		problem.BindError(w, r, err)
+		return
+	}
+
+	if !config.checkPassword(w, r, "password", req.Password, req.Email) {
 		return
 	}
 
@@ -34,6 +42,9 @@ func (config *UserConfig) CreateUserHandler(w http.ResponseWriter, r *http.Reque
 	if userRole == "" {
 		userRole = "user"
 	}
+	if !config.checkRole(w, r, userRole) {
+		return
+	}
 
 	user := &dbmodel.User{
 		Email:    req.Email,
@@ -41,80 +52,70 @@ func (config *UserConfig) CreateUserHandler(w http.ResponseWriter, r *http.Reque
 		Role:     userRole,
 	}
 
-	savedUser, err := config.UserRepository.Create(user)
+	savedUser, err := config.UserRepository.Create(r.Context(), user)
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "unable to save user",
-		})
+		problem.Write(w, r, http.StatusInternalServerError, "unable to save user")
 		return
 	}
 
 	render.Status(r, http.StatusCreated)
 	w.Header().Set("Content-Type", "application/json")
-	render.JSON(w, r, savedUser)
+	render.JSON(w, r, models.NewUserResponse(savedUser))
 }
 
 func (config *UserConfig) GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
-	users, err := config.UserRepository.FindAll()
+	opts, err := models.ParseQueryOptions(r)
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "unable to fetch users",
-		})
+		problem.InvalidQuery(w, r, err)
 		return
 	}
+
+	users, total, err := config.UserRepository.FindAll(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "unable to fetch users")
+		return
+	}
+	models.SetPaginationHeaders(w, r, opts, total)
 	render.Status(r, http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
-	render.JSON(w, r, users)
+	render.JSON(w, r, models.NewUserResponses(users))
 }
 
 func (config *UserConfig) GetUserByIDHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	userID, err := strconv.Atoi(idParam)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid user ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid user ID")
 		return
 	}
-	user, err := config.UserRepository.FindById(uint(userID))
+	user, err := config.UserRepository.FindById(r.Context(), uint(userID))
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{
-			"error": "user not found",
-		})
+		problem.LookupError(w, r, err, http.StatusNotFound, "user not found")
 		return
 	}
 	render.Status(r, http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
-	render.JSON(w, r, user)
+	render.JSON(w, r, models.NewUserResponse(user))
 }
 
 func (config *UserConfig) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	userID, err := strconv.Atoi(idParam)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid user ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid user ID")
 		return
 	}
-	existingUser, err := config.UserRepository.FindById(uint(userID))
+	existingUser, err := config.UserRepository.FindById(r.Context(), uint(userID))
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{
-			"error": "user not found",
-		})
+		problem.LookupError(w, r, err, http.StatusNotFound, "user not found")
 		return
 	}
-	if err := config.UserRepository.Delete(uint(userID), existingUser); err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to delete user",
-		})
+	if err := config.UserRepository.Delete(r.Context(), uint(userID), existingUser); err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to delete user")
 		return
 	}
 }
@@ -123,39 +124,193 @@ func (config *UserConfig) UpdateUserHandler(w http.ResponseWriter, r *http.Reque
 	idParam := chi.URLParam(r, "id")
 	userID, err := strconv.Atoi(idParam)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid user ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid user ID")
 		return
 	}
 	req := &models.UserRequest{}
 	if err := render.Bind(r, req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid request payload",
-		})
+		problem.BindError(w, r, err)
 		return
 	}
-	existingUser, err := config.UserRepository.FindById(uint(userID))
+	existingUser, err := config.UserRepository.FindById(r.Context(), uint(userID))
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{
-			"error": "user not found",
-		})
+		problem.LookupError(w, r, err, http.StatusNotFound, "user not found")
+		return
+	}
+	if !config.checkPassword(w, r, "password", req.Password, req.Email) {
 		return
 	}
+	if req.Role != "" {
+		if !config.checkRole(w, r, req.Role) {
+			return
+		}
+		existingUser.Role = req.Role
+	}
+
 	existingUser.Email = req.Email
-	existingUser.Password = req.Password
-	updatedUser, err := config.UserRepository.Update(existingUser)
+	updatedUser, err := config.saveUser(r.Context(), existingUser, req.Password)
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to update user",
-		})
+		problem.Write(w, r, http.StatusInternalServerError, "failed to update user")
 		return
 	}
 	render.Status(r, http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
-	render.JSON(w, r, updatedUser)
+	render.JSON(w, r, models.NewUserResponse(updatedUser))
+}
+
+// PatchUserHandler changes only the fields sent in a merge patch or a JSON Patch. A new
+// password goes through the password policy, as in UpdateUserHandler.
+func (config *UserConfig) PatchUserHandler(w http.ResponseWriter, r *http.Request) {
+	idParam := chi.URLParam(r, "id")
+	userID, err := strconv.Atoi(idParam)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+	existingUser, err := config.UserRepository.FindById(r.Context(), uint(userID))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "user not found")
+		return
+	}
+	req := models.NewUserPatchRequest(existingUser)
+	if err := patch.Bind(r, req); err != nil {
+		patch.WriteError(w, r, err)
+		return
+	}
+	if req.Role != existingUser.Role && !config.checkRole(w, r, req.Role) {
+		return
+	}
+	if req.Password != "" && !config.checkPassword(w, r, "password", req.Password, req.Email) {
+		return
+	}
+
+	existingUser.Email = req.Email
+	existingUser.Role = req.Role
+	updatedUser, err := config.saveUser(r.Context(), existingUser, req.Password)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to update user")
+		return
+	}
+	render.Status(r, http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	render.JSON(w, r, models.NewUserResponse(updatedUser))
+}
+
+// UnlockUserHandler lifts a lockout caused by failed logins before it expires.
+func (config *UserConfig) UnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	idParam := chi.URLParam(r, "id")
+	userID, err := strconv.Atoi(idParam)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+	user, err := config.UserRepository.FindById(r.Context(), uint(userID))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "user not found")
+		return
+	}
+	if err := config.UserRepository.Unlock(r.Context(), user); err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to unlock user")
+		return
+	}
+	render.Status(r, http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	render.JSON(w, r, models.NewUserResponse(user))
+}
+
+// DisableUserHandler stops the user from logging in and ends their sessions. Their
+// access tokens are refused from the next request on.
+func (config *UserConfig) DisableUserHandler(w http.ResponseWriter, r *http.Request) {
+	config.setDisabled(w, r, true)
+}
+
+// EnableUserHandler lets a disabled user log in again.
+func (config *UserConfig) EnableUserHandler(w http.ResponseWriter, r *http.Request) {
+	config.setDisabled(w, r, false)
+}
+
+func (config *UserConfig) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	idParam := chi.URLParam(r, "id")
+	userID, err := strconv.Atoi(idParam)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+	user, err := config.UserRepository.FindById(r.Context(), uint(userID))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "user not found")
+		return
+	}
+	if principal, ok := authentification.PrincipalFromContext(r.Context()); ok && disabled && principal.UserID == user.ID {
+		problem.Write(w, r, http.StatusConflict, "you cannot disable your own account")
+		return
+	}
+	if err := config.UserRepository.SetDisabled(r.Context(), user, disabled); err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to update user")
+		return
+	}
+	if disabled {
+		if err := config.RefreshTokenRepository.RevokeUser(r.Context(), user.ID); err != nil {
+			problem.Write(w, r, http.StatusInternalServerError, "failed to revoke the user's sessions")
+			return
+		}
+	}
+	render.Status(r, http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	render.JSON(w, r, models.NewUserResponse(user))
+}
+
+// checkPassword applies the password policy and answers 400 with one error per broken rule.
+func (config *UserConfig) checkPassword(w http.ResponseWriter, r *http.Request, field, password, email string) bool {
+	if err := config.PasswordPolicy.Validate(field, password, email); err != nil {
+		problem.BindError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// checkRole answers 400 unless the role exists.
+func (config *UserConfig) checkRole(w http.ResponseWriter, r *http.Request, role string) bool {
+	if _, err := config.RoleRepository.FindByName(r.Context(), role); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, http.StatusInternalServerError, "failed to check role")
+			return false
+		}
+		problem.BindError(w, r, problem.Field("role", "unknown role: "+role))
+		return false
+	}
+	return true
+}
+
+// saveUser stores the account and, unless password is empty, its new password in one
+// transaction, so that a failed password change does not leave the other changes behind.
+// The password must already have passed the policy.
+func (config *UserConfig) saveUser(ctx context.Context, user *dbmodel.User, password string) (*dbmodel.User, error) {
+	var updatedUser *dbmodel.User
+	err := config.UnitOfWork.Do(ctx, func(repos dbmodel.Repositories) error {
+		var err error
+		if updatedUser, err = repos.Users.Update(ctx, user); err != nil {
+			return err
+		}
+		if password == "" {
+			return nil
+		}
+		return setPassword(ctx, repos, updatedUser, password)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updatedUser, nil
+}
+
+// setPassword stores a new password, unless it is the current one, and then ends the
+// user's sessions so that a stolen refresh token stops working.
+func setPassword(ctx context.Context, repos dbmodel.Repositories, user *dbmodel.User, password string) error {
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) == nil {
+		return nil
+	}
+	if err := repos.Users.UpdatePassword(ctx, user, password); err != nil {
+		return err
+	}
+	return repos.RefreshTokens.RevokeUser(ctx, user.ID)
 }
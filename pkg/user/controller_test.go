@@ -0,0 +1,130 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/database/migrations"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/password"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestConfig returns the configuration the user handlers need, on a fresh in-memory
+// SQLite database.
+func newTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to ":memory:" opens its own database.
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.SeedRoles(db); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := password.NewPolicy(10, 3, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &config.Config{
+		PasswordPolicy:         policy,
+		UserRepository:         dbmodel.NewUserRepository(db),
+		RoleRepository:         dbmodel.NewRoleRepository(db),
+		RefreshTokenRepository: dbmodel.NewRefreshTokenRepository(db),
+		UnitOfWork:             dbmodel.NewUnitOfWork(db),
+	}
+}
+
+// assertNoSecrets fails when a response body carries a password, a password hash or a
+// refresh token.
+func assertNoSecrets(t *testing.T, body string) {
+	t.Helper()
+	for _, secret := range []string{"password", "refresh_token", "$2"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("response contains %q: %s", secret, body)
+		}
+	}
+}
+
+func TestUserResponsesHideSecrets(t *testing.T) {
+	cfg := newTestConfig(t)
+	ctx := context.Background()
+
+	user, err := cfg.UserRepository.Create(ctx, &dbmodel.User{
+		Email:    "vet@example.com",
+		Password: "Stored-Passw0rd",
+		Role:     "user",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(user.Password, "$2") {
+		t.Fatalf("stored password is not a bcrypt hash: %q", user.Password)
+	}
+	now := time.Now()
+	if _, err := cfg.RefreshTokenRepository.Create(ctx, &dbmodel.RefreshToken{
+		UserID:          user.ID,
+		FamilyID:        "family",
+		TokenHash:       "hash",
+		ExpiresAt:       now.Add(time.Hour),
+		FamilyExpiresAt: now.Add(time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := Routes(cfg)
+	path := "/" + strconv.Itoa(int(user.ID))
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"create", http.MethodPost, "/", "application/json",
+			`{"email":"new@example.com","password":"Created-Passw0rd"}`, http.StatusCreated},
+		{"list", http.MethodGet, "/", "", "", http.StatusOK},
+		{"get by id", http.MethodGet, path, "", "", http.StatusOK},
+		{"update", http.MethodPut, path, "application/json",
+			`{"email":"vet@example.com","password":"Updated-Passw0rd","role":"user"}`, http.StatusOK},
+		{"merge patch", http.MethodPatch, path, "application/merge-patch+json",
+			`{"password":"Patched-Passw0rd"}`, http.StatusOK},
+		{"json patch", http.MethodPatch, path, "application/json-patch+json",
+			`[{"op":"replace","path":"/email","value":"renamed@example.com"}]`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			assertNoSecrets(t, rec.Body.String())
+		})
+	}
+}
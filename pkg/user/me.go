@@ -0,0 +1,97 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/authentification"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"github.com/go-chi/render"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// GetMeHandler godoc
+// @Summary Get the logged-in user
+// @Description Returns the account the access token was issued to, with the permissions of its role.
+// @Tags me
+// @Produce json
+// @Success 200 {object} models.MeResponse
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /me [get]
+func (config *UserConfig) GetMeHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authentification.PrincipalFromContext(r.Context())
+	if !ok {
+		problem.Write(w, r, http.StatusUnauthorized, "Missing token")
+		return
+	}
+
+	user, err := config.UserRepository.FindById(r.Context(), principal.UserID)
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusUnauthorized, "user not found")
+		return
+	}
+
+	permissions := principal.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	render.JSON(w, r, &models.MeResponse{
+		UserResponse: models.NewUserResponse(user),
+		Permissions:  permissions,
+		SessionID:    principal.SessionID,
+	})
+}
+
+// ChangeOwnPasswordHandler godoc
+// @Summary Change the password of the logged-in user
+// @Description Requires the current password. Every refresh token of the user is revoked.
+// @Tags me
+// @Accept json
+// @Param password body models.PasswordChangeRequest true "Current and new password"
+// @Success 204 {object} nil
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /me/password [put]
+func (config *UserConfig) ChangeOwnPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	req := &models.PasswordChangeRequest{}
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	principal, ok := authentification.PrincipalFromContext(r.Context())
+	if !ok {
+		problem.Write(w, r, http.StatusUnauthorized, "Missing token")
+		return
+	}
+	user, err := config.UserRepository.FindById(r.Context(), principal.UserID)
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusUnauthorized, "user not found")
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		problem.BindError(w, r, problem.Field("current_password", "the current password is incorrect"))
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		problem.BindError(w, r, problem.Field("new_password", "the new password must differ from the current one"))
+		return
+	}
+	if !config.checkPassword(w, r, "new_password", req.NewPassword, user.Email) {
+		return
+	}
+
+	err = config.UnitOfWork.Do(r.Context(), func(repos dbmodel.Repositories) error {
+		return setPassword(r.Context(), repos, user, req.NewPassword)
+	})
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to change password")
+		return
+	}
+
+	render.NoContent(w, r)
+}
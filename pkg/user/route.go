@@ -14,7 +14,23 @@ func Routes(configuration *config.Config) *chi.Mux {
 	router.Get("/", userConfig.GetAllUsersHandler)
 	router.Get("/{id}", userConfig.GetUserByIDHandler)
 	router.Put("/{id}", userConfig.UpdateUserHandler)
+	router.Patch("/{id}", userConfig.PatchUserHandler)
 	router.Delete("/{id}", userConfig.DeleteUserHandler)
+	router.Post("/{id}/unlock", userConfig.UnlockUserHandler)
+	router.Post("/{id}/disable", userConfig.DisableUserHandler)
+	router.Post("/{id}/enable", userConfig.EnableUserHandler)
+
+	return router
+}
+
+// MeRoutes are the routes every logged-in user can call on their own account.
+func MeRoutes(configuration *config.Config) *chi.Mux {
+	userConfig := New(configuration)
+
+	router := chi.NewRouter()
+
+	router.Get("/", userConfig.GetMeHandler)
+	router.Put("/password", userConfig.ChangeOwnPasswordHandler)
 
 	return router
 }
@@ -0,0 +1,438 @@
+package vaccination
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/etag"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"gorm.io/gorm"
+)
+
+// defaultDueWindow is how far ahead the due report looks when no before date is given.
+const defaultDueWindow = 30 * 24 * time.Hour
+
+type VaccinationConfig struct {
+	*config.Config
+}
+
+func New(configuration *config.Config) *VaccinationConfig {
+	return &VaccinationConfig{configuration}
+}
+
+// CreateVaccinationHandler godoc
+// @Summary Record a vaccination
+// @Tags vaccinations
+// @Accept json
+// @Produce json
+// @Param vaccination body models.VaccinationRequest true "Vaccination payload"
+// @Success 201 {object} models.VaccinationResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /vaccinations [post]
+func (config *VaccinationConfig) CreateVaccinationHandler(w http.ResponseWriter, r *http.Request) {
+	req := &models.VaccinationRequest{}
+
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	vaccination := &dbmodel.Vaccination{}
+	applyVaccinationRequest(vaccination, req)
+
+	config.saveVaccination(w, r, vaccination, http.StatusCreated)
+}
+
+// GetAllVaccinationsHandler godoc
+// @Summary List vaccinations
+// @Tags vaccinations
+// @Produce json
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. -administered_at (id, vaccine_name, administered_at, next_due_at, cat_id, created_at)"
+// @Param include_deleted query bool false "Also list deleted records (requires deleted:manage)"
+// @Param cat_id query int false "Cat ID"
+// @Param veterinarian_id query int false "Veterinarian ID"
+// @Param visit_id query int false "Visit ID"
+// @Param vaccine_name query string false "Vaccine name contains"
+// @Param lot_number query string false "Lot number"
+// @Param administered_from query string false "Administered on or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param administered_to query string false "Administered on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Param due_before query string false "Next due on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Success 200 {array} models.VaccinationResponse
+// @Header 200 {integer} X-Total-Count "Total number of matching vaccinations"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /vaccinations [get]
+func (config *VaccinationConfig) GetAllVaccinationsHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := models.ParseQueryOptions(r)
+	if err != nil {
+		problem.InvalidQuery(w, r, err)
+		return
+	}
+
+	config.listVaccinations(w, r, opts)
+}
+
+// GetVaccinationByIDHandler godoc
+// @Summary Get a vaccination by ID
+// @Tags vaccinations
+// @Produce json
+// @Param id path int true "Vaccination ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {object} models.VaccinationResponse
+// @Header 200 {string} ETag "Version of the vaccination"
+// @Success 304 "The cached copy is current"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /vaccinations/{id} [get]
+func (config *VaccinationConfig) GetVaccinationByIDHandler(w http.ResponseWriter, r *http.Request) {
+	vaccination, ok := config.findVaccination(w, r)
+	if !ok || etag.NotModified(w, r, vaccination.Version) {
+		return
+	}
+
+	render.JSON(w, r, models.NewVaccinationResponse(vaccination))
+}
+
+// UpdateVaccinationHandler godoc
+// @Summary Update a vaccination
+// @Tags vaccinations
+// @Accept json
+// @Produce json
+// @Param id path int true "Vaccination ID"
+// @Param If-Match header string true "ETag of the version being updated"
+// @Param vaccination body models.VaccinationRequest true "Vaccination payload"
+// @Success 200 {object} models.VaccinationResponse
+// @Header 200 {string} ETag "New version of the vaccination"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /vaccinations/{id} [put]
+func (config *VaccinationConfig) UpdateVaccinationHandler(w http.ResponseWriter, r *http.Request) {
+	existing, ok := config.findVaccination(w, r)
+	if !ok || !etag.Match(w, r, existing.Version) {
+		return
+	}
+
+	req := &models.VaccinationRequest{}
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	applyVaccinationRequest(existing, req)
+
+	config.saveVaccination(w, r, existing, http.StatusOK)
+}
+
+// DeleteVaccinationHandler godoc
+// @Summary Delete a vaccination
+// @Tags vaccinations
+// @Param id path int true "Vaccination ID"
+// @Param If-Match header string true "ETag of the version being deleted"
+// @Success 204 {object} nil
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /vaccinations/{id} [delete]
+func (config *VaccinationConfig) DeleteVaccinationHandler(w http.ResponseWriter, r *http.Request) {
+	vaccination, ok := config.findVaccination(w, r)
+	if !ok || !etag.Match(w, r, vaccination.Version) {
+		return
+	}
+
+	if err := config.VaccinationRepository.Delete(r.Context(), vaccination.ID, vaccination); err != nil {
+		if errors.Is(err, dbmodel.ErrVersionConflict) {
+			etag.Conflict(w, r)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to delete vaccination")
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+// RestoreVaccinationHandler godoc
+// @Summary Restore a deleted vaccination
+// @Description The cat of the vaccination must not be deleted.
+// @Tags vaccinations
+// @Produce json
+// @Param id path int true "Vaccination ID"
+// @Success 200 {object} models.VaccinationResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /vaccinations/{id}/restore [post]
+func (config *VaccinationConfig) RestoreVaccinationHandler(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid vaccination ID")
+		return
+	}
+
+	restored, err := config.VaccinationRepository.Restore(r.Context(), uint(id64))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			problem.Write(w, r, http.StatusNotFound, "vaccination not found")
+		case errors.Is(err, dbmodel.ErrNotDeleted), errors.Is(err, dbmodel.ErrParentDeleted):
+			problem.Write(w, r, http.StatusConflict, err.Error())
+		default:
+			problem.Write(w, r, http.StatusInternalServerError, "failed to restore vaccination")
+		}
+		return
+	}
+
+	etag.Set(w, restored.Version)
+	render.JSON(w, r, models.NewVaccinationResponse(restored))
+}
+
+// GetDueVaccinationsHandler godoc
+// @Summary List vaccines that are overdue or due soon
+// @Description Only the latest shot of each vaccine per cat is considered, so a booster clears the previous due date.
+// @Tags vaccinations
+// @Produce json
+// @Param before query string false "Due before the end of this day (YYYY-MM-DD, clinic timezone) or this instant (RFC 3339); default 30 days from now"
+// @Success 200 {array} models.DueVaccinationResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /vaccinations/due [get]
+func (config *VaccinationConfig) GetDueVaccinationsHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	before := now.Add(defaultDueWindow)
+	if value := r.URL.Query().Get("before"); value != "" {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			before = t
+		} else if day, err := time.ParseInLocation(time.DateOnly, value, config.Location()); err == nil {
+			before = day.AddDate(0, 0, 1)
+		} else {
+			problem.Write(w, r, http.StatusBadRequest, "before must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+			return
+		}
+	}
+
+	vaccinations, err := config.VaccinationRepository.FindDue(r.Context(), before.UTC())
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch due vaccinations")
+		return
+	}
+
+	due := make([]models.DueVaccinationResponse, 0, len(vaccinations))
+	for _, vaccination := range vaccinations {
+		item := models.DueVaccinationResponse{
+			VaccinationID:      vaccination.ID,
+			CatID:              vaccination.CatID,
+			VaccineName:        vaccination.VaccineName,
+			LastAdministeredAt: vaccination.AdministeredAt,
+			NextDueAt:          *vaccination.NextDueAt,
+			Overdue:            vaccination.NextDueAt.Before(now),
+		}
+		if vaccination.Cat != nil {
+			item.CatName = vaccination.Cat.Name
+			item.OwnerID = vaccination.Cat.OwnerID
+		}
+		due = append(due, item)
+	}
+
+	render.JSON(w, r, due)
+}
+
+// CreateCatVaccinationHandler godoc
+// @Summary Record a vaccination for a cat
+// @Tags vaccinations
+// @Accept json
+// @Produce json
+// @Param id path int true "Cat ID"
+// @Param vaccination body models.VaccinationRequest true "Vaccination payload (cat_id may be omitted)"
+// @Success 201 {object} models.VaccinationResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /cats/{id}/vaccinations [post]
+func (config *VaccinationConfig) CreateCatVaccinationHandler(w http.ResponseWriter, r *http.Request) {
+	catID, ok := config.findCat(w, r)
+	if !ok {
+		return
+	}
+
+	req := &models.VaccinationRequest{CatID: catID}
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	if req.CatID != catID {
+		problem.Write(w, r, http.StatusBadRequest, "cat_id does not match the cat in the URL")
+		return
+	}
+
+	vaccination := &dbmodel.Vaccination{}
+	applyVaccinationRequest(vaccination, req)
+
+	config.saveVaccination(w, r, vaccination, http.StatusCreated)
+}
+
+// GetCatVaccinationsHandler godoc
+// @Summary List the vaccinations of a cat
+// @Tags vaccinations
+// @Produce json
+// @Param id path int true "Cat ID"
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. -administered_at"
+// @Param include_deleted query bool false "Also list deleted records (requires deleted:manage)"
+// @Param vaccine_name query string false "Vaccine name contains"
+// @Success 200 {array} models.VaccinationResponse
+// @Header 200 {integer} X-Total-Count "Total number of matching vaccinations"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /cats/{id}/vaccinations [get]
+func (config *VaccinationConfig) GetCatVaccinationsHandler(w http.ResponseWriter, r *http.Request) {
+	catID, ok := config.findCat(w, r)
+	if !ok {
+		return
+	}
+
+	opts, err := models.ParseQueryOptions(r)
+	if err != nil {
+		problem.InvalidQuery(w, r, err)
+		return
+	}
+	opts.Filters["cat_id"] = strconv.FormatUint(uint64(catID), 10)
+
+	config.listVaccinations(w, r, opts)
+}
+
+func (config *VaccinationConfig) listVaccinations(w http.ResponseWriter, r *http.Request, opts dbmodel.QueryOptions) {
+	vaccinations, total, err := config.VaccinationRepository.FindAll(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch vaccinations")
+		return
+	}
+	models.SetPaginationHeaders(w, r, opts, total)
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, models.NewVaccinationResponses(vaccinations))
+}
+
+func (config *VaccinationConfig) findVaccination(w http.ResponseWriter, r *http.Request) (*dbmodel.Vaccination, bool) {
+	idParam := chi.URLParam(r, "id")
+	id64, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid vaccination ID")
+		return nil, false
+	}
+
+	vaccination, err := config.VaccinationRepository.FindById(r.Context(), uint(id64))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "vaccination not found")
+		return nil, false
+	}
+	return vaccination, true
+}
+
+func (config *VaccinationConfig) findCat(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	idParam := chi.URLParam(r, "id")
+	id64, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid cat ID")
+		return 0, false
+	}
+
+	if _, err := config.CatRepository.FindById(r.Context(), uint(id64)); err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "cat not found")
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+// saveVaccination checks the cat, the veterinarian and the visit, then creates or updates the vaccination.
+// When only the visit is given, the administering veterinarian is taken from it.
+func (config *VaccinationConfig) saveVaccination(w http.ResponseWriter, r *http.Request, vaccination *dbmodel.Vaccination, status int) {
+	if _, err := config.CatRepository.FindById(r.Context(), vaccination.CatID); err != nil {
+		problem.LookupError(w, r, err, http.StatusUnprocessableEntity, "cat not found")
+		return
+	}
+
+	if vaccination.VisitID != nil {
+		visit, err := config.VisitRepository.FindById(r.Context(), *vaccination.VisitID)
+		if err != nil {
+			problem.LookupError(w, r, err, http.StatusUnprocessableEntity, "visit not found")
+			return
+		}
+		if visit.CatID != vaccination.CatID {
+			problem.Write(w, r, http.StatusUnprocessableEntity, "the visit belongs to another cat")
+			return
+		}
+		if vaccination.VeterinarianID == nil {
+			vaccination.VeterinarianID = visit.VeterinarianID
+		}
+	}
+
+	if vaccination.VeterinarianID != nil {
+		if _, err := config.VeterinarianRepository.FindById(r.Context(), *vaccination.VeterinarianID); err != nil {
+			problem.LookupError(w, r, err, http.StatusUnprocessableEntity, "veterinarian not found")
+			return
+		}
+	}
+
+	var saved *dbmodel.Vaccination
+	var err error
+	if vaccination.ID == 0 {
+		saved, err = config.VaccinationRepository.Create(r.Context(), vaccination)
+	} else {
+		saved, err = config.VaccinationRepository.Update(r.Context(), vaccination)
+	}
+	if errors.Is(err, dbmodel.ErrVersionConflict) {
+		etag.Conflict(w, r)
+		return
+	}
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "unable to save vaccination")
+		return
+	}
+
+	etag.Set(w, saved.Version)
+	render.Status(r, status)
+	render.JSON(w, r, models.NewVaccinationResponse(saved))
+}
+
+func applyVaccinationRequest(vaccination *dbmodel.Vaccination, req *models.VaccinationRequest) {
+	vaccination.CatID = req.CatID
+	vaccination.VaccineName = req.VaccineName
+	vaccination.LotNumber = req.LotNumber
+	vaccination.Manufacturer = req.Manufacturer
+	vaccination.AdministeredAt = req.AdministeredAt
+	vaccination.VeterinarianID = req.VeterinarianID
+	vaccination.Veterinarian = nil
+	vaccination.NextDueAt = req.NextDueAt
+	vaccination.VisitID = req.VisitID
+	vaccination.Notes = req.Notes
+}
@@ -0,0 +1,41 @@
+package vaccination
+
+import (
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/authentification"
+	"github.com/go-chi/chi/v5"
+)
+
+func Routes(configuration *config.Config) *chi.Mux {
+	vaccinationConfig := New(configuration)
+	router := chi.NewRouter()
+
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionVaccinationsRead))
+		r.Get("/", vaccinationConfig.GetAllVaccinationsHandler)
+		r.Get("/due", vaccinationConfig.GetDueVaccinationsHandler)
+		r.Get("/{id}", vaccinationConfig.GetVaccinationByIDHandler)
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionVaccinationsWrite))
+		r.Post("/", vaccinationConfig.CreateVaccinationHandler)
+		r.Put("/{id}", vaccinationConfig.UpdateVaccinationHandler)
+		r.Delete("/{id}", vaccinationConfig.DeleteVaccinationHandler)
+		r.With(authentification.RequirePermission(dbmodel.PermissionDeletedManage)).Post("/{id}/restore", vaccinationConfig.RestoreVaccinationHandler)
+	})
+
+	return router
+}
+
+// CatRoutes serves the vaccinations of a single cat and is mounted under /cats/{id}/vaccinations.
+func CatRoutes(configuration *config.Config) *chi.Mux {
+	vaccinationConfig := New(configuration)
+	router := chi.NewRouter()
+
+	router.With(authentification.RequirePermission(dbmodel.PermissionVaccinationsRead)).Get("/", vaccinationConfig.GetCatVaccinationsHandler)
+	router.With(authentification.RequirePermission(dbmodel.PermissionVaccinationsWrite)).Post("/", vaccinationConfig.CreateCatVaccinationHandler)
+
+	return router
+}
@@ -0,0 +1,348 @@
+package veterinarian
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"gorm.io/gorm"
+)
+
+type VeterinarianConfig struct {
+	*config.Config
+}
+
+func New(configuration *config.Config) *VeterinarianConfig {
+	return &VeterinarianConfig{configuration}
+}
+
+// CreateVeterinarianHandler godoc
+// @Summary Create a veterinarian
+// @Description The veterinarian is active unless active is false; user_id optionally links a login account.
+// @Tags veterinarians
+// @Accept json
+// @Produce json
+// @Param veterinarian body models.VeterinarianRequest true "Veterinarian payload"
+// @Success 201 {object} models.VeterinarianResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /veterinarians [post]
+func (config *VeterinarianConfig) CreateVeterinarianHandler(w http.ResponseWriter, r *http.Request) {
+	req := &models.VeterinarianRequest{}
+
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	veterinarian := &dbmodel.Veterinarian{Active: true}
+	applyVeterinarianRequest(veterinarian, req)
+
+	config.saveVeterinarian(w, r, veterinarian, http.StatusCreated)
+}
+
+// GetAllVeterinariansHandler godoc
+// @Summary List veterinarians
+// @Tags veterinarians
+// @Produce json
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. name (id, name, created_at)"
+// @Param name query string false "Name contains"
+// @Param active query bool false "Active veterinarians only (true) or inactive only (false)"
+// @Param specialty query string false "Specialty contains"
+// @Success 200 {array} models.VeterinarianResponse
+// @Header 200 {integer} X-Total-Count "Total number of matching veterinarians"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /veterinarians [get]
+func (config *VeterinarianConfig) GetAllVeterinariansHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := models.ParseQueryOptions(r)
+	if err != nil {
+		problem.InvalidQuery(w, r, err)
+		return
+	}
+
+	veterinarians, total, err := config.VeterinarianRepository.FindAll(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch veterinarians")
+		return
+	}
+	models.SetPaginationHeaders(w, r, opts, total)
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, models.NewVeterinarianResponses(veterinarians))
+}
+
+// GetVeterinarianByIDHandler godoc
+// @Summary Get a veterinarian by ID
+// @Tags veterinarians
+// @Produce json
+// @Param id path int true "Veterinarian ID"
+// @Success 200 {object} models.VeterinarianResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /veterinarians/{id} [get]
+func (config *VeterinarianConfig) GetVeterinarianByIDHandler(w http.ResponseWriter, r *http.Request) {
+	veterinarian, ok := config.findVeterinarian(w, r)
+	if !ok {
+		return
+	}
+
+	render.JSON(w, r, models.NewVeterinarianResponse(veterinarian))
+}
+
+// UpdateVeterinarianHandler godoc
+// @Summary Update a veterinarian
+// @Description Set active to false to deactivate a veterinarian who left; their past visits are kept.
+// @Tags veterinarians
+// @Accept json
+// @Produce json
+// @Param id path int true "Veterinarian ID"
+// @Param veterinarian body models.VeterinarianRequest true "Veterinarian payload"
+// @Success 200 {object} models.VeterinarianResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /veterinarians/{id} [put]
+func (config *VeterinarianConfig) UpdateVeterinarianHandler(w http.ResponseWriter, r *http.Request) {
+	existing, ok := config.findVeterinarian(w, r)
+	if !ok {
+		return
+	}
+
+	req := &models.VeterinarianRequest{}
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	applyVeterinarianRequest(existing, req)
+
+	config.saveVeterinarian(w, r, existing, http.StatusOK)
+}
+
+// DeleteVeterinarianHandler godoc
+// @Summary Delete a veterinarian
+// @Description Only veterinarians without visits, appointments or vaccinations, even deleted ones, can be deleted; deactivate the others. A deleted veterinarian can be restored until purged.
+// @Tags veterinarians
+// @Param id path int true "Veterinarian ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /veterinarians/{id} [delete]
+func (config *VeterinarianConfig) DeleteVeterinarianHandler(w http.ResponseWriter, r *http.Request) {
+	veterinarian, ok := config.findVeterinarian(w, r)
+	if !ok {
+		return
+	}
+
+	if err := config.VeterinarianRepository.Delete(r.Context(), veterinarian.ID, veterinarian); err != nil {
+		if errors.Is(err, dbmodel.ErrVeterinarianInUse) {
+			problem.Write(w, r, http.StatusConflict, err.Error())
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to delete veterinarian")
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+// RestoreVeterinarianHandler godoc
+// @Summary Restore a deleted veterinarian
+// @Description Also brings back the working hours of the veterinarian.
+// @Tags veterinarians
+// @Produce json
+// @Param id path int true "Veterinarian ID"
+// @Success 200 {object} models.VeterinarianResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /veterinarians/{id}/restore [post]
+func (config *VeterinarianConfig) RestoreVeterinarianHandler(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid veterinarian ID")
+		return
+	}
+
+	restored, err := config.VeterinarianRepository.Restore(r.Context(), uint(id64))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			problem.Write(w, r, http.StatusNotFound, "veterinarian not found")
+		case errors.Is(err, dbmodel.ErrNotDeleted), errors.Is(err, dbmodel.ErrParentDeleted):
+			problem.Write(w, r, http.StatusConflict, err.Error())
+		default:
+			problem.Write(w, r, http.StatusInternalServerError, "failed to restore veterinarian")
+		}
+		return
+	}
+
+	render.JSON(w, r, models.NewVeterinarianResponse(restored))
+}
+
+// GetWorkingHoursHandler godoc
+// @Summary Get the weekly working hours of a veterinarian
+// @Tags veterinarians
+// @Produce json
+// @Param id path int true "Veterinarian ID"
+// @Success 200 {array} models.WorkingHoursResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /veterinarians/{id}/working-hours [get]
+func (config *VeterinarianConfig) GetWorkingHoursHandler(w http.ResponseWriter, r *http.Request) {
+	veterinarian, ok := config.findVeterinarian(w, r)
+	if !ok {
+		return
+	}
+
+	hours, err := config.WorkingHoursRepository.FindByVeterinarian(r.Context(), veterinarian.ID)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to load working hours")
+		return
+	}
+
+	render.JSON(w, r, models.NewWorkingHoursResponses(hours))
+}
+
+// ReplaceWorkingHoursHandler godoc
+// @Summary Replace the weekly working hours of a veterinarian
+// @Description Times are HH:MM in the clinic timezone; weekday is 0 (Sunday) to 6 (Saturday).
+// @Tags veterinarians
+// @Accept json
+// @Produce json
+// @Param id path int true "Veterinarian ID"
+// @Param hours body models.WorkingHoursRequest true "Working hours"
+// @Success 200 {array} models.WorkingHoursResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /veterinarians/{id}/working-hours [put]
+func (config *VeterinarianConfig) ReplaceWorkingHoursHandler(w http.ResponseWriter, r *http.Request) {
+	veterinarian, ok := config.findVeterinarian(w, r)
+	if !ok {
+		return
+	}
+
+	req := &models.WorkingHoursRequest{}
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	hours := make([]dbmodel.WorkingHours, 0, len(req.Hours))
+	for _, slot := range req.Hours {
+		hours = append(hours, dbmodel.WorkingHours{
+			Weekday: slot.Weekday,
+			Start:   slot.Start,
+			End:     slot.End,
+		})
+	}
+
+	saved, err := config.WorkingHoursRepository.Replace(r.Context(), veterinarian.ID, hours)
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "failed to save working hours")
+		return
+	}
+
+	render.JSON(w, r, models.NewWorkingHoursResponses(saved))
+}
+
+func (config *VeterinarianConfig) findVeterinarian(w http.ResponseWriter, r *http.Request) (*dbmodel.Veterinarian, bool) {
+	idParam := chi.URLParam(r, "id")
+	id64, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid veterinarian ID")
+		return nil, false
+	}
+
+	veterinarian, err := config.VeterinarianRepository.FindById(r.Context(), uint(id64))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "veterinarian not found")
+		return nil, false
+	}
+	return veterinarian, true
+}
+
+// saveVeterinarian checks the linked user and the license number, then creates or updates the veterinarian.
+func (config *VeterinarianConfig) saveVeterinarian(w http.ResponseWriter, r *http.Request, veterinarian *dbmodel.Veterinarian, status int) {
+	if veterinarian.UserID != nil {
+		if _, err := config.UserRepository.FindById(r.Context(), *veterinarian.UserID); err != nil {
+			problem.LookupError(w, r, err, http.StatusUnprocessableEntity, "user not found")
+			return
+		}
+		linked, err := config.VeterinarianRepository.FindByUserID(r.Context(), *veterinarian.UserID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, http.StatusInternalServerError, "failed to check the linked user")
+			return
+		}
+		if err == nil && linked.ID != veterinarian.ID {
+			problem.Write(w, r, http.StatusConflict, "the user is already linked to another veterinarian")
+			return
+		}
+	}
+
+	if veterinarian.LicenseNumber != nil {
+		other, err := config.VeterinarianRepository.FindByLicenseNumber(r.Context(), *veterinarian.LicenseNumber)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, http.StatusInternalServerError, "failed to check the license number")
+			return
+		}
+		if err == nil && other.ID != veterinarian.ID {
+			problem.Write(w, r, http.StatusConflict, "another veterinarian already has this license number")
+			return
+		}
+	}
+
+	var saved *dbmodel.Veterinarian
+	var err error
+	if veterinarian.ID == 0 {
+		saved, err = config.VeterinarianRepository.Create(r.Context(), veterinarian)
+	} else {
+		saved, err = config.VeterinarianRepository.Update(r.Context(), veterinarian)
+	}
+	if err != nil {
+		problem.Write(w, r, http.StatusInternalServerError, "unable to save veterinarian")
+		return
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, models.NewVeterinarianResponse(saved))
+}
+
+func applyVeterinarianRequest(veterinarian *dbmodel.Veterinarian, req *models.VeterinarianRequest) {
+	veterinarian.Name = req.Name
+	veterinarian.LicenseNumber = nil
+	if req.LicenseNumber != "" {
+		veterinarian.LicenseNumber = &req.LicenseNumber
+	}
+	veterinarian.Specialties = req.Specialties
+	if req.Active != nil {
+		veterinarian.Active = *req.Active
+	}
+	veterinarian.UserID = req.UserID
+	veterinarian.User = nil
+}
@@ -0,0 +1,31 @@
+package veterinarian
+
+import (
+	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/authentification"
+	"github.com/go-chi/chi/v5"
+)
+
+func Routes(configuration *config.Config) *chi.Mux {
+	veterinarianConfig := New(configuration)
+	router := chi.NewRouter()
+
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionVeterinariansRead))
+		r.Get("/", veterinarianConfig.GetAllVeterinariansHandler)
+		r.Get("/{id}", veterinarianConfig.GetVeterinarianByIDHandler)
+		r.Get("/{id}/working-hours", veterinarianConfig.GetWorkingHoursHandler)
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionVeterinariansWrite))
+		r.Post("/", veterinarianConfig.CreateVeterinarianHandler)
+		r.Put("/{id}", veterinarianConfig.UpdateVeterinarianHandler)
+		r.Delete("/{id}", veterinarianConfig.DeleteVeterinarianHandler)
+		r.With(authentification.RequirePermission(dbmodel.PermissionDeletedManage)).Post("/{id}/restore", veterinarianConfig.RestoreVeterinarianHandler)
+		r.Put("/{id}/working-hours", veterinarianConfig.ReplaceWorkingHoursHandler)
+	})
+
+	return router
+}
@@ -1,14 +1,20 @@
 package visit
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/emmanuelYohore/vet-clinic-api/config"
 	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/etag"
 	"github.com/emmanuelYohore/vet-clinic-api/pkg/models"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/patch"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/problem"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"gorm.io/gorm"
 )
 
 type VisitConfig struct {
@@ -25,62 +31,138 @@ func New(configuration *config.Config) *VisitConfig {
 // @Accept json
 // @Produce json
 // @Param visit body models.VisitRequest true "Visit payload"
-// @Success 201 {object} dbmodel.Visit
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 201 {object} models.VisitResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /visits [post]
 func (config *VisitConfig) CreateVisitHandler(w http.ResponseWriter, r *http.Request) {
 	req := &models.VisitRequest{}
 
 	if err := render.Bind(r, req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid request payload",
-		})
+		problem.BindError(w, r, err)
 		return
 	}
 
-	visit := &dbmodel.Visit{
-		Motif:       req.Motif,
-		Date:        req.Date,
-		Veterinaire: req.Veterinaire,
+	savedVisit, err := config.VisitService.Create(r.Context(), newVisit(req))
+	if err != nil {
+		writeSaveError(w, r, err)
+		return
 	}
 
-	savedVisit, err := config.VisitRepository.Create(visit)
-	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "unable to save visit",
+	etag.Set(w, savedVisit.Version)
+	render.Status(r, http.StatusCreated)
+	w.Header().Set("Content-Type", "application/json")
+
+	render.JSON(w, r, models.NewVisitResponse(savedVisit))
+}
+
+// CompleteVisitHandler doc
+// @Summary Record a visit with its treatments
+// @Description Creates the visit and all its treatments in one transaction: if anything fails, nothing is saved.
+// @Tags visits
+// @Accept json
+// @Produce json
+// @Param visit body models.CompleteVisitRequest true "Visit and treatments payload (treatments carry no visit_id)"
+// @Success 201 {object} models.VisitHistoryResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /visits/complete [post]
+func (config *VisitConfig) CompleteVisitHandler(w http.ResponseWriter, r *http.Request) {
+	req := &models.CompleteVisitRequest{}
+
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	treatments := make([]*dbmodel.Treatment, 0, len(req.Treatments))
+	for _, treatmentReq := range req.Treatments {
+		treatments = append(treatments, &dbmodel.Treatment{
+			Name:      treatmentReq.Name,
+			Dosage:    treatmentReq.Dosage,
+			Unit:      treatmentReq.Unit,
+			Route:     treatmentReq.Route,
+			Frequency: treatmentReq.Frequency,
+			StartDate: treatmentReq.StartDate,
+			EndDate:   treatmentReq.EndDate,
+			Notes:     treatmentReq.Notes,
 		})
+	}
+
+	savedVisit, err := config.VisitService.Complete(r.Context(), newVisit(&req.VisitRequest), treatments)
+	if err != nil {
+		writeSaveError(w, r, err)
 		return
 	}
 
+	etag.Set(w, savedVisit.Version)
 	render.Status(r, http.StatusCreated)
-	w.Header().Set("Content-Type", "application/json")
+	render.JSON(w, r, models.NewVisitHistoryResponse(savedVisit))
+}
+
+func newVisit(req *models.VisitRequest) *dbmodel.Visit {
+	return &dbmodel.Visit{
+		Motif:          req.Motif,
+		Date:           req.Date,
+		VeterinarianID: &req.VeterinarianID,
+		CatID:          req.CatID,
+	}
+}
 
-	render.JSON(w, r, savedVisit)
+// writeSaveError answers 422 when the cat or the veterinarian of a new visit cannot be
+// used, and 500 otherwise.
+func writeSaveError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrCatNotFound), errors.Is(err, service.ErrVeterinarianUnavailable):
+		problem.Write(w, r, http.StatusUnprocessableEntity, err.Error())
+	default:
+		problem.Write(w, r, http.StatusInternalServerError, "unable to save visit")
+	}
 }
 
 // GetAllVisitsHandler doc
 // @Summary Get all visits
 // @Tags visits
 // @Produce json
-// @Success 200 {array} dbmodel.Visit
-// @Failure 500 {object} map[string]string
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. -date (id, date, motif, veterinarian_id, cat_id, created_at)"
+// @Param include_deleted query bool false "Also list deleted records (requires deleted:manage)"
+// @Param cat_id query int false "Cat ID"
+// @Param motif query string false "Motif contains"
+// @Param veterinarian_id query int false "Veterinarian ID"
+// @Param veterinaire query string false "Veterinarian name contains (ignores \"Dr\" prefixes)"
+// @Param date_from query string false "Visits on or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param date_to query string false "Visits on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Success 200 {array} models.VisitResponse
+// @Header 200 {integer} X-Total-Count "Total number of matching visits"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /visits [get]
 func (config *VisitConfig) GetAllVisitsHandler(w http.ResponseWriter, r *http.Request) {
-	visits, err := config.TreatmentRepository.FindAll()
+	opts, err := models.ParseQueryOptions(r)
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to fetch visits",
-		})
+		problem.InvalidQuery(w, r, err)
+		return
+	}
+
+	visits, total, err := config.VisitRepository.FindAll(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch visits")
 		return
 	}
+	models.SetPaginationHeaders(w, r, opts, total)
 	render.Status(r, http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
 
-	render.JSON(w, r, visits)
+	render.JSON(w, r, models.NewVisitResponses(visits))
 }
 
 // GetVisitByIDHandler doc
@@ -88,33 +170,34 @@ func (config *VisitConfig) GetAllVisitsHandler(w http.ResponseWriter, r *http.Re
 // @Tags visits
 // @Produce json
 // @Param id path int true "Visit ID"
-// @Success 200 {object} dbmodel.Visit
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {object} models.VisitResponse
+// @Header 200 {string} ETag "Version of the visit"
+// @Success 304 "The cached copy is current"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /visits/{id} [get]
 func (config *VisitConfig) GetVisitByIDHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	id64, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid visit ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid visit ID")
 		return
 	}
 
-	visit, err := config.VisitRepository.FindById(uint(id64))
+	visit, err := config.VisitRepository.FindById(r.Context(), uint(id64))
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{
-			"error": "cat not found",
-		})
+		problem.LookupError(w, r, err, http.StatusNotFound, "visit not found")
+		return
+	}
+	if etag.NotModified(w, r, visit.Version) {
 		return
 	}
 	render.Status(r, http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
 
-	render.JSON(w, r, visit)
+	render.JSON(w, r, models.NewVisitResponse(visit))
 }
 
 // UpdateVisitHandler doc
@@ -123,153 +206,357 @@ func (config *VisitConfig) GetVisitByIDHandler(w http.ResponseWriter, r *http.Re
 // @Accept json
 // @Produce json
 // @Param id path int true "Visit ID"
-// @Param visit body models.VisitRequest true "Visit payload"
-// @Success 200 {object} dbmodel.Visit
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Param If-Match header string true "ETag of the version being updated"
+// @Param visit body models.VisitUpdateRequest true "Visit payload (veterinarian_id may stay null on a visit that has no veterinarian)"
+// @Success 200 {object} models.VisitResponse
+// @Header 200 {string} ETag "New version of the visit"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /visits/{id} [put]
 func (config *VisitConfig) UpdateVisitHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	id64, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid visit ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid visit ID")
 		return
 	}
 
-	req := &models.VisitRequest{}
+	req := &models.VisitUpdateRequest{}
 	if err := render.Bind(r, req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid request payload",
-		})
+		problem.BindError(w, r, err)
 		return
 	}
 
-	existing, err := config.VisitRepository.FindById(uint(id64))
+	existing, err := config.VisitRepository.FindById(r.Context(), uint(id64))
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{
-			"error": "visit not found",
-		})
+		problem.LookupError(w, r, err, http.StatusNotFound, "visit not found")
+		return
+	}
+	if !etag.Match(w, r, existing.Version) {
 		return
 	}
 
+	config.updateVisit(w, r, existing, req)
+}
+
+// PatchVisitHandler doc
+// @Summary Partially update a visit
+// @Description Send a merge patch (application/merge-patch+json) with only the fields to change, or a JSON Patch (application/json-patch+json). The patched visit is validated as a whole.
+// @Tags visits
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path int true "Visit ID"
+// @Param If-Match header string true "ETag of the version being updated"
+// @Param visit body models.VisitUpdateRequest true "Fields to change"
+// @Success 200 {object} models.VisitResponse
+// @Header 200 {string} ETag "New version of the visit"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 415 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /visits/{id} [patch]
+func (config *VisitConfig) PatchVisitHandler(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid visit ID")
+		return
+	}
+
+	existing, err := config.VisitRepository.FindById(r.Context(), uint(id64))
+	if err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "visit not found")
+		return
+	}
+	if !etag.Match(w, r, existing.Version) {
+		return
+	}
+
+	req := models.NewVisitUpdateRequest(existing)
+	if err := patch.Bind(r, req); err != nil {
+		patch.WriteError(w, r, err)
+		return
+	}
+
+	config.updateVisit(w, r, existing, req)
+}
+
+// updateVisit checks the cat and the veterinarian, then saves the visit with the fields of req.
+func (config *VisitConfig) updateVisit(w http.ResponseWriter, r *http.Request, existing *dbmodel.Visit, req *models.VisitUpdateRequest) {
+	if _, err := config.CatRepository.FindById(r.Context(), req.CatID); err != nil {
+		problem.LookupError(w, r, err, http.StatusUnprocessableEntity, "cat not found")
+		return
+	}
+
+	// A visit may keep a veterinarian who has since been deactivated, and a visit without
+	// a veterinarian may stay without one, but a veterinarian cannot be removed.
+	if req.VeterinarianID == nil {
+		if existing.VeterinarianID != nil {
+			problem.BindError(w, r, problem.Field("veterinarian_id", "veterinarian_id must not be empty"))
+			return
+		}
+	} else if existing.VeterinarianID == nil || *existing.VeterinarianID != *req.VeterinarianID {
+		veterinarian, err := config.VeterinarianRepository.FindById(r.Context(), *req.VeterinarianID)
+		if err != nil {
+			problem.LookupError(w, r, err, http.StatusUnprocessableEntity, "veterinarian not found or inactive")
+			return
+		}
+		if !veterinarian.Active {
+			problem.Write(w, r, http.StatusUnprocessableEntity, "veterinarian not found or inactive")
+			return
+		}
+	}
+
 	existing.Motif = req.Motif
 	existing.Date = req.Date
-	existing.Veterinaire = req.Veterinaire
+	existing.VeterinarianID = req.VeterinarianID
+	existing.Veterinarian = nil
+	existing.CatID = req.CatID
 
-	updatedVisit, err := config.VisitRepository.Update(existing)
+	updatedVisit, err := config.VisitRepository.Update(r.Context(), existing)
+	if errors.Is(err, dbmodel.ErrVersionConflict) {
+		etag.Conflict(w, r)
+		return
+	}
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to update visit",
-		})
+		problem.Write(w, r, http.StatusInternalServerError, "failed to update visit")
 		return
 	}
+	etag.Set(w, updatedVisit.Version)
 	render.Status(r, http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
 
-	render.JSON(w, r, updatedVisit)
+	render.JSON(w, r, models.NewVisitResponse(updatedVisit))
 }
 
 // DeleteVisitHandler doc
 // @Summary Delete a visit
 // @Tags visits
 // @Param id path int true "Visit ID"
+// @Param If-Match header string true "ETag of the version being deleted"
 // @Success 204 {object} nil
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /visits/{id} [delete]
 func (config *VisitConfig) DeleteVisitHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	id64, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid visit ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid visit ID")
 		return
 	}
 
-	visit, err := config.VisitRepository.FindById(uint(id64))
+	visit, err := config.VisitRepository.FindById(r.Context(), uint(id64))
 	if err != nil {
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{
-			"error": "visit not found",
-		})
+		problem.LookupError(w, r, err, http.StatusNotFound, "visit not found")
+		return
+	}
+	if !etag.Match(w, r, visit.Version) {
 		return
 	}
 
-	if err := config.VisitRepository.Delete(uint(id64), visit); err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to delete visit",
-		})
+	if err := config.VisitRepository.Delete(r.Context(), uint(id64), visit); err != nil {
+		if errors.Is(err, dbmodel.ErrVersionConflict) {
+			etag.Conflict(w, r)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to delete visit")
 		return
 	}
 	
 	render.Status(r, http.StatusNoContent)
 }
 
+// RestoreVisitHandler godoc
+// @Summary Restore a deleted visit
+// @Description Also restores the treatments deleted along with the visit. The cat of the visit must not be deleted.
+// @Tags visits
+// @Produce json
+// @Param id path int true "Visit ID"
+// @Success 200 {object} models.VisitResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /visits/{id}/restore [post]
+func (config *VisitConfig) RestoreVisitHandler(w http.ResponseWriter, r *http.Request) {
+	id64, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid visit ID")
+		return
+	}
+
+	restored, err := config.VisitRepository.Restore(r.Context(), uint(id64))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			problem.Write(w, r, http.StatusNotFound, "visit not found")
+		case errors.Is(err, dbmodel.ErrNotDeleted), errors.Is(err, dbmodel.ErrParentDeleted):
+			problem.Write(w, r, http.StatusConflict, err.Error())
+		default:
+			problem.Write(w, r, http.StatusInternalServerError, "failed to restore visit")
+		}
+		return
+	}
+
+	etag.Set(w, restored.Version)
+	render.JSON(w, r, models.NewVisitResponse(restored))
+}
+
+// CreateCatVisitHandler doc
+// @Summary Create a visit for a cat
+// @Tags visits
+// @Accept json
+// @Produce json
+// @Param id path int true "Cat ID"
+// @Param visit body models.VisitRequest true "Visit payload (cat_id may be omitted)"
+// @Success 201 {object} models.VisitResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /cats/{id}/visits [post]
+func (config *VisitConfig) CreateCatVisitHandler(w http.ResponseWriter, r *http.Request) {
+	idParam := chi.URLParam(r, "id")
+	id64, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "invalid cat ID")
+		return
+	}
+
+	if _, err := config.CatRepository.FindById(r.Context(), uint(id64)); err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "cat not found")
+		return
+	}
+
+	req := &models.VisitRequest{CatID: uint(id64)}
+	if err := render.Bind(r, req); err != nil {
+		problem.BindError(w, r, err)
+		return
+	}
+
+	if req.CatID != uint(id64) {
+		problem.Write(w, r, http.StatusBadRequest, "cat_id does not match the cat in the URL")
+		return
+	}
+
+	savedVisit, err := config.VisitService.Create(r.Context(), newVisit(req))
+	if err != nil {
+		writeSaveError(w, r, err)
+		return
+	}
+
+	etag.Set(w, savedVisit.Version)
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, models.NewVisitResponse(savedVisit))
+}
+
 // GetVisitsByCatHandler doc
 // @Summary Get visits by Cat ID
 // @Tags visits
 // @Produce json
 // @Param id path int true "Cat ID"
-// @Success 200 {array} dbmodel.Visit
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. -date"
+// @Param include_deleted query bool false "Also list deleted records (requires deleted:manage)"
+// @Header 200 {integer} X-Total-Count "Total number of matching visits"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Success 200 {array} models.VisitResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /cats/{id}/visits [get]
 func (config *VisitConfig) GetVisitsByCatHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	id64, err := strconv.ParseUint(idParam, 10, 32)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{
-			"error": "invalid cat ID",
-		})
+		problem.Write(w, r, http.StatusBadRequest, "invalid cat ID")
+		return
+	}
+
+	if _, err := config.CatRepository.FindById(r.Context(), uint(id64)); err != nil {
+		problem.LookupError(w, r, err, http.StatusNotFound, "cat not found")
 		return
 	}
-	visits, err := config.VisitRepository.FindByCatID(uint(id64))
+
+	opts, err := models.ParseQueryOptions(r)
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to fetch visits for cat",
-		})
+		problem.InvalidQuery(w, r, err)
+		return
+	}
+	opts.Filters["cat_id"] = idParam
+	visits, total, err := config.VisitRepository.FindAll(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to fetch visits for cat")
 		return
 	}
+	models.SetPaginationHeaders(w, r, opts, total)
 	
 	render.Status(r, http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
-	render.JSON(w, r, visits)
+	render.JSON(w, r, models.NewVisitResponses(visits))
 }
 
 // FilterByMotifOrVeterinaireHandler doc
-// @Summary Filter visits by motif or veterinaire
+// @Summary Filter visits by motif or veterinarian
 // @Tags visits
 // @Produce json
 // @Param motif query string false "Motif"
-// @Param veterinaire query string false "Veterinaire"
-// @Success 200 {array} dbmodel.Visit
-// @Failure 500 {object} map[string]string
+// @Param veterinarian_id query int false "Veterinarian ID"
+// @Param page query int false "Page number (starts at 1)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param sort query string false "Sort fields, e.g. -date (id, date, motif, veterinarian_id, cat_id, created_at)"
+// @Success 200 {array} models.VisitResponse
+// @Header 200 {integer} X-Total-Count "Total number of matching visits"
+// @Header 200 {string} Link "Pagination links (first, prev, next, last)"
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /visits/filter [get]
 func (config *VisitConfig) FilterByMotifOrVeterinaireHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := models.ParseQueryOptions(r)
+	if err != nil {
+		problem.InvalidQuery(w, r, err)
+		return
+	}
 	motif := r.URL.Query().Get("motif")
-	veterinaire := r.URL.Query().Get("veterinaire")
-	visits, err := config.VisitRepository.FilterByMotifOrVeterinaire(motif, veterinaire)
+
+	var veterinarianID uint
+	if idParam := r.URL.Query().Get("veterinarian_id"); idParam != "" {
+		id64, err := strconv.ParseUint(idParam, 10, 32)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, "invalid veterinarian ID")
+			return
+		}
+		veterinarianID = uint(id64)
+	}
+
+	visits, total, err := config.VisitRepository.FilterByMotifOrVeterinaire(r.Context(), motif, veterinarianID, opts)
 	if err != nil {
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{
-			"error": "failed to filter visits",
-		})
+		if errors.Is(err, dbmodel.ErrInvalidQuery) {
+			problem.InvalidQuery(w, r, err)
+			return
+		}
+		problem.Write(w, r, http.StatusInternalServerError, "failed to filter visits")
 		return
 	}
+	models.SetPaginationHeaders(w, r, opts, total)
 	render.Status(r, http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
-	render.JSON(w, r, visits)
+	render.JSON(w, r, models.NewVisitResponses(visits))
 }
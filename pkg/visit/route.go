@@ -2,6 +2,8 @@ package visit
 
 import (
 	"github.com/emmanuelYohore/vet-clinic-api/config"
+	"github.com/emmanuelYohore/vet-clinic-api/database/dbmodel"
+	"github.com/emmanuelYohore/vet-clinic-api/pkg/authentification"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -9,16 +11,33 @@ func Routes(configuration *config.Config) *chi.Mux {
 	visitConfig := New(configuration)
 	router := chi.NewRouter()
 
-	router.Post("/", visitConfig.CreateVisitHandler)
-	router.Get("/", visitConfig.GetAllVisitsHandler)
-	router.Get("/{id}", visitConfig.GetVisitByIDHandler)
-	router.Put("/{id}", visitConfig.UpdateVisitHandler)
-	router.Delete("/{id}", visitConfig.DeleteVisitHandler)
-	router.Get("/cats/{id}/visits", visitConfig.GetVisitsByCatHandler)
-	
-	
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionVisitsRead))
+		r.Get("/", visitConfig.GetAllVisitsHandler)
+		r.Get("/filter", visitConfig.FilterByMotifOrVeterinaireHandler)
+		r.Get("/{id}", visitConfig.GetVisitByIDHandler)
+	})
 
-	
+	router.Group(func(r chi.Router) {
+		r.Use(authentification.RequirePermission(dbmodel.PermissionVisitsWrite))
+		r.Post("/", visitConfig.CreateVisitHandler)
+		r.With(authentification.RequirePermission(dbmodel.PermissionTreatmentsWrite)).Post("/complete", visitConfig.CompleteVisitHandler)
+		r.Put("/{id}", visitConfig.UpdateVisitHandler)
+		r.Patch("/{id}", visitConfig.PatchVisitHandler)
+		r.Delete("/{id}", visitConfig.DeleteVisitHandler)
+		r.With(authentification.RequirePermission(dbmodel.PermissionDeletedManage)).Post("/{id}/restore", visitConfig.RestoreVisitHandler)
+	})
+
+	return router
+}
+
+// CatRoutes serves the visits of a single cat and is mounted under /cats/{id}/visits.
+func CatRoutes(configuration *config.Config) *chi.Mux {
+	visitConfig := New(configuration)
+	router := chi.NewRouter()
+
+	router.With(authentification.RequirePermission(dbmodel.PermissionVisitsRead)).Get("/", visitConfig.GetVisitsByCatHandler)
+	router.With(authentification.RequirePermission(dbmodel.PermissionVisitsWrite)).Post("/", visitConfig.CreateCatVisitHandler)
 
 	return router
 }